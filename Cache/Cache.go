@@ -0,0 +1,71 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Cache
+
+type Cache interface {
+	// Get 返回key对应的值，并更新key的访问记录，同时计入命中/未命中统计
+	Get(key interface{}) (interface{}, bool)
+
+	// Put 添加或更新key对应的值，容量不足时按照淘汰策略淘汰其他元素
+	Put(key, value interface{}) error
+
+	// Peek 返回key对应的值，但不更新访问记录和统计信息
+	Peek(key interface{}) (interface{}, bool)
+
+	// Remove 删除key，返回key是否存在，被删除的元素不会触发淘汰回调
+	Remove(key interface{}) bool
+
+	// Len 返回缓存中的元素数量
+	Len() int
+
+	// Weight 返回缓存中所有元素的权重之和
+	Weight() int
+
+	// Capacity 返回缓存的容量
+	Capacity() int
+
+	// Stats 返回缓存的命中统计信息
+	Stats() Stats
+
+	Clear()
+}
+
+// Weigher 计算一个元素的权重，权重必须为正数
+// 创建缓存时Weigher为nil表示每个元素的权重都为1，此时容量即为元素数量
+type Weigher func(key, value interface{}) int
+
+// EvictCallback 当元素因为容量不足被淘汰时调用
+// 对于safe缓存，回调在持有锁的情况下执行，因此回调中不能再访问该缓存
+type EvictCallback func(key, value interface{})
+
+// Stats 缓存的命中统计信息
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// HitRate 返回命中率，没有任何访问时返回0
+func (s Stats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(total)
+}
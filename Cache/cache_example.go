@@ -0,0 +1,58 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Cache
+
+import "fmt"
+
+func UnsafeLRUCacheExample() {
+	c, err := NewUnsafeLRUCache(2, nil, func(key, value interface{}) {
+		fmt.Println("evict", key, value)
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	_ = c.Put("a", 1)
+	_ = c.Put("b", 2)
+	v, ok := c.Get("a")
+	fmt.Println("c.Get =", v, ok)
+	_ = c.Put("c", 3)
+	v, ok = c.Peek("b")
+	fmt.Println("c.Peek =", v, ok)
+	fmt.Println("c.Len =", c.Len())
+	fmt.Println("c.Stats =", c.Stats(), c.Stats().HitRate())
+}
+
+func SafeARCCacheExample() {
+	// 以字符串长度作为权重，容量为总长度
+	c, err := NewSafeARCCache(16, func(key, value interface{}) int {
+		return len(value.(string))
+	}, nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	_ = c.Put(1, "hello")
+	_ = c.Put(2, "world")
+	_ = c.Put(3, "golang")
+	fmt.Println("c.Weight =", c.Weight())
+	_, ok := c.Get(1)
+	fmt.Println("c.Get =", ok)
+	fmt.Println("c.Remove =", c.Remove(3))
+	fmt.Println("c.Len =", c.Len())
+}
@@ -0,0 +1,115 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Cache
+
+import "errors"
+
+// entry 缓存中的一个元素
+type entry struct {
+	key    interface{}
+	value  interface{}
+	weight int
+
+	// list 元素当前所在的链表
+	list *entryList
+
+	// freq LFU缓存中元素所在的频率结点
+	freq *freqNode
+
+	prev *entry
+	next *entry
+}
+
+// entryList 带哨兵结点的双向循环链表，链表头部是最近访问的元素
+// 淘汰策略需要在O(1)时间内移动或删除任意一个元素，而Deque不对外暴露结点，
+// 只能从两端操作，所以元素直接作为链表结点嵌入，由map按key索引
+type entryList struct {
+	root   entry
+	size   int
+	weight int
+}
+
+func newEntryList() *entryList {
+	l := &entryList{}
+	l.root.next = &l.root
+	l.root.prev = &l.root
+
+	return l
+}
+
+func (l *entryList) pushFront(e *entry) {
+	e.prev = &l.root
+	e.next = l.root.next
+	l.root.next.prev = e
+	l.root.next = e
+	e.list = l
+	l.size++
+	l.weight += e.weight
+}
+
+func (l *entryList) remove(e *entry) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.prev = nil
+	e.next = nil
+	e.list = nil
+	l.size--
+	l.weight -= e.weight
+}
+
+func (l *entryList) moveToFront(e *entry) {
+	l.remove(e)
+	l.pushFront(e)
+}
+
+// back 返回链表尾部(最久未访问)的元素，链表为空时返回nil
+func (l *entryList) back() *entry {
+	if l.size == 0 {
+		return nil
+	}
+
+	return l.root.prev
+}
+
+// setWeight 修改链表中元素的权重
+func (l *entryList) setWeight(e *entry, weight int) {
+	l.weight += weight - e.weight
+	e.weight = weight
+}
+
+// weigh 使用weigher计算元素的权重
+func weigh(weigher Weigher, key, value interface{}) (int, error) {
+	if weigher == nil {
+		return 1, nil
+	}
+
+	w := weigher(key, value)
+	if w <= 0 {
+		return 0, errors.New("Weight must be positive.")
+	}
+
+	return w, nil
+}
+
+func checkCapacity(capacity int) error {
+	if capacity <= 0 {
+		return errors.New("Capacity must be positive.")
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Cache
+
+import "GTL/GSync"
+
+// safeCache 为任意一种淘汰策略的缓存加上读写锁
+// Get会更新访问记录，所以和Put一样需要获取写锁
+type safeCache struct {
+	uc Cache
+	m  *GSync.RWMutex
+}
+
+func NewSafeLRUCache(capacity int, weigher Weigher, onEvict EvictCallback) (*safeCache, error) {
+	c, err := NewUnsafeLRUCache(capacity, weigher, onEvict)
+	if err != nil {
+		return nil, err
+	}
+
+	return &safeCache{
+		uc: c,
+		m:  new(GSync.RWMutex),
+	}, nil
+}
+
+func NewSafeLFUCache(capacity int, weigher Weigher, onEvict EvictCallback) (*safeCache, error) {
+	c, err := NewUnsafeLFUCache(capacity, weigher, onEvict)
+	if err != nil {
+		return nil, err
+	}
+
+	return &safeCache{
+		uc: c,
+		m:  new(GSync.RWMutex),
+	}, nil
+}
+
+func NewSafeARCCache(capacity int, weigher Weigher, onEvict EvictCallback) (*safeCache, error) {
+	c, err := NewUnsafeARCCache(capacity, weigher, onEvict)
+	if err != nil {
+		return nil, err
+	}
+
+	return &safeCache{
+		uc: c,
+		m:  new(GSync.RWMutex),
+	}, nil
+}
+
+// NewSafeCacheWithLocker 使用m保护不加锁的缓存uc，之后不能再直接使用uc
+// m可以由GSync.NewRWMutexWithLocker创建，为nil时使用新的GSync.RWMutex
+func NewSafeCacheWithLocker(m *GSync.RWMutex, uc Cache) *safeCache {
+	if m == nil {
+		m = new(GSync.RWMutex)
+	}
+
+	return &safeCache{
+		uc: uc,
+		m:  m,
+	}
+}
+
+func (c *safeCache) Get(key interface{}) (interface{}, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.uc.Get(key)
+}
+
+func (c *safeCache) Put(key, value interface{}) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.uc.Put(key, value)
+}
+
+func (c *safeCache) Peek(key interface{}) (interface{}, bool) {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	return c.uc.Peek(key)
+}
+
+func (c *safeCache) Remove(key interface{}) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.uc.Remove(key)
+}
+
+func (c *safeCache) Len() int {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	return c.uc.Len()
+}
+
+func (c *safeCache) Weight() int {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	return c.uc.Weight()
+}
+
+func (c *safeCache) Capacity() int {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	return c.uc.Capacity()
+}
+
+func (c *safeCache) Stats() Stats {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	return c.uc.Stats()
+}
+
+func (c *safeCache) Clear() {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.uc.Clear()
+}
+
+// Do 持有写锁执行f，f中对u的多次操作整体上是原子的
+// u是不加锁的内部缓存，不能在f返回后继续使用；f返回错误时已经做出的修改不会回滚
+func (c *safeCache) Do(f func(u Cache) error) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return f(c.uc)
+}
+
+// Locker 返回保护缓存的读写锁，可以用来开启锁的统计
+func (c *safeCache) Locker() *GSync.RWMutex {
+	return c.m
+}
@@ -0,0 +1,71 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Cache
+
+import (
+	"sync"
+	"testing"
+)
+
+// 并发读写时统计信息准确，且淘汰回调的次数与统计一致
+func TestSafeCacheConcurrent(t *testing.T) {
+	constructors := map[string]func(int, Weigher, EvictCallback) (*safeCache, error){
+		"LRU": NewSafeLRUCache,
+		"LFU": NewSafeLFUCache,
+		"ARC": NewSafeARCCache,
+	}
+
+	const workers, ops = 8, 2000
+	for name, newCache := range constructors {
+		var evicted uint64
+		// 回调在持有锁时执行，所以这里不需要额外同步
+		c, err := newCache(16, nil, func(key, value interface{}) {
+			evicted++
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			w := w
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < ops; i++ {
+					key := (w*ops + i) % 40
+					if _, ok := c.Get(key); !ok {
+						c.Put(key, i)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		s := c.Stats()
+		if s.Hits+s.Misses != workers*ops {
+			t.Fatalf("%s: hits + misses = %d, want %d", name, s.Hits+s.Misses, workers*ops)
+		}
+		if s.Evictions != evicted {
+			t.Fatalf("%s: Stats().Evictions = %d, callback fired %d times", name, s.Evictions, evicted)
+		}
+		if c.Len() > c.Capacity() || c.Weight() != c.Len() {
+			t.Fatalf("%s: Len() = %d, Weight() = %d, Capacity() = %d", name, c.Len(), c.Weight(), c.Capacity())
+		}
+	}
+}
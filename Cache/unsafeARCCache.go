@@ -0,0 +1,237 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Cache
+
+import "errors"
+
+// unsafeARCCache 自适应替换缓存(Adaptive Replacement Cache)
+// t1保存只访问过一次的元素，t2保存访问过多次的元素，
+// b1和b2分别保存最近从t1和t2中淘汰的元素的key(幽灵元素)，
+// 命中幽灵元素时调整t1的目标权重p，从而在近期性与频率之间自适应
+type unsafeARCCache struct {
+	m map[interface{}]*entry
+
+	t1 *entryList
+	t2 *entryList
+	b1 *entryList
+	b2 *entryList
+
+	// p t1的目标权重
+	p        int
+	capacity int
+	weigher  Weigher
+	onEvict  EvictCallback
+	stats    Stats
+}
+
+// NewUnsafeARCCache 返回一个容量为capacity的ARC缓存，weigher与onEvict可以为nil
+func NewUnsafeARCCache(capacity int, weigher Weigher, onEvict EvictCallback) (*unsafeARCCache, error) {
+	if err := checkCapacity(capacity); err != nil {
+		return nil, err
+	}
+
+	return &unsafeARCCache{
+		m:        make(map[interface{}]*entry),
+		t1:       newEntryList(),
+		t2:       newEntryList(),
+		b1:       newEntryList(),
+		b2:       newEntryList(),
+		p:        0,
+		capacity: capacity,
+		weigher:  weigher,
+		onEvict:  onEvict,
+		stats:    Stats{},
+	}, nil
+}
+
+// resident 判断元素是否真正保存在缓存中(而不是幽灵元素)
+func (c *unsafeARCCache) resident(e *entry) bool {
+	return e.list == c.t1 || e.list == c.t2
+}
+
+func (c *unsafeARCCache) Get(key interface{}) (interface{}, bool) {
+	e, ok := c.m[key]
+	if !ok || !c.resident(e) {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.stats.Hits++
+	// 再次访问的元素移动到t2
+	e.list.remove(e)
+	c.t2.pushFront(e)
+
+	return e.value, true
+}
+
+func (c *unsafeARCCache) Put(key, value interface{}) error {
+	w, err := weigh(c.weigher, key, value)
+	if err != nil {
+		return err
+	}
+	if w > c.capacity {
+		return errors.New("Weight of value exceeds capacity.")
+	}
+
+	e, ok := c.m[key]
+	switch {
+	case ok && c.resident(e):
+		e.list.remove(e)
+		e.value = value
+		e.weight = w
+		c.replace(w, false)
+		c.t2.pushFront(e)
+		return nil
+	case ok && e.list == c.b1:
+		// 命中b1说明t1的目标权重太小
+		c.p = min(c.capacity, c.p+c.delta(w, c.b2.weight, c.b1.weight))
+		c.b1.remove(e)
+		e.value = value
+		e.weight = w
+		c.replace(w, false)
+		c.t2.pushFront(e)
+	case ok && e.list == c.b2:
+		// 命中b2说明t2的目标权重太小
+		c.p = max(0, c.p-c.delta(w, c.b1.weight, c.b2.weight))
+		c.b2.remove(e)
+		e.value = value
+		e.weight = w
+		c.replace(w, true)
+		c.t2.pushFront(e)
+	default:
+		c.replace(w, false)
+		e = &entry{key: key, value: value, weight: w}
+		c.m[key] = e
+		c.t1.pushFront(e)
+	}
+
+	c.trimGhosts()
+
+	return nil
+}
+
+// delta 计算p的调整幅度，另一个幽灵链表越大调整幅度越大
+func (c *unsafeARCCache) delta(w, other, self int) int {
+	if self == 0 || other <= self {
+		return w
+	}
+
+	return w * other / self
+}
+
+// replace 淘汰t1或t2中的元素，直到能够容纳权重为w的新元素
+func (c *unsafeARCCache) replace(w int, inB2 bool) {
+	for c.t1.weight+c.t2.weight+w > c.capacity {
+		if c.t1.size > 0 && (c.t1.weight > c.p || (inB2 && c.t1.weight == c.p) || c.t2.size == 0) {
+			c.evict(c.t1.back(), c.b1)
+		} else {
+			c.evict(c.t2.back(), c.b2)
+		}
+	}
+}
+
+// evict 将元素从t1或t2淘汰到幽灵链表ghost中
+func (c *unsafeARCCache) evict(e *entry, ghost *entryList) {
+	e.list.remove(e)
+	value := e.value
+	e.value = nil
+	ghost.pushFront(e)
+	c.stats.Evictions++
+
+	if c.onEvict != nil {
+		c.onEvict(e.key, value)
+	}
+}
+
+// trimGhosts 限制幽灵链表的大小：t1 + b1不超过容量，全部链表之和不超过两倍容量
+func (c *unsafeARCCache) trimGhosts() {
+	for c.b1.size > 0 && c.t1.weight+c.b1.weight > c.capacity {
+		c.dropGhost(c.b1.back())
+	}
+	for c.b2.size > 0 && c.t1.weight+c.t2.weight+c.b1.weight+c.b2.weight > 2*c.capacity {
+		c.dropGhost(c.b2.back())
+	}
+}
+
+func (c *unsafeARCCache) dropGhost(e *entry) {
+	e.list.remove(e)
+	delete(c.m, e.key)
+}
+
+func (c *unsafeARCCache) Peek(key interface{}) (interface{}, bool) {
+	e, ok := c.m[key]
+	if !ok || !c.resident(e) {
+		return nil, false
+	}
+
+	return e.value, true
+}
+
+func (c *unsafeARCCache) Remove(key interface{}) bool {
+	e, ok := c.m[key]
+	if !ok {
+		return false
+	}
+
+	ret := c.resident(e)
+	e.list.remove(e)
+	delete(c.m, key)
+
+	return ret
+}
+
+func (c *unsafeARCCache) Len() int {
+	return c.t1.size + c.t2.size
+}
+
+func (c *unsafeARCCache) Weight() int {
+	return c.t1.weight + c.t2.weight
+}
+
+func (c *unsafeARCCache) Capacity() int {
+	return c.capacity
+}
+
+func (c *unsafeARCCache) Stats() Stats {
+	return c.stats
+}
+
+func (c *unsafeARCCache) Clear() {
+	c.m = make(map[interface{}]*entry)
+	c.t1 = newEntryList()
+	c.t2 = newEntryList()
+	c.b1 = newEntryList()
+	c.b2 = newEntryList()
+	c.p = 0
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,174 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Cache
+
+import (
+	"reflect"
+	"testing"
+)
+
+// 只访问一次的元素组成的扫描不会冲掉被多次访问的元素
+func TestARCCacheScanResistance(t *testing.T) {
+	c, err := NewUnsafeARCCache(4, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Get("a")
+	c.Get("b")
+	for i := 0; i < 100; i++ {
+		c.Put(i, i)
+	}
+
+	for _, k := range []string{"a", "b"} {
+		if _, ok := c.Peek(k); !ok {
+			t.Fatalf("%q was flushed out by a scan", k)
+		}
+	}
+	if c.Len() != 4 {
+		t.Fatalf("Len() = %d, want 4", c.Len())
+	}
+}
+
+// 命中b1时增大t1的目标权重，命中b2时减小，幽灵元素本身不算命中
+func TestARCCacheGhostAdaptation(t *testing.T) {
+	log := &evictLog{}
+	c, err := NewUnsafeARCCache(4, nil, log.onEvict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Put("a", "a")
+	c.Put("b", "b")
+	c.Get("a")
+	c.Get("b")
+	c.Put("c", "c")
+	c.Put("d", "d")
+	// t1已满，p为0，淘汰t1中最旧的c
+	c.Put("e", "e")
+	if want := []interface{}{"c"}; !reflect.DeepEqual(log.keys, want) {
+		t.Fatalf("evicted %v, want %v", log.keys, want)
+	}
+	if _, ok := c.Get("c"); ok {
+		t.Fatal("Get on a ghost entry hit")
+	}
+	if c.b1.size != 1 || c.Len() != 4 {
+		t.Fatalf("b1.size, Len() = %d, %d, want 1, 4", c.b1.size, c.Len())
+	}
+
+	// c命中b1，p增大到1，c直接进入t2
+	c.Put("c", "C")
+	if c.p != 1 {
+		t.Fatalf("p after b1 hit = %d, want 1", c.p)
+	}
+	if v, ok := c.Peek("c"); !ok || v != "C" || c.m["c"].list != c.t2 {
+		t.Fatalf("Peek(c) = %v, %v, want C in t2", v, ok)
+	}
+
+	// t1只剩下e，不超过p，所以从t2中淘汰最旧的a
+	c.Put("f", "f")
+	// a命中b2，p减小到0，再从t1中淘汰e
+	c.Put("a", "A")
+	if c.p != 0 {
+		t.Fatalf("p after b2 hit = %d, want 0", c.p)
+	}
+	if want := []interface{}{"c", "d", "a", "e"}; !reflect.DeepEqual(log.keys, want) {
+		t.Fatalf("evicted %v, want %v", log.keys, want)
+	}
+	// 回调收到的是淘汰之前的值
+	if want := []interface{}{"c", "d", "a", "e"}; !reflect.DeepEqual(log.values, want) {
+		t.Fatalf("evicted values %v, want %v", log.values, want)
+	}
+	for _, k := range []string{"a", "b", "c", "f"} {
+		if _, ok := c.Peek(k); !ok {
+			t.Fatalf("Peek(%q) missed", k)
+		}
+	}
+	if c.Len() != 4 || c.Weight() != 4 {
+		t.Fatalf("Len(), Weight() = %d, %d, want 4, 4", c.Len(), c.Weight())
+	}
+}
+
+// 幽灵链表的总大小受容量限制
+func TestARCCacheGhostBounds(t *testing.T) {
+	c, err := NewUnsafeARCCache(8, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		c.Put(i%37, i)
+		if i%3 == 0 {
+			c.Get(i % 11)
+		}
+
+		if c.t1.weight+c.b1.weight > c.capacity {
+			t.Fatalf("t1 + b1 = %d exceeds capacity", c.t1.weight+c.b1.weight)
+		}
+		if total := c.t1.size + c.t2.size + c.b1.size + c.b2.size; total > 2*c.capacity || total != len(c.m) {
+			t.Fatalf("lists hold %d entries, map %d, capacity %d", total, len(c.m), c.capacity)
+		}
+		if c.Weight() > c.capacity {
+			t.Fatalf("Weight() = %d exceeds capacity", c.Weight())
+		}
+	}
+}
+
+// 按权重淘汰，Remove不触发回调，删除幽灵元素返回false
+func TestARCCacheWeightsAndStats(t *testing.T) {
+	log := &evictLog{}
+	c, err := NewUnsafeARCCache(10, intWeigher, log.onEvict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Put("a", 4)
+	c.Put("b", 4)
+	c.Get("a")
+	c.Get("x")
+	c.Put("c", 4)
+	if want := []interface{}{"b"}; !reflect.DeepEqual(log.keys, want) {
+		t.Fatalf("evicted %v, want %v", log.keys, want)
+	}
+	if c.Weight() != 8 {
+		t.Fatalf("Weight() = %d, want 8", c.Weight())
+	}
+	if err := c.Put("y", 11); err == nil {
+		t.Fatal("Put with weight 11 into capacity 10 succeeded")
+	}
+
+	if c.Remove("b") {
+		t.Fatal("Remove on a ghost entry returned true")
+	}
+	if !c.Remove("a") || c.Weight() != 4 {
+		t.Fatalf("Remove(a) failed, Weight() = %d", c.Weight())
+	}
+	// b的幽灵元素已经删除，重新插入时作为新元素进入t1
+	c.Put("b", 4)
+	if c.m["b"].list != c.t1 {
+		t.Fatal("re-inserted b did not go to t1")
+	}
+	if len(log.keys) != 1 {
+		t.Fatalf("evicted %v, Remove fired the evict callback", log.keys)
+	}
+	if s := c.Stats(); s != (Stats{Hits: 1, Misses: 1, Evictions: 1}) {
+		t.Fatalf("Stats() = %+v, want 1 hit, 1 miss, 1 eviction", s)
+	}
+}
@@ -0,0 +1,239 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Cache
+
+import "errors"
+
+// freqNode 保存访问次数相同的所有元素，频率结点按访问次数从小到大组成双向循环链表
+type freqNode struct {
+	count   uint64
+	entries *entryList
+	prev    *freqNode
+	next    *freqNode
+}
+
+// unsafeLFUCache 淘汰访问次数最少的元素，访问次数相同时淘汰最久未访问的元素
+// 所有操作的时间复杂度都是O(1)
+type unsafeLFUCache struct {
+	m map[interface{}]*entry
+
+	// freqs 频率链表的哨兵结点，freqs.next是访问次数最少的结点
+	freqs    freqNode
+	size     int
+	weight   int
+	capacity int
+	weigher  Weigher
+	onEvict  EvictCallback
+	stats    Stats
+}
+
+// NewUnsafeLFUCache 返回一个容量为capacity的LFU缓存，weigher与onEvict可以为nil
+func NewUnsafeLFUCache(capacity int, weigher Weigher, onEvict EvictCallback) (*unsafeLFUCache, error) {
+	if err := checkCapacity(capacity); err != nil {
+		return nil, err
+	}
+
+	c := &unsafeLFUCache{
+		m:        make(map[interface{}]*entry),
+		capacity: capacity,
+		weigher:  weigher,
+		onEvict:  onEvict,
+		stats:    Stats{},
+	}
+	c.freqs.next = &c.freqs
+	c.freqs.prev = &c.freqs
+
+	return c, nil
+}
+
+// insertFreqAfter 在结点prev之后插入一个访问次数为count的频率结点
+func (c *unsafeLFUCache) insertFreqAfter(prev *freqNode, count uint64) *freqNode {
+	f := &freqNode{
+		count:   count,
+		entries: newEntryList(),
+		prev:    prev,
+		next:    prev.next,
+	}
+	prev.next.prev = f
+	prev.next = f
+
+	return f
+}
+
+func (c *unsafeLFUCache) removeFreq(f *freqNode) {
+	f.prev.next = f.next
+	f.next.prev = f.prev
+}
+
+// detach 将元素从所在的频率结点中移除，频率结点为空时一并删除
+func (c *unsafeLFUCache) detach(e *entry) {
+	f := e.freq
+	f.entries.remove(e)
+	e.freq = nil
+	if f.entries.size == 0 {
+		c.removeFreq(f)
+	}
+}
+
+// increment 将元素的访问次数加一
+func (c *unsafeLFUCache) increment(e *entry) {
+	f := e.freq
+	next := f.next
+	if next == &c.freqs || next.count != f.count+1 {
+		next = c.insertFreqAfter(f, f.count+1)
+	}
+
+	c.detach(e)
+	next.entries.pushFront(e)
+	e.freq = next
+}
+
+func (c *unsafeLFUCache) Get(key interface{}) (interface{}, bool) {
+	e, ok := c.m[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.stats.Hits++
+	c.increment(e)
+
+	return e.value, true
+}
+
+func (c *unsafeLFUCache) Put(key, value interface{}) error {
+	w, err := weigh(c.weigher, key, value)
+	if err != nil {
+		return err
+	}
+	if w > c.capacity {
+		return errors.New("Weight of value exceeds capacity.")
+	}
+
+	if e, ok := c.m[key]; ok {
+		e.value = value
+		c.weight += w - e.weight
+		e.freq.entries.setWeight(e, w)
+		c.increment(e)
+		c.evictUntilFit(e)
+		return nil
+	}
+
+	// 先淘汰再插入，避免新元素因为访问次数最少而被立即淘汰
+	for c.weight+w > c.capacity {
+		c.evict(c.freqs.next.entries.back())
+	}
+
+	first := c.freqs.next
+	if first == &c.freqs || first.count != 1 {
+		first = c.insertFreqAfter(&c.freqs, 1)
+	}
+
+	e := &entry{key: key, value: value, weight: w, freq: first}
+	first.entries.pushFront(e)
+	c.m[key] = e
+	c.size++
+	c.weight += w
+
+	return nil
+}
+
+// evictUntilFit 淘汰元素直到总权重不超过容量，keep不会被淘汰
+func (c *unsafeLFUCache) evictUntilFit(keep *entry) {
+	for c.weight > c.capacity {
+		for f := c.freqs.next; f != &c.freqs; f = f.next {
+			if e := f.entries.back(); e != keep {
+				c.evict(e)
+				break
+			} else if f.entries.size > 1 {
+				c.evict(e.prev)
+				break
+			}
+		}
+	}
+}
+
+func (c *unsafeLFUCache) evict(e *entry) {
+	c.remove(e)
+	c.stats.Evictions++
+
+	if c.onEvict != nil {
+		c.onEvict(e.key, e.value)
+	}
+}
+
+func (c *unsafeLFUCache) remove(e *entry) {
+	c.detach(e)
+	delete(c.m, e.key)
+	c.size--
+	c.weight -= e.weight
+}
+
+func (c *unsafeLFUCache) Peek(key interface{}) (interface{}, bool) {
+	e, ok := c.m[key]
+	if !ok {
+		return nil, false
+	}
+
+	return e.value, true
+}
+
+func (c *unsafeLFUCache) Remove(key interface{}) bool {
+	e, ok := c.m[key]
+	if !ok {
+		return false
+	}
+
+	c.remove(e)
+
+	return true
+}
+
+// Frequency 返回key的访问次数，key不存在时返回0
+func (c *unsafeLFUCache) Frequency(key interface{}) uint64 {
+	e, ok := c.m[key]
+	if !ok {
+		return 0
+	}
+
+	return e.freq.count
+}
+
+func (c *unsafeLFUCache) Len() int {
+	return c.size
+}
+
+func (c *unsafeLFUCache) Weight() int {
+	return c.weight
+}
+
+func (c *unsafeLFUCache) Capacity() int {
+	return c.capacity
+}
+
+func (c *unsafeLFUCache) Stats() Stats {
+	return c.stats
+}
+
+func (c *unsafeLFUCache) Clear() {
+	c.m = make(map[interface{}]*entry)
+	c.freqs.next = &c.freqs
+	c.freqs.prev = &c.freqs
+	c.size = 0
+	c.weight = 0
+}
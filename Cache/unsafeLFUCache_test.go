@@ -0,0 +1,160 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Cache
+
+import (
+	"reflect"
+	"testing"
+)
+
+// 淘汰访问次数最少的元素，次数相同时淘汰最久未访问的元素
+func TestLFUCacheEvictionOrder(t *testing.T) {
+	log := &evictLog{}
+	c, err := NewUnsafeLFUCache(3, nil, log.onEvict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Put("c", 3)
+	c.Get("a")
+	c.Get("a")
+	c.Get("b")
+	c.Peek("c")
+
+	c.Put("d", 4)
+	// 新元素的访问次数最少，会先于更早但访问更多的元素被淘汰
+	c.Put("e", 5)
+	if want := []interface{}{"c", "d"}; !reflect.DeepEqual(log.keys, want) {
+		t.Fatalf("evicted %v, want %v", log.keys, want)
+	}
+
+	for k, want := range map[string]uint64{"a": 3, "b": 2, "e": 1, "c": 0} {
+		if got := c.Frequency(k); got != want {
+			t.Fatalf("Frequency(%q) = %d, want %d", k, got, want)
+		}
+	}
+
+	// 更新已有的key也算一次访问
+	c.Put("e", 50)
+	c.Put("e", 500)
+	if got := c.Frequency("e"); got != 3 {
+		t.Fatalf("Frequency(e) after two updates = %d, want 3", got)
+	}
+	c.Put("f", 6)
+	if want := []interface{}{"c", "d", "b"}; !reflect.DeepEqual(log.keys, want) {
+		t.Fatalf("evicted %v, want %v", log.keys, want)
+	}
+
+	// 访问次数都为1时退化为LRU
+	log = &evictLog{}
+	c, _ = NewUnsafeLFUCache(2, nil, log.onEvict)
+	c.Put("x", 1)
+	c.Put("y", 2)
+	c.Put("z", 3)
+	if want := []interface{}{"x"}; !reflect.DeepEqual(log.keys, want) {
+		t.Fatalf("evicted %v with equal frequencies, want %v", log.keys, want)
+	}
+}
+
+// 更新元素权重时淘汰其他元素，而不会淘汰被更新的元素本身
+func TestLFUCacheWeights(t *testing.T) {
+	log := &evictLog{}
+	c, err := NewUnsafeLFUCache(10, intWeigher, log.onEvict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Put("a", 3)
+	c.Put("b", 3)
+	c.Put("c", 3)
+	c.Get("b")
+	c.Get("c")
+
+	// a的访问次数变为2，与b、c相同但最近被访问，因此先淘汰b再淘汰c
+	c.Put("a", 8)
+	if want := []interface{}{"b", "c"}; !reflect.DeepEqual(log.keys, want) {
+		t.Fatalf("evicted %v, want %v", log.keys, want)
+	}
+	if c.Weight() != 8 || c.Len() != 1 {
+		t.Fatalf("Weight(), Len() = %d, %d, want 8, 1", c.Weight(), c.Len())
+	}
+
+	// 新元素插入之前先淘汰足够的权重
+	c.Put("d", 2)
+	c.Put("e", 2)
+	if want := []interface{}{"b", "c", "d"}; !reflect.DeepEqual(log.keys, want) {
+		t.Fatalf("evicted %v, want %v", log.keys, want)
+	}
+	if c.Weight() != 10 || c.Len() != 2 {
+		t.Fatalf("Weight(), Len() = %d, %d, want 10, 2", c.Weight(), c.Len())
+	}
+
+	if err := c.Put("x", 11); err == nil {
+		t.Fatal("Put with weight 11 into capacity 10 succeeded")
+	}
+	if c.Weight() != 10 || c.Len() != 2 {
+		t.Fatal("rejected Put changed the cache")
+	}
+}
+
+// Remove不触发淘汰回调并清除访问次数，统计信息只计入Get和淘汰
+func TestLFUCacheStats(t *testing.T) {
+	log := &evictLog{}
+	c, err := NewUnsafeLFUCache(2, nil, log.onEvict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Put(1, "one")
+	c.Put(2, "two")
+	c.Get(2)
+	c.Get(2)
+	c.Get(3)
+	c.Peek(1)
+	if !c.Remove(2) || c.Remove(2) {
+		t.Fatal("Remove(2) twice did not return true, false")
+	}
+	if got := c.Frequency(2); got != 0 {
+		t.Fatalf("Frequency(2) after Remove = %d, want 0", got)
+	}
+	c.Put(2, "two")
+	if got := c.Frequency(2); got != 1 {
+		t.Fatalf("Frequency(2) after re-Put = %d, want 1", got)
+	}
+	c.Put(3, "three")
+
+	if want := []interface{}{1}; !reflect.DeepEqual(log.keys, want) {
+		t.Fatalf("evicted %v, want %v", log.keys, want)
+	}
+	if s := c.Stats(); s != (Stats{Hits: 2, Misses: 1, Evictions: 1}) {
+		t.Fatalf("Stats() = %+v, want 2 hits, 1 miss, 1 eviction", s)
+	}
+
+	c.Clear()
+	if c.Len() != 0 || c.Weight() != 0 || c.Frequency(3) != 0 {
+		t.Fatal("Clear left elements behind")
+	}
+	c.Put(4, "four")
+	c.Put(5, "five")
+	c.Put(6, "six")
+	if want := []interface{}{1, 4}; !reflect.DeepEqual(log.keys, want) {
+		t.Fatalf("evicted %v after Clear, want %v", log.keys, want)
+	}
+}
@@ -0,0 +1,138 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Cache
+
+import "errors"
+
+// unsafeLRUCache 淘汰最久未访问的元素
+type unsafeLRUCache struct {
+	m        map[interface{}]*entry
+	l        *entryList
+	capacity int
+	weigher  Weigher
+	onEvict  EvictCallback
+	stats    Stats
+}
+
+// NewUnsafeLRUCache 返回一个容量为capacity的LRU缓存，weigher与onEvict可以为nil
+func NewUnsafeLRUCache(capacity int, weigher Weigher, onEvict EvictCallback) (*unsafeLRUCache, error) {
+	if err := checkCapacity(capacity); err != nil {
+		return nil, err
+	}
+
+	return &unsafeLRUCache{
+		m:        make(map[interface{}]*entry),
+		l:        newEntryList(),
+		capacity: capacity,
+		weigher:  weigher,
+		onEvict:  onEvict,
+		stats:    Stats{},
+	}, nil
+}
+
+func (c *unsafeLRUCache) Get(key interface{}) (interface{}, bool) {
+	e, ok := c.m[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.stats.Hits++
+	c.l.moveToFront(e)
+
+	return e.value, true
+}
+
+func (c *unsafeLRUCache) Put(key, value interface{}) error {
+	w, err := weigh(c.weigher, key, value)
+	if err != nil {
+		return err
+	}
+	if w > c.capacity {
+		return errors.New("Weight of value exceeds capacity.")
+	}
+
+	if e, ok := c.m[key]; ok {
+		e.value = value
+		c.l.setWeight(e, w)
+		c.l.moveToFront(e)
+	} else {
+		e = &entry{key: key, value: value, weight: w}
+		c.m[key] = e
+		c.l.pushFront(e)
+	}
+
+	// 新元素位于链表头部且权重不超过容量，所以不会被淘汰
+	for c.l.weight > c.capacity {
+		c.evict(c.l.back())
+	}
+
+	return nil
+}
+
+func (c *unsafeLRUCache) evict(e *entry) {
+	c.l.remove(e)
+	delete(c.m, e.key)
+	c.stats.Evictions++
+
+	if c.onEvict != nil {
+		c.onEvict(e.key, e.value)
+	}
+}
+
+func (c *unsafeLRUCache) Peek(key interface{}) (interface{}, bool) {
+	e, ok := c.m[key]
+	if !ok {
+		return nil, false
+	}
+
+	return e.value, true
+}
+
+func (c *unsafeLRUCache) Remove(key interface{}) bool {
+	e, ok := c.m[key]
+	if !ok {
+		return false
+	}
+
+	c.l.remove(e)
+	delete(c.m, key)
+
+	return true
+}
+
+func (c *unsafeLRUCache) Len() int {
+	return c.l.size
+}
+
+func (c *unsafeLRUCache) Weight() int {
+	return c.l.weight
+}
+
+func (c *unsafeLRUCache) Capacity() int {
+	return c.capacity
+}
+
+func (c *unsafeLRUCache) Stats() Stats {
+	return c.stats
+}
+
+func (c *unsafeLRUCache) Clear() {
+	c.m = make(map[interface{}]*entry)
+	c.l = newEntryList()
+}
@@ -0,0 +1,165 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Cache
+
+import (
+	"reflect"
+	"testing"
+)
+
+// evictLog 记录淘汰回调收到的key
+type evictLog struct {
+	keys   []interface{}
+	values []interface{}
+}
+
+func (l *evictLog) onEvict(key, value interface{}) {
+	l.keys = append(l.keys, key)
+	l.values = append(l.values, value)
+}
+
+// intWeigher 以值本身作为权重
+func intWeigher(key, value interface{}) int {
+	return value.(int)
+}
+
+// 淘汰最久未访问的元素，Get会刷新访问顺序而Peek不会
+func TestLRUCacheEvictionOrder(t *testing.T) {
+	log := &evictLog{}
+	c, err := NewUnsafeLRUCache(3, nil, log.onEvict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range []string{"a", "b", "c"} {
+		if err := c.Put(k, k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Get("a")
+	c.Peek("b")
+	c.Put("d", "d")
+	c.Put("e", "e")
+
+	if want := []interface{}{"b", "c"}; !reflect.DeepEqual(log.keys, want) {
+		t.Fatalf("evicted %v, want %v", log.keys, want)
+	}
+	for _, k := range []string{"a", "d", "e"} {
+		if _, ok := c.Peek(k); !ok {
+			t.Fatalf("Peek(%q) missed after evictions", k)
+		}
+	}
+
+	// 更新已有的key同样会刷新访问顺序
+	c.Put("a", "A")
+	c.Put("f", "f")
+	if want := []interface{}{"b", "c", "d"}; !reflect.DeepEqual(log.keys, want) {
+		t.Fatalf("evicted %v, want %v", log.keys, want)
+	}
+	if v, _ := c.Peek("a"); v != "A" {
+		t.Fatalf("Peek(a) = %v, want A", v)
+	}
+	if c.Len() != 3 {
+		t.Fatalf("Len() = %d, want 3", c.Len())
+	}
+}
+
+// 按权重淘汰，更新权重会触发淘汰，超过容量的元素被拒绝
+func TestLRUCacheWeights(t *testing.T) {
+	log := &evictLog{}
+	c, err := NewUnsafeLRUCache(10, intWeigher, log.onEvict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Put("a", 4)
+	c.Put("b", 4)
+	c.Put("c", 4)
+	if want := []interface{}{"a"}; !reflect.DeepEqual(log.keys, want) {
+		t.Fatalf("evicted %v, want %v", log.keys, want)
+	}
+	if c.Weight() != 8 || c.Len() != 2 {
+		t.Fatalf("Weight(), Len() = %d, %d, want 8, 2", c.Weight(), c.Len())
+	}
+
+	// b变重之后位于头部，需要淘汰c才能容纳
+	c.Put("b", 9)
+	if want := []interface{}{"a", "c"}; !reflect.DeepEqual(log.keys, want) {
+		t.Fatalf("evicted %v, want %v", log.keys, want)
+	}
+	if c.Weight() != 9 || c.Len() != 1 {
+		t.Fatalf("Weight(), Len() = %d, %d, want 9, 1", c.Weight(), c.Len())
+	}
+
+	if err := c.Put("x", 11); err == nil {
+		t.Fatal("Put with weight 11 into capacity 10 succeeded")
+	}
+	if _, ok := c.Peek("x"); ok || c.Weight() != 9 {
+		t.Fatal("rejected Put changed the cache")
+	}
+
+	if err := c.Put("z", 0); err == nil {
+		t.Fatal("Put with weight 0 succeeded")
+	}
+}
+
+// Remove不触发淘汰回调，统计信息只计入Get和淘汰
+func TestLRUCacheStats(t *testing.T) {
+	log := &evictLog{}
+	c, err := NewUnsafeLRUCache(2, nil, log.onEvict)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Put(1, "one")
+	c.Put(2, "two")
+	c.Get(1)
+	c.Get(1)
+	c.Get(3)
+	c.Peek(2)
+	c.Peek(3)
+	if !c.Remove(2) || c.Remove(2) {
+		t.Fatal("Remove(2) twice did not return true, false")
+	}
+	c.Put(3, "three")
+	c.Put(4, "four")
+
+	if want := []interface{}{1}; !reflect.DeepEqual(log.keys, want) {
+		t.Fatalf("evicted %v, want %v", log.keys, want)
+	}
+	if want := []interface{}{"one"}; !reflect.DeepEqual(log.values, want) {
+		t.Fatalf("evicted values %v, want %v", log.values, want)
+	}
+	if s := c.Stats(); s != (Stats{Hits: 2, Misses: 1, Evictions: 1}) {
+		t.Fatalf("Stats() = %+v, want 2 hits, 1 miss, 1 eviction", s)
+	}
+	if r := c.Stats().HitRate(); r != 2.0/3 {
+		t.Fatalf("HitRate() = %v, want 2/3", r)
+	}
+
+	c.Clear()
+	if c.Len() != 0 || c.Weight() != 0 {
+		t.Fatalf("Len(), Weight() after Clear = %d, %d", c.Len(), c.Weight())
+	}
+	if _, ok := c.Get(3); ok {
+		t.Fatal("Get(3) hit after Clear")
+	}
+	if len(log.keys) != 1 {
+		t.Fatal("Clear fired the evict callback")
+	}
+}
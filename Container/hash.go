@@ -0,0 +1,183 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Container
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"reflect"
+)
+
+// Hash128 计算value的128位哈希值，以两个uint64的形式返回
+// 每种编码都带有类型标记，因此"1"、[]byte("1")与true不会因为编码相同而冲突；
+// 整数类型按数值编码，因此int(5)与int64(5)的哈希值相同；字符串与[]byte按内容编码；
+// 指针、通道、函数、map和unsafe.Pointer按地址编码，修改指向的内容不会改变哈希值；
+// 结构体、数组和切片逐个编码其中的字段或元素，包括未导出的字段
+func Hash128(value interface{}) (uint64, uint64, error) {
+	b, err := valueBytes(value)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	h := fnv.New128a()
+	_, _ = h.Write(b)
+	sum := h.Sum(nil)
+
+	// FNV的输出在低位上分布不够均匀，再经过一次混淆使每一位都足够随机
+	return fmix64(binary.BigEndian.Uint64(sum[:8])), fmix64(binary.BigEndian.Uint64(sum[8:])), nil
+}
+
+// Hash64 计算value的64位哈希值
+func Hash64(value interface{}) (uint64, error) {
+	h1, _, err := Hash128(value)
+	return h1, err
+}
+
+// fmix64 MurmurHash3中的64位混淆函数
+func fmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+
+	return k
+}
+
+// 编码中使用的类型标记
+const (
+	tagNil byte = iota
+	tagBool
+	tagInt
+	tagFloat
+	tagComplex
+	tagString
+	tagBytes
+	tagPointer
+	tagArray
+	tagStruct
+	tagInterface
+)
+
+// valueBytes 将value编码为带类型标记的字节切片
+func valueBytes(value interface{}) ([]byte, error) {
+	b := make([]byte, 0, 16)
+
+	// 常见类型不经过反射
+	switch t := value.(type) {
+	case nil:
+		return append(b, tagNil), nil
+	case string:
+		return appendString(append(b, tagString), t), nil
+	case []byte:
+		return appendString(append(b, tagBytes), string(t)), nil
+	case bool:
+		return appendBool(append(b, tagBool), t), nil
+	case int:
+		return appendUint64(append(b, tagInt), uint64(t)), nil
+	case int64:
+		return appendUint64(append(b, tagInt), uint64(t)), nil
+	case uint64:
+		return appendUint64(append(b, tagInt), t), nil
+	case float64:
+		return appendFloat(append(b, tagFloat), t), nil
+	}
+
+	return appendValue(b, reflect.ValueOf(value)), nil
+}
+
+// appendValue 按照v的种类编码v
+func appendValue(b []byte, v reflect.Value) []byte {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return append(b, tagNil)
+	case reflect.Bool:
+		return appendBool(append(b, tagBool), v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return appendUint64(append(b, tagInt), uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return appendUint64(append(b, tagInt), v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return appendFloat(append(b, tagFloat), v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return appendFloat(appendFloat(append(b, tagComplex), real(c)), imag(c))
+	case reflect.String:
+		return appendString(append(b, tagString), v.String())
+	case reflect.Ptr, reflect.Chan, reflect.Func, reflect.Map, reflect.UnsafePointer:
+		return appendUint64(append(b, tagPointer), uint64(v.Pointer()))
+	case reflect.Interface:
+		if v.IsNil() {
+			return append(b, tagNil)
+		}
+		return appendValue(append(b, tagInterface), v.Elem())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return appendString(append(b, tagBytes), string(v.Bytes()))
+		}
+		fallthrough
+	case reflect.Array:
+		b = appendUint64(append(b, tagArray), uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			b = appendValue(b, v.Index(i))
+		}
+		return b
+	case reflect.Struct:
+		// 结构体的标记后带上类型名，字段相同的不同结构体不会冲突
+		b = appendString(append(b, tagStruct), v.Type().String())
+		for i := 0; i < v.NumField(); i++ {
+			b = appendValue(b, v.Field(i))
+		}
+		return b
+	default:
+		return append(b, tagNil)
+	}
+}
+
+func appendBool(b []byte, t bool) []byte {
+	if t {
+		return append(b, 1)
+	}
+
+	return append(b, 0)
+}
+
+func appendUint64(b []byte, u uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], u)
+
+	return append(b, buf[:]...)
+}
+
+// appendFloat 编码浮点数，0与-0相等，因此编码相同
+func appendFloat(b []byte, f float64) []byte {
+	if f == 0 {
+		f = 0
+	}
+
+	return appendUint64(b, math.Float64bits(f))
+}
+
+// appendString 先编码长度再编码内容，使嵌套在结构体或数组中的字符串不会互相混淆
+func appendString(b []byte, s string) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(s)))
+
+	return append(append(b, buf[:n]...), s...)
+}
@@ -0,0 +1,120 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Container
+
+import (
+	"math"
+	"testing"
+)
+
+// mustHash 返回value的128位哈希值
+func mustHash(t *testing.T, value interface{}) [2]uint64 {
+	t.Helper()
+	h1, h2, err := Hash128(value)
+	if err != nil {
+		t.Fatalf("Hash128(%#v): %v", value, err)
+	}
+
+	return [2]uint64{h1, h2}
+}
+
+// 指针、map、chan和函数按地址计算哈希值，与指向的内容无关
+func TestHashPointersByAddress(t *testing.T) {
+	type node struct {
+		value int
+		next  *node
+	}
+
+	a, b := &node{value: 1}, &node{value: 1}
+	ha := mustHash(t, a)
+	if ha == mustHash(t, b) {
+		t.Fatal("distinct pointers to equal values hash the same")
+	}
+
+	a.value = 2
+	a.next = b
+	if mustHash(t, a) != ha {
+		t.Fatal("mutating the pointee changed the pointer's hash")
+	}
+
+	// 自引用的结构不会导致无限递归
+	a.next = a
+	if mustHash(t, a) != ha {
+		t.Fatal("a self-referencing pointer hashes differently")
+	}
+	if mustHash(t, *a) == mustHash(t, *b) {
+		t.Fatal("structs with different fields hash the same")
+	}
+
+	m := map[int]int{1: 1}
+	hm := mustHash(t, m)
+	m[2] = 2
+	if mustHash(t, m) != hm {
+		t.Fatal("adding to a map changed its hash")
+	}
+	if mustHash(t, map[int]int{1: 1}) == hm {
+		t.Fatal("distinct maps hash the same")
+	}
+
+	ch := make(chan int, 1)
+	hc := mustHash(t, ch)
+	ch <- 1
+	if mustHash(t, ch) != hc {
+		t.Fatal("sending on a channel changed its hash")
+	}
+}
+
+// 相等的值哈希值相同，类型标记使编码相同但种类不同的值不冲突
+func TestHashValues(t *testing.T) {
+	type pair struct{ A, B string }
+	type other struct{ A, B string }
+
+	same := [][2]interface{}{
+		{int(5), int64(5)},
+		{int8(-1), int64(-1)},
+		{uint16(7), uint64(7)},
+		{float32(0.5), 0.5},
+		{0.0, math.Copysign(0, -1)},
+		{"ab", "ab"},
+		{[]byte("ab"), []byte("ab")},
+		{pair{"a", "b"}, pair{"a", "b"}},
+		{[]interface{}{1, "x"}, []interface{}{int64(1), "x"}},
+	}
+	for _, c := range same {
+		if mustHash(t, c[0]) != mustHash(t, c[1]) {
+			t.Fatalf("%#v and %#v hash differently", c[0], c[1])
+		}
+	}
+
+	different := [][2]interface{}{
+		{"1", []byte("1")},
+		{"1", 1},
+		{true, 1},
+		{nil, 0},
+		{1, 1.0},
+		{pair{"a", "b"}, other{"a", "b"}},
+		{[2]string{"ab", "c"}, [2]string{"a", "bc"}},
+		{[]int{1, 2}, []int{2, 1}},
+		{"", []int{}},
+	}
+	for _, c := range different {
+		if mustHash(t, c[0]) == mustHash(t, c[1]) {
+			t.Fatalf("%#v and %#v hash the same", c[0], c[1])
+		}
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Container
+
+import (
+	"GTL/GSync"
+)
+
+// Transactional 可以参与多容器事务的safe容器
+type Transactional interface {
+	// Locker 返回保护容器的读写锁
+	Locker() *GSync.RWMutex
+
+	// UnsafeView 返回不加锁的内部容器，只能在持有锁时使用
+	UnsafeView() interface{}
+
+	// Snapshot 保存容器当前的内容，返回的函数把容器恢复到保存时的状态，调用时必须持有写锁
+	Snapshot() func()
+}
+
+// Transact 锁住所有容器后执行f，views[i]是containers[i]不加锁的内部容器
+// 所有容器按照锁的地址顺序加写锁，多个事务同时锁住相同的容器时不会死锁；
+// f返回错误或者panic时，所有容器都会恢复到执行f之前的状态。
+// 为了能够回滚，每个容器在执行f之前都会被复制一次，views不能在f返回后继续使用
+func Transact(f func(views []interface{}) error, containers ...Transactional) (err error) {
+	ml := GSync.NewMultiLock()
+	views := make([]interface{}, len(containers))
+	for i, c := range containers {
+		ml.Add(c.Locker(), true)
+		views[i] = c.UnsafeView()
+	}
+
+	ml.Lock()
+	defer ml.Unlock()
+
+	restores := make([]func(), len(containers))
+	for i, c := range containers {
+		restores[i] = c.Snapshot()
+	}
+	rollback := func() {
+		for i := len(restores) - 1; i >= 0; i-- {
+			restores[i]()
+		}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			rollback()
+			panic(r)
+		}
+	}()
+
+	err = f(views)
+	if err != nil {
+		rollback()
+	}
+
+	return err
+}
@@ -0,0 +1,167 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Container
+
+import (
+	"GTL/GSync"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sliceContainer 用于测试的Transactional实现
+type sliceContainer struct {
+	m *GSync.RWMutex
+	s []int
+}
+
+func newSliceContainer(values ...int) *sliceContainer {
+	return &sliceContainer{m: new(GSync.RWMutex), s: values}
+}
+
+func (c *sliceContainer) Locker() *GSync.RWMutex {
+	return c.m
+}
+
+func (c *sliceContainer) UnsafeView() interface{} {
+	return c
+}
+
+func (c *sliceContainer) Snapshot() func() {
+	old := append([]int(nil), c.s...)
+
+	return func() {
+		c.s = old
+	}
+}
+
+func (c *sliceContainer) get() []int {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	return append([]int(nil), c.s...)
+}
+
+// modifyAll 修改views中的所有容器
+func modifyAll(views []interface{}) {
+	for i, v := range views {
+		c := v.(*sliceContainer)
+		c.s = append(c.s[:0], i*100)
+	}
+}
+
+// f成功时所有修改生效，返回错误或者panic时所有容器恢复原状
+func TestTransactRollback(t *testing.T) {
+	a, b, c := newSliceContainer(1, 2), newSliceContainer(3), newSliceContainer()
+	check := func(when string, want ...[]int) {
+		t.Helper()
+		for i, c := range []*sliceContainer{a, b, c} {
+			if got := c.get(); !reflect.DeepEqual(got, want[i]) {
+				t.Fatalf("%s: container %d = %v, want %v", when, i, got, want[i])
+			}
+		}
+	}
+
+	fail := errors.New("fail")
+	err := Transact(func(views []interface{}) error {
+		modifyAll(views)
+		return fail
+	}, a, b, c)
+	if err != fail {
+		t.Fatalf("Transact() = %v, want %v", err, fail)
+	}
+	check("after error", []int{1, 2}, []int{3}, nil)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Fatalf("recover() = %v, want boom", r)
+			}
+		}()
+		_ = Transact(func(views []interface{}) error {
+			modifyAll(views)
+			panic("boom")
+		}, a, b, c)
+	}()
+	check("after panic", []int{1, 2}, []int{3}, nil)
+
+	err = Transact(func(views []interface{}) error {
+		modifyAll(views)
+		return nil
+	}, c, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("after commit", []int{100}, []int{200}, []int{0})
+}
+
+// 多个事务以不同的顺序传入相同的容器时不会死锁，事务之间互相隔离
+func TestTransactConsistentLockOrder(t *testing.T) {
+	const rounds = 2000
+	a, b := newSliceContainer(rounds), newSliceContainer(rounds)
+
+	transfer := func(views []interface{}) error {
+		from, to := views[0].(*sliceContainer), views[1].(*sliceContainer)
+		from.s[0]--
+		to.s[0]++
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			_ = Transact(transfer, a, b)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			_ = Transact(transfer, b, a)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			_ = Transact(func(views []interface{}) error {
+				if sum := views[0].(*sliceContainer).s[0] + views[1].(*sliceContainer).s[0]; sum != 2*rounds {
+					t.Errorf("transaction saw total %d, want %d", sum, 2*rounds)
+				}
+				return nil
+			}, b, a)
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("transactions deadlocked")
+	}
+
+	if got := [2]int{a.get()[0], b.get()[0]}; got != [2]int{rounds, rounds} {
+		t.Fatalf("balances = %v, want [%d %d]", got, rounds, rounds)
+	}
+}
@@ -17,7 +17,10 @@
 
 package Deque
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+)
 
 func UnsafeQueueExample() {
 	q, err := NewUnsafeDeque(-1, 1, 2, 3, 4, 5)
@@ -64,3 +67,44 @@ func UnsafeQueueExample() {
 func SafeQueueExample() {
 
 }
+
+func WorkStealingDequeExample() {
+	q, err := NewWorkStealingDeque(16)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// 拥有者go程在底部加入任务，其他go程从顶部窃取
+	for i := 0; i < 100; i++ {
+		q.PushBottom(i)
+	}
+
+	var wg sync.WaitGroup
+	stolen := make([]int, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for {
+				_, err := q.Steal()
+				if err != nil {
+					return
+				}
+				stolen[i]++
+			}
+		}(i)
+	}
+
+	owned := 0
+	for {
+		_, err := q.PopBottom()
+		if err != nil {
+			break
+		}
+		owned++
+	}
+	wg.Wait()
+
+	fmt.Println("owned =", owned, "stolen =", stolen)
+}
@@ -17,19 +17,19 @@
 
 package Deque
 
-import "sync"
+import "GTL/GSync"
 
 type safeDeque struct {
 	uq *unsafeDeque
-	m  *sync.RWMutex
+	m  *GSync.RWMutex
 }
 
 func NewSafeDeque(maxSize int, values ...interface{}) (*safeDeque, error) {
-	q, err := NewUnsafeDeque(maxSize, values)
+	q, err := NewUnsafeDeque(maxSize, values...)
 
 	return &safeDeque{
 		uq: q,
-		m:  new(sync.RWMutex),
+		m:  new(GSync.RWMutex),
 	}, err
 }
 
@@ -38,10 +38,20 @@ func NewSafeDequeWithSlice(maxSize int, values []interface{}) (*safeDeque, error
 
 	return &safeDeque{
 		uq: q,
-		m:  new(sync.RWMutex),
+		m:  new(GSync.RWMutex),
 	}, err
 }
 
+// NewSafeDequeWithLocker 返回使用m保护的双端队列，m可以由GSync.NewRWMutexWithLocker创建，为nil时与NewSafeDequeWithSlice相同
+func NewSafeDequeWithLocker(m *GSync.RWMutex, maxSize int, values []interface{}) (*safeDeque, error) {
+	q, err := NewSafeDequeWithSlice(maxSize, values)
+	if m != nil {
+		q.m = m
+	}
+
+	return q, err
+}
+
 func (q *safeDeque) PushFront(value interface{}) error {
 	q.m.Lock()
 	defer q.m.Unlock()
@@ -160,3 +170,31 @@ func (q *safeDeque) UnmarshalJSON(b []byte) error {
 
 	return q.uq.UnmarshalJSON(b)
 }
+
+// Do 持有写锁执行f，f中对u的多次操作整体上是原子的
+// u是不加锁的内部双端队列，不能在f返回后继续使用；f返回错误时已经做出的修改不会回滚，需要回滚时使用Container.Transact
+func (q *safeDeque) Do(f func(u Deque) error) error {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	return f(q.uq)
+}
+
+// Locker 返回保护双端队列的读写锁
+func (q *safeDeque) Locker() *GSync.RWMutex {
+	return q.m
+}
+
+// UnsafeView 返回不加锁的内部双端队列，只能在持有锁时使用
+func (q *safeDeque) UnsafeView() interface{} {
+	return q.uq
+}
+
+// Snapshot 保存双端队列当前的内容，返回的函数把双端队列恢复到保存时的状态，调用时必须持有写锁
+func (q *safeDeque) Snapshot() func() {
+	old := q.uq.clone()
+
+	return func() {
+		*q.uq = *old
+	}
+}
@@ -105,6 +105,11 @@ func (q *unsafeDeque) PushFront(value interface{}) error {
 		prev:  q.head,
 	}
 	node.next = q.head.next
+	if node.next == nil {
+		q.rear = node
+	} else {
+		node.next.prev = node
+	}
 	q.head.next = node
 	q.size++
 
@@ -151,6 +156,11 @@ func (q *unsafeDeque) PopFront() (interface{}, error) {
 
 	value := q.head.next.value
 	q.head.next = q.head.next.next
+	if q.head.next == nil {
+		q.rear = q.head
+	} else {
+		q.head.next.prev = q.head
+	}
 	q.size--
 
 	return value, nil
@@ -163,6 +173,7 @@ func (q *unsafeDeque) PopBack() (interface{}, error) {
 
 	value := q.rear.value
 	q.rear = q.rear.prev
+	q.rear.next = nil
 	q.size--
 
 	return value, nil
@@ -244,7 +255,7 @@ func (q *unsafeDeque) String() string {
 
 // ToSlice 将队列以切片形式返回
 func (q *unsafeDeque) ToSlice() []interface{} {
-	ans := make([]interface{}, q.size)
+	ans := make([]interface{}, 0, q.size)
 
 	for p := q.head.next; p != nil; p = p.next {
 		ans = append(ans, p.value)
@@ -253,6 +264,17 @@ func (q *unsafeDeque) ToSlice() []interface{} {
 	return ans
 }
 
+// clone 复制出一个内容相同的双端队列，元素本身不会被复制
+func (q *unsafeDeque) clone() *unsafeDeque {
+	c, _ := NewUnsafeDeque(-1)
+	for p := q.head.next; p != nil; p = p.next {
+		_ = c.PushBack(p.value)
+	}
+	c.maxSize = q.maxSize
+
+	return c
+}
+
 // MarshalJSON 将Deque中的所有元素以Json数组的形式返回
 func (q *unsafeDeque) MarshalJSON() ([]byte, error) {
 	items := make([]string, 0, q.Size())
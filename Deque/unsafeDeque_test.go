@@ -0,0 +1,127 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Deque
+
+import (
+	"GTL/Container"
+	"reflect"
+	"testing"
+)
+
+// 两端交替入队出队时head与rear保持一致
+func TestUnsafeDequeLinks(t *testing.T) {
+	q, err := NewUnsafeDeque(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 空队列PushFront后rear指向新结点
+	_ = q.PushFront(1)
+	if back, _ := q.Back(); back != 1 {
+		t.Fatalf("Back() after PushFront on empty deque = %v, want 1", back)
+	}
+	_ = q.PushBack(2)
+	_ = q.PushFront(0)
+	if got := q.ToSlice(); !reflect.DeepEqual(got, []interface{}{0, 1, 2}) {
+		t.Fatalf("ToSlice() = %v, want [0 1 2]", got)
+	}
+
+	// PopBack断开旧的尾结点，之后PushBack不会把它带回来
+	if value, _ := q.PopBack(); value != 2 {
+		t.Fatalf("PopBack() = %v, want 2", value)
+	}
+	_ = q.PushBack(3)
+	if got := q.ToSlice(); !reflect.DeepEqual(got, []interface{}{0, 1, 3}) {
+		t.Fatalf("ToSlice() = %v, want [0 1 3]", got)
+	}
+
+	// 从前面弹空后rear回到头结点
+	for q.Size() > 0 {
+		_, _ = q.PopFront()
+	}
+	_ = q.PushBack(4)
+	_ = q.PushFront(5)
+	if got := q.ToSlice(); !reflect.DeepEqual(got, []interface{}{5, 4}) {
+		t.Fatalf("ToSlice() after draining = %v, want [5 4]", got)
+	}
+
+	// 从后面弹空
+	for q.Size() > 0 {
+		_, _ = q.PopBack()
+	}
+	if _, err := q.PopBack(); err == nil {
+		t.Fatal("PopBack() on an empty deque succeeded")
+	}
+	_ = q.PushFront(6)
+	_ = q.PushBack(7)
+	if front, _ := q.Front(); front != 6 {
+		t.Fatalf("Front() = %v, want 6", front)
+	}
+	if back, _ := q.Back(); back != 7 {
+		t.Fatalf("Back() = %v, want 7", back)
+	}
+}
+
+// Transact中panic时双端队列恢复原状，锁被释放
+func TestSafeDequeTransactPanic(t *testing.T) {
+	a, err := NewSafeDeque(-1, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewSafeDeque(-1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Fatalf("recover() = %v, want boom", r)
+			}
+		}()
+		_ = Container.Transact(func(views []interface{}) error {
+			x, y := views[0].(Deque), views[1].(Deque)
+			value, _ := x.PopBack()
+			_ = y.PushFront(value)
+			_, _ = y.PopBack()
+			panic("boom")
+		}, a, b)
+	}()
+
+	if got := a.ToSlice(); !reflect.DeepEqual(got, []interface{}{1, 2}) {
+		t.Fatalf("a after rollback = %v", got)
+	}
+	if got := b.ToSlice(); !reflect.DeepEqual(got, []interface{}{3}) {
+		t.Fatalf("b after rollback = %v", got)
+	}
+
+	_ = a.PushBack(9)
+	if back, _ := a.Back(); back != 9 {
+		t.Fatalf("Back() after rollback = %v, want 9", back)
+	}
+	err = a.Do(func(u Deque) error {
+		_, _ = u.PopFront()
+		return u.PushFront(0)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := a.ToSlice(); !reflect.DeepEqual(got, []interface{}{0, 2, 9}) {
+		t.Fatalf("a after Do = %v, want [0 2 9]", got)
+	}
+}
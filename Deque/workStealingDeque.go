@@ -0,0 +1,181 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Deque
+
+import (
+	"errors"
+	"sync/atomic"
+	"unsafe"
+)
+
+// circularArray 工作窃取队列使用的环形数组，每个位置保存一个指向元素的指针，使用原子操作读写
+type circularArray struct {
+	mask    int64
+	buckets []unsafe.Pointer
+}
+
+func newCircularArray(size int64) *circularArray {
+	return &circularArray{
+		mask:    size - 1,
+		buckets: make([]unsafe.Pointer, size),
+	}
+}
+
+func (a *circularArray) size() int64 {
+	return a.mask + 1
+}
+
+// get 读取下标i处的元素，若该位置为空则返回false
+// 扩容只复制[top, bottom)之间的元素，读到过时top的窃取者可能在新数组中看到空位置
+func (a *circularArray) get(i int64) (interface{}, bool) {
+	p := atomic.LoadPointer(&a.buckets[i&a.mask])
+	if p == nil {
+		return nil, false
+	}
+
+	return *(*interface{})(p), true
+}
+
+func (a *circularArray) put(i int64, value interface{}) {
+	atomic.StorePointer(&a.buckets[i&a.mask], unsafe.Pointer(&value))
+}
+
+// grow 返回一个两倍大小的新数组，并复制下标在[top, bottom)之间的元素
+func (a *circularArray) grow(bottom, top int64) *circularArray {
+	na := newCircularArray(a.size() * 2)
+	for i := top; i < bottom; i++ {
+		atomic.StorePointer(&na.buckets[i&na.mask], atomic.LoadPointer(&a.buckets[i&a.mask]))
+	}
+
+	return na
+}
+
+// workStealingDeque Chase-Lev工作窃取队列
+// 只有拥有者go程可以调用PushBottom与PopBottom，它们在队列底部操作且不需要加锁；
+// 其他go程调用Steal从队列顶部窃取元素，窃取者之间以及与拥有者之间通过对top的CAS操作竞争最后一个元素
+type workStealingDeque struct {
+	top    int64
+	bottom int64
+
+	// array 当前使用的环形数组，扩容时整体替换，窃取者可能仍在读取旧数组
+	array unsafe.Pointer
+}
+
+// NewWorkStealingDeque 返回一个初始容量为capacity的工作窃取队列，容量会被向上取整为2的幂，队列满时自动扩容
+func NewWorkStealingDeque(capacity int) (*workStealingDeque, error) {
+	if capacity <= 0 {
+		return nil, errors.New("Capacity must be positive.")
+	}
+
+	size := int64(1)
+	for size < int64(capacity) {
+		size <<= 1
+	}
+
+	return &workStealingDeque{
+		top:    0,
+		bottom: 0,
+		array:  unsafe.Pointer(newCircularArray(size)),
+	}, nil
+}
+
+func (q *workStealingDeque) loadArray() *circularArray {
+	return (*circularArray)(atomic.LoadPointer(&q.array))
+}
+
+// PushBottom 从队列底部加入元素，只能由拥有者go程调用
+func (q *workStealingDeque) PushBottom(value interface{}) {
+	b := atomic.LoadInt64(&q.bottom)
+	t := atomic.LoadInt64(&q.top)
+	a := q.loadArray()
+
+	if b-t >= a.size()-1 {
+		a = a.grow(b, t)
+		atomic.StorePointer(&q.array, unsafe.Pointer(a))
+	}
+
+	a.put(b, value)
+	atomic.StoreInt64(&q.bottom, b+1)
+}
+
+// PopBottom 从队列底部弹出元素，只能由拥有者go程调用
+func (q *workStealingDeque) PopBottom() (interface{}, error) {
+	// 先减小bottom以阻止窃取者拿走底部元素，再检查top
+	b := atomic.LoadInt64(&q.bottom) - 1
+	a := q.loadArray()
+	atomic.StoreInt64(&q.bottom, b)
+	t := atomic.LoadInt64(&q.top)
+
+	if b < t {
+		atomic.StoreInt64(&q.bottom, t)
+		return nil, errors.New("This deque is empty.")
+	}
+
+	value, _ := a.get(b)
+	if b > t {
+		return value, nil
+	}
+
+	// 只剩最后一个元素，与窃取者竞争
+	ok := atomic.CompareAndSwapInt64(&q.top, t, t+1)
+	atomic.StoreInt64(&q.bottom, t+1)
+	if !ok {
+		return nil, errors.New("This deque is empty.")
+	}
+
+	return value, nil
+}
+
+// Steal 从队列顶部窃取元素，可以由任意go程调用
+func (q *workStealingDeque) Steal() (interface{}, error) {
+	for {
+		// 先读取数组再读取top：扩容时复制的范围从当时的top开始，
+		// 这样读到的数组一定包含下标t处的元素
+		a := q.loadArray()
+		t := atomic.LoadInt64(&q.top)
+		b := atomic.LoadInt64(&q.bottom)
+		if t >= b {
+			return nil, errors.New("This deque is empty.")
+		}
+
+		value, ok := a.get(t)
+		if !ok {
+			// 位置为空说明下标t已被拿走且数组已被替换，重试
+			continue
+		}
+		if atomic.CompareAndSwapInt64(&q.top, t, t+1) {
+			return value, nil
+		}
+		// 与其他窃取者或拥有者竞争失败，重试
+	}
+}
+
+// Size 返回队列中元素数量的近似值，并发修改时结果可能已经过时
+func (q *workStealingDeque) Size() int {
+	b := atomic.LoadInt64(&q.bottom)
+	t := atomic.LoadInt64(&q.top)
+	if b < t {
+		return 0
+	}
+
+	return int(b - t)
+}
+
+func (q *workStealingDeque) Empty() bool {
+	return q.Size() == 0
+}
@@ -0,0 +1,132 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Deque
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// 一个拥有者在底部压入和弹出，多个窃取者同时从顶部窃取，每个元素必须恰好被消费一次
+func TestWorkStealingDequeConcurrentSteal(t *testing.T) {
+	const (
+		items    = 20000
+		stealers = 4
+	)
+
+	// 初始容量很小，使扩容与窃取同时发生
+	q, err := NewWorkStealingDeque(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	consumed := make([]int32, items)
+	consume := func(value interface{}) {
+		atomic.AddInt32(&consumed[value.(int)], 1)
+	}
+
+	var done int32
+	var wg sync.WaitGroup
+	wg.Add(stealers)
+	for i := 0; i < stealers; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				value, err := q.Steal()
+				if err == nil {
+					consume(value)
+					continue
+				}
+				if atomic.LoadInt32(&done) == 1 && q.Empty() {
+					return
+				}
+				runtime.Gosched()
+			}
+		}()
+	}
+
+	for i := 0; i < items; i++ {
+		q.PushBottom(i)
+		// 每压入三个元素弹出一个，让拥有者与窃取者竞争底部的元素
+		if i%3 == 2 {
+			if value, err := q.PopBottom(); err == nil {
+				consume(value)
+			}
+		}
+	}
+	for {
+		value, err := q.PopBottom()
+		if err != nil {
+			break
+		}
+		consume(value)
+	}
+	atomic.StoreInt32(&done, 1)
+	wg.Wait()
+
+	for i, n := range consumed {
+		if n != 1 {
+			t.Fatalf("item %d consumed %d times, want 1", i, n)
+		}
+	}
+	if !q.Empty() {
+		t.Fatalf("Size() = %d after draining, want 0", q.Size())
+	}
+}
+
+// 窃取者读到top后，另一个窃取者拿走该元素，拥有者随后压入元素触发扩容，
+// 新数组中不会复制已被拿走的位置，读取该位置必须返回false而不是解引用空指针
+func TestWorkStealingDequeStaleTopAfterGrow(t *testing.T) {
+	q, err := NewWorkStealingDeque(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		q.PushBottom(i)
+	}
+
+	stale := atomic.LoadInt64(&q.top)
+	if value, err := q.Steal(); err != nil || value != 0 {
+		t.Fatalf("Steal() = %v, %v, want 0, nil", value, err)
+	}
+
+	old := q.loadArray()
+	for i := 3; i < 8; i++ {
+		q.PushBottom(i)
+	}
+	a := q.loadArray()
+	if a == old {
+		t.Fatal("PushBottom did not grow the array")
+	}
+
+	if value, ok := a.get(stale); ok {
+		t.Fatalf("get(%d) on grown array = %v, true, want false", stale, value)
+	}
+
+	for want := 1; want < 8; want++ {
+		value, err := q.Steal()
+		if err != nil || value != want {
+			t.Fatalf("Steal() = %v, %v, want %d, nil", value, err, want)
+		}
+	}
+	if _, err := q.Steal(); err == nil {
+		t.Fatal("Steal() on empty deque succeeded")
+	}
+}
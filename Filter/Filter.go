@@ -0,0 +1,40 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Filter
+
+// Filter 是概率型成员过滤器的接口，只保存元素的哈希信息，内存占用与元素本身的大小无关
+// Add与MightContain分别对应Set.Insert与Set.Contains，
+// MightContain返回false时元素一定不存在，返回true时元素可能存在（存在一定的误判率）
+type Filter interface {
+	// Add 向过滤器中添加元素
+	Add(value interface{}) error
+
+	// MightContain 判断values是否可能全部存在于过滤器中
+	MightContain(values ...interface{}) bool
+
+	// Count 返回已添加的元素数量
+	Count() int
+
+	Clear()
+
+	// MarshalBinary 将过滤器编码为字节切片，所有整数均以小端序存储
+	MarshalBinary() ([]byte, error)
+
+	// UnmarshalBinary 从MarshalBinary产生的字节切片中解析出过滤器
+	UnmarshalBinary(b []byte) error
+}
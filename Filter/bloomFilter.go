@@ -0,0 +1,168 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Filter
+
+import (
+	"GTL/Container"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// maxHashFunctions 哈希函数个数的上限，NewBloomFilter计算出的k约为log2(1 / falsePositiveRate)，不会超过1100
+const maxHashFunctions = 2048
+
+// bloomFilter 布隆过滤器，使用k个哈希函数将元素映射到m位的位数组上
+// 哈希函数由两个独立的哈希值组合而成：g(i) = h1 + i * h2 (Kirsch-Mitzenmacher)
+type bloomFilter struct {
+	// m 位数组的长度
+	m uint64
+
+	// k 哈希函数的个数
+	k uint64
+
+	// count 已添加的元素数量
+	count uint64
+
+	words []uint64
+}
+
+// NewBloomFilter 根据预期元素数量expectedItems和误判率falsePositiveRate计算位数组长度和哈希函数个数
+func NewBloomFilter(expectedItems int, falsePositiveRate float64) (*bloomFilter, error) {
+	if expectedItems <= 0 {
+		return nil, errors.New("Expected item count must be positive.")
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return nil, errors.New("False positive rate must be in (0, 1).")
+	}
+
+	n := float64(expectedItems)
+	m := math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	k := math.Max(1, math.Round(m/n*math.Ln2))
+
+	return newBloomFilter(uint64(m), uint64(k)), nil
+}
+
+func newBloomFilter(m, k uint64) *bloomFilter {
+	return &bloomFilter{
+		m:     m,
+		k:     k,
+		count: 0,
+		words: make([]uint64, (m+63)/64),
+	}
+}
+
+func (f *bloomFilter) Add(value interface{}) error {
+	h1, h2, err := Container.Hash128(value)
+	if err != nil {
+		return err
+	}
+
+	for i := uint64(0); i < f.k; i++ {
+		pos := (h1 + i*h2) % f.m
+		f.words[pos>>6] |= 1 << (pos & 63)
+	}
+	f.count++
+
+	return nil
+}
+
+func (f *bloomFilter) MightContain(values ...interface{}) bool {
+	for _, value := range values {
+		h1, h2, err := Container.Hash128(value)
+		if err != nil {
+			return false
+		}
+
+		for i := uint64(0); i < f.k; i++ {
+			pos := (h1 + i*h2) % f.m
+			if f.words[pos>>6]&(1<<(pos&63)) == 0 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Merge 将other中的元素合并到f中，两个过滤器的参数必须相同
+func (f *bloomFilter) Merge(other *bloomFilter) error {
+	if f.m != other.m || f.k != other.k {
+		return errors.New("Bloom filters have different parameters.")
+	}
+
+	for i := range f.words {
+		f.words[i] |= other.words[i]
+	}
+	f.count += other.count
+
+	return nil
+}
+
+// FalsePositiveRate 根据已添加的元素数量估计当前的误判率
+func (f *bloomFilter) FalsePositiveRate() float64 {
+	return math.Pow(1-math.Exp(-float64(f.k)*float64(f.count)/float64(f.m)), float64(f.k))
+}
+
+func (f *bloomFilter) Count() int {
+	return int(f.count)
+}
+
+func (f *bloomFilter) Clear() {
+	for i := range f.words {
+		f.words[i] = 0
+	}
+	f.count = 0
+}
+
+// MarshalBinary 编码格式为 m | k | count | words
+func (f *bloomFilter) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(24 + 8*len(f.words))
+
+	for _, v := range []interface{}{f.m, f.k, f.count, f.words} {
+		if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (f *bloomFilter) UnmarshalBinary(b []byte) error {
+	r := bytes.NewReader(b)
+
+	var header [3]uint64
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	m, k, count := header[0], header[1], header[2]
+	if m == 0 || k == 0 || k > maxHashFunctions || uint64(r.Len()) != (m+63)/64*8 {
+		return errors.New("Invalid bloom filter data.")
+	}
+
+	words := make([]uint64, (m+63)/64)
+	if err := binary.Read(r, binary.LittleEndian, words); err != nil {
+		return err
+	}
+
+	f.m, f.k, f.count, f.words = m, k, count, words
+
+	return nil
+}
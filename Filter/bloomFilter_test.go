@@ -0,0 +1,142 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Filter
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// 已添加的元素一定被判定为存在，未添加的元素的误判率接近设定值
+func TestBloomFilterFalsePositiveRate(t *testing.T) {
+	const n, p = 10000, 0.01
+	f, err := NewBloomFilter(n, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if err := f.Add(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		if !f.MightContain(i) {
+			t.Fatalf("false negative for %d", i)
+		}
+	}
+
+	const trials = 100000
+	fp := 0
+	for i := n; i < n+trials; i++ {
+		if f.MightContain(i) {
+			fp++
+		}
+	}
+	rate := float64(fp) / trials
+	if rate > 2*p {
+		t.Fatalf("false positive rate = %v, want about %v", rate, p)
+	}
+	if est := f.FalsePositiveRate(); est < p/2 || est > 2*p {
+		t.Fatalf("FalsePositiveRate() = %v at the expected load, want about %v", est, p)
+	}
+}
+
+// 合并两个参数相同的过滤器等价于把两边的元素添加到同一个过滤器中
+func TestBloomFilterMerge(t *testing.T) {
+	newFilter := func() *bloomFilter {
+		f, err := NewBloomFilter(1000, 0.01)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	evens, odds, all := newFilter(), newFilter(), newFilter()
+	for i := 0; i < 1000; i++ {
+		if i%2 == 0 {
+			evens.Add(i)
+		} else {
+			odds.Add(i)
+		}
+		all.Add(i)
+	}
+
+	if err := evens.Merge(odds); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(evens, all) {
+		t.Fatal("merged filter differs from a filter built from both halves")
+	}
+	if evens.Count() != 1000 {
+		t.Fatalf("Count() after Merge = %d, want 1000", evens.Count())
+	}
+
+	other, _ := NewBloomFilter(1000, 0.001)
+	if err := evens.Merge(other); err == nil {
+		t.Fatal("Merge of filters with different parameters succeeded")
+	}
+}
+
+// 编码之后解码得到相同的过滤器，参数不合法的数据被拒绝
+func TestBloomFilterMarshal(t *testing.T) {
+	f, _ := NewBloomFilter(500, 0.05)
+	for i := 0; i < 300; i++ {
+		f.Add(i)
+	}
+
+	b, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g bloomFilter
+	if err := g.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&g, f) {
+		t.Fatal("decoded filter differs from the original")
+	}
+
+	// 编码的头部依次是m、k、count
+	withK := func(k uint64) []byte {
+		c := append([]byte(nil), b...)
+		binary.LittleEndian.PutUint64(c[8:], k)
+		return c
+	}
+	for _, k := range []uint64{0, maxHashFunctions + 1, 1 << 62} {
+		if err := g.UnmarshalBinary(withK(k)); err == nil {
+			t.Fatalf("UnmarshalBinary accepted k = %d", k)
+		}
+	}
+	if err := g.UnmarshalBinary(withK(maxHashFunctions)); err != nil {
+		t.Fatalf("UnmarshalBinary rejected k = %d: %v", maxHashFunctions, err)
+	}
+	if err := g.UnmarshalBinary(b[:len(b)-8]); err == nil {
+		t.Fatal("UnmarshalBinary accepted truncated data")
+	}
+
+	// 最小的误判率对应的k仍然在上限之内
+	h, err := NewBloomFilter(1, 5e-324)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.k > maxHashFunctions {
+		t.Fatalf("NewBloomFilter chose k = %d, above maxHashFunctions", h.k)
+	}
+}
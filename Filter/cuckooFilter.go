@@ -0,0 +1,315 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Filter
+
+import (
+	"GTL/Container"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand"
+)
+
+const (
+	// bucketSize 每个桶中指纹的数量
+	bucketSize = 4
+
+	// maxKicks 插入时最多踢出已有指纹的次数
+	maxKicks = 500
+
+	// cuckooLoadFactor 桶数组的目标装载率
+	cuckooLoadFactor = 0.95
+)
+
+// victimCache 当踢出次数达到上限时，保存最后一个无处安放的指纹
+type victimCache struct {
+	used  bool
+	index uint64
+	fp    uint32
+}
+
+// cuckooFilter 布谷鸟过滤器，每个元素以指纹的形式保存在两个候选桶之一，
+// 两个候选桶可以通过指纹相互计算：i2 = i1 ^ hash(fp)，因此支持删除
+type cuckooFilter struct {
+	// buckets 长度为numBuckets * bucketSize，0表示空位
+	buckets    []uint32
+	numBuckets uint64
+
+	// fpBits 指纹的位数
+	fpBits uint32
+	count  uint64
+	victim victimCache
+	rnd    *rand.Rand
+}
+
+// NewCuckooFilter 根据预期元素数量expectedItems和误判率falsePositiveRate计算桶的数量和指纹位数
+func NewCuckooFilter(expectedItems int, falsePositiveRate float64) (*cuckooFilter, error) {
+	if expectedItems <= 0 {
+		return nil, errors.New("Expected item count must be positive.")
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return nil, errors.New("False positive rate must be in (0, 1).")
+	}
+
+	// 误判率约为 2 * bucketSize / 2^fpBits
+	fpBits := math.Ceil(math.Log2(2 * bucketSize / falsePositiveRate))
+	fpBits = math.Min(math.Max(fpBits, 4), 32)
+
+	numBuckets := uint64(1)
+	for float64(numBuckets*bucketSize)*cuckooLoadFactor < float64(expectedItems) {
+		numBuckets <<= 1
+	}
+
+	return newCuckooFilter(numBuckets, uint32(fpBits)), nil
+}
+
+func newCuckooFilter(numBuckets uint64, fpBits uint32) *cuckooFilter {
+	return &cuckooFilter{
+		buckets:    make([]uint32, numBuckets*bucketSize),
+		numBuckets: numBuckets,
+		fpBits:     fpBits,
+		count:      0,
+		victim:     victimCache{},
+		rnd:        rand.New(rand.NewSource(int64(numBuckets)<<8 | int64(fpBits))),
+	}
+}
+
+// indexAndFingerprint 计算元素的第一个候选桶和指纹
+func (f *cuckooFilter) indexAndFingerprint(value interface{}) (uint64, uint32, error) {
+	h1, h2, err := Container.Hash128(value)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	fp := uint32(h2 >> (64 - f.fpBits))
+	if fp == 0 {
+		// 0用来表示空位，所以指纹不能为0
+		fp = 1
+	}
+
+	return h1 & (f.numBuckets - 1), fp, nil
+}
+
+// altIndex 计算指纹fp的另一个候选桶
+func (f *cuckooFilter) altIndex(index uint64, fp uint32) uint64 {
+	return (index ^ uint64(fp)*0x5bd1e995) & (f.numBuckets - 1)
+}
+
+func (f *cuckooFilter) bucket(index uint64) []uint32 {
+	return f.buckets[index*bucketSize : (index+1)*bucketSize]
+}
+
+func (f *cuckooFilter) insertInto(index uint64, fp uint32) bool {
+	b := f.bucket(index)
+	for i := range b {
+		if b[i] == 0 {
+			b[i] = fp
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f *cuckooFilter) bucketContains(index uint64, fp uint32) bool {
+	for _, x := range f.bucket(index) {
+		if x == fp {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f *cuckooFilter) deleteFrom(index uint64, fp uint32) bool {
+	b := f.bucket(index)
+	for i := range b {
+		if b[i] == fp {
+			b[i] = 0
+			return true
+		}
+	}
+
+	return false
+}
+
+// Add 向过滤器中添加元素，过滤器已满时返回错误
+func (f *cuckooFilter) Add(value interface{}) error {
+	if f.victim.used {
+		return errors.New("This filter is full.")
+	}
+
+	i1, fp, err := f.indexAndFingerprint(value)
+	if err != nil {
+		return err
+	}
+
+	i2 := f.altIndex(i1, fp)
+	if f.insertInto(i1, fp) || f.insertInto(i2, fp) {
+		f.count++
+		return nil
+	}
+
+	// 两个候选桶都满了，随机踢出一个指纹并将它移动到它的另一个候选桶
+	index := i1
+	if f.rnd.Intn(2) == 1 {
+		index = i2
+	}
+	for n := 0; n < maxKicks; n++ {
+		b := f.bucket(index)
+		slot := f.rnd.Intn(bucketSize)
+		fp, b[slot] = b[slot], fp
+
+		index = f.altIndex(index, fp)
+		if f.insertInto(index, fp) {
+			f.count++
+			return nil
+		}
+	}
+
+	// 元素已经保存在victim中，本次添加依然成功，但之后的添加都将失败
+	f.victim = victimCache{used: true, index: index, fp: fp}
+	f.count++
+
+	return nil
+}
+
+func (f *cuckooFilter) MightContain(values ...interface{}) bool {
+	for _, value := range values {
+		i1, fp, err := f.indexAndFingerprint(value)
+		if err != nil {
+			return false
+		}
+		i2 := f.altIndex(i1, fp)
+
+		found := f.bucketContains(i1, fp) || f.bucketContains(i2, fp) ||
+			(f.victim.used && f.victim.fp == fp && (f.victim.index == i1 || f.victim.index == i2))
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Delete 删除一个元素，返回元素是否存在
+// 只能删除确实添加过的元素，否则可能误删与之指纹相同的其他元素
+func (f *cuckooFilter) Delete(value interface{}) bool {
+	i1, fp, err := f.indexAndFingerprint(value)
+	if err != nil {
+		return false
+	}
+	i2 := f.altIndex(i1, fp)
+
+	switch {
+	case f.deleteFrom(i1, fp), f.deleteFrom(i2, fp):
+		f.count--
+		// 腾出了空位，尝试将victim重新放回桶中
+		if f.victim.used {
+			v := f.victim
+			f.victim = victimCache{}
+			if !f.insertInto(v.index, v.fp) && !f.insertInto(f.altIndex(v.index, v.fp), v.fp) {
+				f.victim = v
+			}
+		}
+		return true
+	case f.victim.used && f.victim.fp == fp && (f.victim.index == i1 || f.victim.index == i2):
+		f.victim = victimCache{}
+		f.count--
+		return true
+	}
+
+	return false
+}
+
+func (f *cuckooFilter) Count() int {
+	return int(f.count)
+}
+
+// LoadFactor 返回已使用的指纹槽位比例
+func (f *cuckooFilter) LoadFactor() float64 {
+	return float64(f.count) / float64(len(f.buckets))
+}
+
+func (f *cuckooFilter) Clear() {
+	for i := range f.buckets {
+		f.buckets[i] = 0
+	}
+	f.count = 0
+	f.victim = victimCache{}
+}
+
+// MarshalBinary 编码格式为 numBuckets | fpBits | count | victimUsed | victimIndex | victimFp | buckets
+func (f *cuckooFilter) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(33 + 4*len(f.buckets))
+
+	var used uint8
+	if f.victim.used {
+		used = 1
+	}
+
+	for _, v := range []interface{}{
+		f.numBuckets, f.fpBits, f.count, used, f.victim.index, f.victim.fp, f.buckets,
+	} {
+		if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (f *cuckooFilter) UnmarshalBinary(b []byte) error {
+	invalid := errors.New("Invalid cuckoo filter data.")
+	r := bytes.NewReader(b)
+
+	var header struct {
+		NumBuckets  uint64
+		FpBits      uint32
+		Count       uint64
+		VictimUsed  uint8
+		VictimIndex uint64
+		VictimFp    uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+
+	nb := header.NumBuckets
+	if nb == 0 || nb&(nb-1) != 0 || header.FpBits == 0 || header.FpBits > 32 ||
+		uint64(r.Len()) != nb*bucketSize*4 || header.VictimIndex >= nb {
+		return invalid
+	}
+
+	g := newCuckooFilter(nb, header.FpBits)
+	if err := binary.Read(r, binary.LittleEndian, g.buckets); err != nil {
+		return err
+	}
+	g.count = header.Count
+	g.victim = victimCache{
+		used:  header.VictimUsed != 0,
+		index: header.VictimIndex,
+		fp:    header.VictimFp,
+	}
+
+	*f = *g
+
+	return nil
+}
@@ -0,0 +1,204 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Filter
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// 已添加的元素一定被判定为存在，未添加的元素的误判率不超过设定值
+func TestCuckooFilterFalsePositiveRate(t *testing.T) {
+	const n, p = 10000, 0.01
+	f, err := NewCuckooFilter(n, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if err := f.Add(i); err != nil {
+			t.Fatalf("Add(%d): %v", i, err)
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		if !f.MightContain(i) {
+			t.Fatalf("false negative for %d", i)
+		}
+	}
+
+	const trials = 100000
+	fp := 0
+	for i := n; i < n+trials; i++ {
+		if f.MightContain(i) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / trials; rate > p {
+		t.Fatalf("false positive rate = %v, want at most %v", rate, p)
+	}
+}
+
+// 删除之后元素不再存在，重复添加的元素需要删除相同的次数
+func TestCuckooFilterDelete(t *testing.T) {
+	f, _ := NewCuckooFilter(1000, 0.001)
+	for i := 0; i < 500; i++ {
+		f.Add(i)
+	}
+	f.Add(7)
+
+	for i := 0; i < 500; i += 2 {
+		if !f.Delete(i) {
+			t.Fatalf("Delete(%d) = false", i)
+		}
+	}
+	for i := 0; i < 500; i++ {
+		if got, want := f.MightContain(i), i%2 == 1; want && !got {
+			t.Fatalf("MightContain(%d) = false after deleting other elements", i)
+		}
+	}
+	if f.Count() != 251 {
+		t.Fatalf("Count() = %d, want 251", f.Count())
+	}
+
+	if !f.Delete(7) || !f.MightContain(7) {
+		t.Fatal("deleting one copy of 7 removed both")
+	}
+	if !f.Delete(7) || f.MightContain(7) {
+		t.Fatal("7 still present after deleting both copies")
+	}
+
+	absent := -1
+	for f.MightContain(absent) {
+		absent--
+	}
+	if f.Delete(absent) {
+		t.Fatalf("Delete(%d) of an absent element = true", absent)
+	}
+	if f.Count() != 249 {
+		t.Fatalf("Count() = %d, want 249", f.Count())
+	}
+}
+
+// fillCuckoo 添加元素直到过滤器已满，返回成功添加的元素
+func fillCuckoo(t *testing.T, f *cuckooFilter) []int {
+	t.Helper()
+	var added []int
+	for i := 0; ; i++ {
+		if err := f.Add(i); err != nil {
+			return added
+		}
+		added = append(added, i)
+		if len(added) > len(f.buckets)+1 {
+			t.Fatalf("added %d elements into %d slots", len(added), len(f.buckets))
+		}
+	}
+}
+
+// 过滤器满时添加失败，但已添加的元素(包括victim中的)仍然存在，删除之后可以继续添加
+func TestCuckooFilterFull(t *testing.T) {
+	f, _ := NewCuckooFilter(8, 0.01)
+	added := fillCuckoo(t, f)
+
+	if !f.victim.used {
+		t.Fatal("filter reported full without a victim")
+	}
+	if f.Count() != len(added) {
+		t.Fatalf("Count() = %d, want %d", f.Count(), len(added))
+	}
+	for _, v := range added {
+		if !f.MightContain(v) {
+			t.Fatalf("false negative for %d in a full filter", v)
+		}
+	}
+	if err := f.Add(-1); err == nil {
+		t.Fatal("Add into a full filter succeeded")
+	}
+
+	// 只有在victim的候选桶中腾出空位时victim才能放回桶中
+	deleted := 0
+	for f.victim.used {
+		if deleted == len(added) {
+			t.Fatal("victim was never moved back into a bucket")
+		}
+		if !f.Delete(added[deleted]) {
+			t.Fatalf("Delete(%d) from a full filter failed", added[deleted])
+		}
+		deleted++
+	}
+	for _, v := range added[deleted:] {
+		if !f.MightContain(v) {
+			t.Fatalf("false negative for %d after reinserting the victim", v)
+		}
+	}
+	if f.Count() != len(added)-deleted {
+		t.Fatalf("Count() = %d, want %d", f.Count(), len(added)-deleted)
+	}
+	if err := f.Add(-1); err != nil {
+		t.Fatalf("Add after Delete: %v", err)
+	}
+
+	f.Clear()
+	if f.Count() != 0 || f.victim.used || f.MightContain(added[len(added)-1]) {
+		t.Fatal("Clear left elements behind")
+	}
+}
+
+// 编码之后解码得到相同的过滤器，包括victim，参数不合法的数据被拒绝
+func TestCuckooFilterMarshal(t *testing.T) {
+	f, _ := NewCuckooFilter(8, 0.01)
+	added := fillCuckoo(t, f)
+
+	b, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g cuckooFilter
+	if err := g.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if g.numBuckets != f.numBuckets || g.fpBits != f.fpBits || g.count != f.count ||
+		g.victim != f.victim || !reflect.DeepEqual(g.buckets, f.buckets) {
+		t.Fatal("decoded filter differs from the original")
+	}
+	for _, v := range added {
+		if !g.MightContain(v) {
+			t.Fatalf("false negative for %d after decoding", v)
+		}
+	}
+	if err := g.Add(-1); err == nil {
+		t.Fatal("decoded full filter accepted Add")
+	}
+
+	// 头部依次是numBuckets(8)、fpBits(4)、count(8)、victimUsed(1)、victimIndex(8)、victimFp(4)
+	corrupt := func(offset int, put func([]byte)) []byte {
+		c := append([]byte(nil), b...)
+		put(c[offset:])
+		return c
+	}
+	for name, data := range map[string][]byte{
+		"buckets not a power of two": corrupt(0, func(c []byte) { binary.LittleEndian.PutUint64(c, 3) }),
+		"fpBits above 32":            corrupt(8, func(c []byte) { binary.LittleEndian.PutUint32(c, 33) }),
+		"victim index out of range":  corrupt(21, func(c []byte) { binary.LittleEndian.PutUint64(c, f.numBuckets) }),
+		"truncated":                  b[:len(b)-4],
+	} {
+		if err := g.UnmarshalBinary(data); err == nil {
+			t.Fatalf("UnmarshalBinary accepted %s", name)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Filter
+
+import "fmt"
+
+func BloomFilterExample() {
+	f, err := NewBloomFilter(1000, 0.01)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for i := 0; i < 1000; i++ {
+		_ = f.Add(i)
+	}
+	fmt.Println("f.MightContain =", f.MightContain(1, 2, 3))
+	fmt.Println("f.FalsePositiveRate =", f.FalsePositiveRate())
+
+	other, _ := NewBloomFilter(1000, 0.01)
+	_ = other.Add("GTL")
+	err = f.Merge(other)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("f.MightContain =", f.MightContain("GTL"))
+
+	b, err := f.MarshalBinary()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	f.Clear()
+	err = f.UnmarshalBinary(b)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("f.Count =", f.Count())
+}
+
+func CuckooFilterExample() {
+	f, err := NewCuckooFilter(1000, 0.01)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for i := 0; i < 1000; i++ {
+		err = f.Add(i)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	fmt.Println("f.MightContain =", f.MightContain(10))
+	fmt.Println("f.Delete =", f.Delete(10))
+	fmt.Println("f.Count =", f.Count())
+	fmt.Println("f.LoadFactor =", f.LoadFactor())
+}
@@ -0,0 +1,180 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// waitTimeout 使用超时时间为d的context调用f
+func waitTimeout(d time.Duration, f func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	return f(ctx)
+}
+
+type barrierGeneration struct {
+	// done 屏障被打开或者被破坏时关闭
+	done chan struct{}
+
+	// broken 屏障是否被破坏，在关闭done之前设置
+	broken bool
+}
+
+// Barrier 可以重复使用的循环屏障
+// 每一轮中先到达的参与者等待，最后一个参与者到达时执行action，然后打开屏障让所有参与者继续，并开始下一轮。
+// 有参与者取消等待或者action发生panic时屏障被破坏，正在等待和之后到达的参与者都会返回错误，直到调用Reset。
+type Barrier struct {
+	m       sync.Mutex
+	parties int
+	action  func()
+
+	// waiting 本轮已经到达的参与者数量
+	waiting int
+	gen     *barrierGeneration
+}
+
+// NewBarrier 返回有parties个参与者的屏障，action可以为nil，不为nil时由最后到达的参与者在打开屏障之前执行
+// action执行期间持有屏障内部的锁，不能在action中调用该屏障的方法
+func NewBarrier(parties int, action func()) (*Barrier, error) {
+	if parties <= 0 {
+		return nil, errors.New("Parties must be positive.")
+	}
+
+	return &Barrier{
+		parties: parties,
+		action:  action,
+		gen:     &barrierGeneration{done: make(chan struct{})},
+	}, nil
+}
+
+// Await 到达屏障并等待其他参与者，返回到达的次序，第一个到达的为parties-1，最后一个到达的为0
+func (b *Barrier) Await(ctx context.Context) (int, error) {
+	b.m.Lock()
+	gen := b.gen
+	if gen.broken {
+		b.m.Unlock()
+		return -1, errors.New("Barrier is broken.")
+	}
+
+	index := b.parties - 1 - b.waiting
+	b.waiting++
+	if index == 0 {
+		defer b.m.Unlock()
+		b.trip()
+		return 0, nil
+	}
+	b.m.Unlock()
+
+	select {
+	case <-gen.done:
+	case <-ctx.Done():
+		b.m.Lock()
+		select {
+		case <-gen.done:
+			// 取消的同时屏障已经打开或者被破坏，以屏障的结果为准
+		default:
+			b.breakBarrier()
+			b.m.Unlock()
+			return -1, ctx.Err()
+		}
+		b.m.Unlock()
+	}
+
+	if gen.broken {
+		return -1, errors.New("Barrier is broken.")
+	}
+
+	return index, nil
+}
+
+// AwaitTimeout 到达屏障并最多等待d
+func (b *Barrier) AwaitTimeout(d time.Duration) (int, error) {
+	var index int
+	err := waitTimeout(d, func(ctx context.Context) error {
+		var err error
+		index, err = b.Await(ctx)
+		return err
+	})
+
+	return index, err
+}
+
+// trip 执行action后打开屏障，调用时必须持有锁
+func (b *Barrier) trip() {
+	if b.action != nil {
+		ok := false
+		defer func() {
+			if !ok {
+				b.breakBarrier()
+			}
+		}()
+		b.action()
+		ok = true
+	}
+
+	close(b.gen.done)
+	b.gen = &barrierGeneration{done: make(chan struct{})}
+	b.waiting = 0
+}
+
+// breakBarrier 破坏当前一轮的屏障，调用时必须持有锁
+func (b *Barrier) breakBarrier() {
+	b.gen.broken = true
+	close(b.gen.done)
+}
+
+// Reset 破坏正在等待的一轮并开始新的一轮，被破坏的屏障在Reset之后可以继续使用
+func (b *Barrier) Reset() {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if b.waiting > 0 && !b.gen.broken {
+		b.breakBarrier()
+	}
+	b.gen = &barrierGeneration{done: make(chan struct{})}
+	b.waiting = 0
+}
+
+// IsBroken 判断屏障是否被破坏
+func (b *Barrier) IsBroken() bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	return b.gen.broken
+}
+
+func (b *Barrier) Parties() int {
+	return b.parties
+}
+
+// Waiting 返回本轮正在等待的参与者数量
+func (b *Barrier) Waiting() int {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if b.gen.broken {
+		return 0
+	}
+
+	return b.waiting
+}
@@ -0,0 +1,165 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 屏障可以重复使用，每一轮都在所有参与者到达后执行一次action，到达次序覆盖[0, parties)
+func TestBarrierGenerations(t *testing.T) {
+	const (
+		parties = 4
+		rounds  = 20
+	)
+	var trips int32
+	b, err := NewBarrier(parties, func() {
+		atomic.AddInt32(&trips, 1)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m sync.Mutex
+	indexes := make([][]int, rounds)
+	var wg sync.WaitGroup
+	wg.Add(parties)
+	for i := 0; i < parties; i++ {
+		go func() {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				index, err := b.Await(context.Background())
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				// 下一轮必须等本go程再次到达才能打开，所以此时action恰好执行了r+1次
+				if n := atomic.LoadInt32(&trips); n != int32(r+1) {
+					t.Errorf("round %d: action ran %d times, want %d", r, n, r+1)
+				}
+				m.Lock()
+				indexes[r] = append(indexes[r], index)
+				m.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for r, got := range indexes {
+		sort.Ints(got)
+		for i, index := range got {
+			if index != i {
+				t.Fatalf("round %d: arrival indexes = %v, want 0..%d", r, got, parties-1)
+			}
+		}
+	}
+	if b.Waiting() != 0 || b.IsBroken() {
+		t.Fatalf("Waiting() = %d, IsBroken() = %v after all rounds", b.Waiting(), b.IsBroken())
+	}
+}
+
+// 等待者取消后屏障被破坏，其他等待者和之后到达的参与者都返回错误，Reset后屏障恢复可用
+func TestBarrierBrokenByCancel(t *testing.T) {
+	b, err := NewBarrier(3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waiterErr := make(chan error, 1)
+	go func() {
+		_, err := b.Await(context.Background())
+		waiterErr <- err
+	}()
+	waitUntil(t, func() bool { return b.Waiting() == 1 })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelErr := make(chan error, 1)
+	go func() {
+		_, err := b.Await(ctx)
+		cancelErr <- err
+	}()
+	waitUntil(t, func() bool { return b.Waiting() == 2 })
+	cancel()
+
+	if err := <-cancelErr; err != context.Canceled {
+		t.Fatalf("cancelled Await returned %v, want %v", err, context.Canceled)
+	}
+	if err := <-waiterErr; err == nil {
+		t.Fatal("waiting Await succeeded on a broken barrier")
+	}
+	if !b.IsBroken() {
+		t.Fatal("IsBroken() = false after a waiter cancelled")
+	}
+	if _, err := b.AwaitTimeout(time.Second); err == nil {
+		t.Fatal("Await succeeded on a broken barrier")
+	}
+
+	b.Reset()
+	if b.IsBroken() {
+		t.Fatal("IsBroken() = true after Reset")
+	}
+	var wg sync.WaitGroup
+	wg.Add(3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			defer wg.Done()
+			if _, err := b.AwaitTimeout(5 * time.Second); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// action发生panic时屏障被破坏，panic传给最后到达的参与者
+func TestBarrierActionPanic(t *testing.T) {
+	b, err := NewBarrier(2, func() {
+		panic("action failed")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waiterErr := make(chan error, 1)
+	go func() {
+		_, err := b.Await(context.Background())
+		waiterErr <- err
+	}()
+	waitUntil(t, func() bool { return b.Waiting() == 1 })
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Await of the last party did not propagate the panic")
+			}
+		}()
+		_, _ = b.Await(context.Background())
+	}()
+
+	if err := <-waiterErr; err == nil {
+		t.Fatal("waiting Await succeeded after the action panicked")
+	}
+	if !b.IsBroken() {
+		t.Fatal("IsBroken() = false after the action panicked")
+	}
+}
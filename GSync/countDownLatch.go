@@ -0,0 +1,95 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// CountDownLatch 倒计数门闩，计数减到0之前Wait会一直等待，计数到0之后门闩保持打开，不能重复使用
+type CountDownLatch struct {
+	m     sync.Mutex
+	count int
+
+	// done 计数减到0时关闭
+	done chan struct{}
+}
+
+// NewCountDownLatch 返回初始计数为count的门闩，count为0时门闩直接打开
+func NewCountDownLatch(count int) (*CountDownLatch, error) {
+	if count < 0 {
+		return nil, errors.New("Count can not be negative.")
+	}
+
+	l := &CountDownLatch{count: count, done: make(chan struct{})}
+	if count == 0 {
+		close(l.done)
+	}
+
+	return l, nil
+}
+
+// CountDown 计数减一，计数已经为0时不做任何操作
+func (l *CountDownLatch) CountDown() {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if l.count == 0 {
+		return
+	}
+	l.count--
+	if l.count == 0 {
+		close(l.done)
+	}
+}
+
+func (l *CountDownLatch) Count() int {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	return l.count
+}
+
+// Wait 等待计数减到0
+func (l *CountDownLatch) Wait(ctx context.Context) error {
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		// 同时满足时以门闩的状态为准
+		select {
+		case <-l.done:
+			return nil
+		default:
+			return ctx.Err()
+		}
+	}
+}
+
+// WaitTimeout 最多等待d
+func (l *CountDownLatch) WaitTimeout(d time.Duration) error {
+	return waitTimeout(d, l.Wait)
+}
+
+// Done 返回计数减到0时关闭的通道，可以在select中使用
+func (l *CountDownLatch) Done() <-chan struct{} {
+	return l.done
+}
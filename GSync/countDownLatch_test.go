@@ -0,0 +1,108 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 计数减到0之前所有等待者都被阻塞，减到0时同时放行，之后门闩保持打开
+func TestCountDownLatchRelease(t *testing.T) {
+	const (
+		count   = 3
+		waiters = 5
+	)
+	l, err := NewCountDownLatch(count)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	released := make(chan error, waiters)
+	for i := 0; i < waiters; i++ {
+		go func() {
+			released <- l.Wait(context.Background())
+		}()
+	}
+
+	for i := 0; i < count-1; i++ {
+		l.CountDown()
+	}
+	select {
+	case <-l.Done():
+		t.Fatal("latch opened before the count reached zero")
+	case err := <-released:
+		t.Fatalf("Wait returned %v before the count reached zero", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err := l.WaitTimeout(10 * time.Millisecond); err != context.DeadlineExceeded {
+		t.Fatalf("WaitTimeout returned %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	l.CountDown()
+	for i := 0; i < waiters; i++ {
+		if err := <-released; err != nil {
+			t.Fatalf("Wait returned %v after the count reached zero", err)
+		}
+	}
+
+	// 计数为0后CountDown不做任何操作，门闩保持打开
+	l.CountDown()
+	if n := l.Count(); n != 0 {
+		t.Fatalf("Count() = %d, want 0", n)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx); err != nil {
+		t.Fatalf("Wait on an open latch returned %v", err)
+	}
+}
+
+// 多个go程并发CountDown，恰好在最后一次时打开
+func TestCountDownLatchConcurrentCountDown(t *testing.T) {
+	const count = 100
+	l, err := NewCountDownLatch(count)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(count)
+	for i := 0; i < count; i++ {
+		go func() {
+			defer wg.Done()
+			l.CountDown()
+		}()
+	}
+	if err := l.WaitTimeout(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	zero, err := NewCountDownLatch(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-zero.Done():
+	default:
+		t.Fatal("latch with count 0 is not open")
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+/*
+WritePreferRWLock解决了写者饥饿的问题，但只要写者源源不断地到来，读者就会一直等待。
+公平锁保证每个等待者在有限的步数内获得锁：
+TicketLock 按照到达的顺序依次持有锁；
+PhaseFairRWLock 让读阶段和写阶段交替进行，读者最多等待一个写阶段，写者之间按照到达顺序排队，并且最多等待一个读阶段。
+这两种锁在等待时自旋并让出处理器，适用于临界区很短的场景。
+*/
+
+// TicketLock 先来先服务的互斥锁，实现了sync.Locker
+// 每个加锁者取一个递增的号码，等待叫号等于自己的号码时持有锁
+type TicketLock struct {
+	// next 下一个要发出的号码
+	next uint32
+
+	// serving 当前持有锁的号码
+	serving uint32
+}
+
+// NewTicketLock 返回一个新的TicketLock指针
+func NewTicketLock() *TicketLock {
+	return &TicketLock{}
+}
+
+func (l *TicketLock) Lock() {
+	debugAcquire(l, true)
+	ticket := atomic.AddUint32(&l.next, 1) - 1
+	for atomic.LoadUint32(&l.serving) != ticket {
+		runtime.Gosched()
+	}
+	debugAcquired(l, true)
+}
+
+// Unlock 叫下一个号码，必须由持有锁的一方调用
+func (l *TicketLock) Unlock() {
+	debugRelease(l, true)
+	if atomic.LoadUint32(&l.serving) == atomic.LoadUint32(&l.next) {
+		panic("Unlock of unlocked TicketLock")
+	}
+
+	atomic.AddUint32(&l.serving, 1)
+}
+
+const (
+	// pfReaderInc 读者计数每次增加的值，低位留给写者标志
+	pfReaderInc uint32 = 0x100
+
+	// pfWriterBits 写者标志位
+	pfWriterBits uint32 = 0x3
+
+	// pfPresent 有写者持有锁或者在等待读者离开
+	pfPresent uint32 = 0x2
+
+	// pfPhaseID 写阶段的编号，相邻的两个写阶段编号不同
+	pfPhaseID uint32 = 0x1
+)
+
+// PhaseFairRWLock 读写阶段交替进行的读写锁（Brandenburg & Anderson的PF-T算法）
+// rin的高位记录进入的读者数量，低两位记录是否有写者以及写阶段编号；rout记录离开的读者数量；
+// 写者之间使用win和wout实现的排号锁排队。
+// 读者到达时如果有写者，就等待写者标志发生变化，也就是当前写阶段结束；
+// 写者设置写者标志后，新到达的读者都要等待，写者只需要等待标志设置之前进入的读者离开。
+type PhaseFairRWLock struct {
+	rin  uint32
+	rout uint32
+	win  uint32
+	wout uint32
+}
+
+// NewPhaseFairRWLock 返回一个新的PhaseFairRWLock指针
+func NewPhaseFairRWLock() *PhaseFairRWLock {
+	return &PhaseFairRWLock{}
+}
+
+func (l *PhaseFairRWLock) RLock() {
+	debugAcquire(l, false)
+	w := atomic.AddUint32(&l.rin, pfReaderInc) & pfWriterBits
+
+	// 有写者时等待当前写阶段结束：写者释放锁时清除标志，下一个写者的阶段编号不同
+	for w != 0 && w == atomic.LoadUint32(&l.rin)&pfWriterBits {
+		runtime.Gosched()
+	}
+	debugAcquired(l, false)
+}
+
+func (l *PhaseFairRWLock) RUnlock() {
+	debugRelease(l, false)
+	atomic.AddUint32(&l.rout, pfReaderInc)
+}
+
+func (l *PhaseFairRWLock) WLock() {
+	debugAcquire(l, true)
+	// 写者之间按照到达顺序排队
+	ticket := atomic.AddUint32(&l.win, 1) - 1
+	for atomic.LoadUint32(&l.wout) != ticket {
+		runtime.Gosched()
+	}
+
+	// 设置写者标志，之后到达的读者都会等待；再等待已经进入的读者离开
+	w := pfPresent | (ticket & pfPhaseID)
+	entered := atomic.AddUint32(&l.rin, w) - w
+	for atomic.LoadUint32(&l.rout) != entered {
+		runtime.Gosched()
+	}
+	debugAcquired(l, true)
+}
+
+func (l *PhaseFairRWLock) WUnlock() {
+	debugRelease(l, true)
+	for {
+		old := atomic.LoadUint32(&l.rin)
+		if old&pfPresent == 0 {
+			panic("WUnlock of unlocked PhaseFairRWLock")
+		}
+		if atomic.CompareAndSwapUint32(&l.rin, old, old&^pfWriterBits) {
+			break
+		}
+	}
+
+	atomic.AddUint32(&l.wout, 1)
+}
@@ -0,0 +1,224 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitUntil 等待cond成立，超时则测试失败
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		runtime.Gosched()
+	}
+}
+
+// orderLog 记录各个go程获得锁的顺序
+type orderLog struct {
+	m     sync.Mutex
+	order []string
+}
+
+func (o *orderLog) add(name string) {
+	o.m.Lock()
+	o.order = append(o.order, name)
+	o.m.Unlock()
+}
+
+func (o *orderLog) get() []string {
+	o.m.Lock()
+	defer o.m.Unlock()
+	return append([]string(nil), o.order...)
+}
+
+// 等待者按照取号的顺序获得TicketLock
+func TestTicketLockFIFO(t *testing.T) {
+	const waiters = 8
+	l := NewTicketLock()
+	var log orderLog
+	var wg sync.WaitGroup
+
+	l.Lock()
+	for i := 0; i < waiters; i++ {
+		name := fmt.Sprint("g", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Lock()
+			log.add(name)
+			l.Unlock()
+		}()
+		// 等这个go程取到号码后再启动下一个，使到达顺序确定
+		ticket := uint32(i + 2)
+		waitUntil(t, func() bool { return atomic.LoadUint32(&l.next) == ticket })
+	}
+	l.Unlock()
+	wg.Wait()
+
+	want := make([]string, waiters)
+	for i := range want {
+		want[i] = fmt.Sprint("g", i)
+	}
+	if got := log.get(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("acquisition order = %v, want %v", got, want)
+	}
+}
+
+// 写者到达后再到达的读者必须等待写者，写者只等待它到达之前进入的读者
+func TestPhaseFairRWLockWriterWaitsOneReaderPhase(t *testing.T) {
+	l := NewPhaseFairRWLock()
+	var log orderLog
+	var wg sync.WaitGroup
+
+	for i := 0; i < 3; i++ {
+		l.RLock()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		l.WLock()
+		log.add("w")
+		l.WUnlock()
+	}()
+	waitUntil(t, func() bool { return atomic.LoadUint32(&l.rin)&pfPresent != 0 })
+
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.RLock()
+			log.add("r")
+			l.RUnlock()
+		}()
+	}
+	waitUntil(t, func() bool { return atomic.LoadUint32(&l.rin)/pfReaderInc == 6 })
+
+	for i := 0; i < 3; i++ {
+		l.RUnlock()
+	}
+	wg.Wait()
+
+	if got, want := log.get(), []string{"w", "r", "r", "r"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("acquisition order = %v, want %v", got, want)
+	}
+}
+
+// 读者源源不断到达时，写者设置标志后最多再放行标志设置之前已经进入的读者
+func TestPhaseFairRWLockWriterBoundedUnderContinuousReaders(t *testing.T) {
+	const (
+		readers = 4
+		rounds  = 50
+	)
+	l := NewPhaseFairRWLock()
+	var stop int32
+	var admitted int64
+	var wg sync.WaitGroup
+
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&stop) == 0 {
+				l.RLock()
+				atomic.AddInt64(&admitted, 1)
+				runtime.Gosched()
+				l.RUnlock()
+			}
+		}()
+	}
+	waitUntil(t, func() bool { return atomic.LoadInt64(&admitted) > readers })
+
+	for i := 0; i < rounds; i++ {
+		acquired := make(chan int64)
+		go func() {
+			l.WLock()
+			// 持有写锁时发送，测试读取之前写阶段不会结束
+			acquired <- atomic.LoadInt64(&admitted)
+			l.WUnlock()
+		}()
+
+		waitUntil(t, func() bool { return atomic.LoadUint32(&l.rin)&pfPresent != 0 })
+		before := atomic.LoadInt64(&admitted)
+		if after := <-acquired; after-before > readers {
+			t.Fatalf("round %d: %d readers admitted while the writer waited, want at most %d", i, after-before, readers)
+		}
+	}
+
+	atomic.StoreInt32(&stop, 1)
+	wg.Wait()
+}
+
+// 写者源源不断排队时，读者最多等待它到达时持有锁的那一个写者
+func TestPhaseFairRWLockReaderWaitsOneWriter(t *testing.T) {
+	const (
+		writers = 4
+		rounds  = 20
+	)
+	for round := 0; round < rounds; round++ {
+		l := NewPhaseFairRWLock()
+		var log orderLog
+		var wg sync.WaitGroup
+
+		l.WLock()
+		for i := 0; i < writers; i++ {
+			name := fmt.Sprint("w", i)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				l.WLock()
+				log.add(name)
+				l.WUnlock()
+			}()
+			ticket := uint32(i + 2)
+			waitUntil(t, func() bool { return atomic.LoadUint32(&l.win) == ticket })
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.RLock()
+			log.add("r")
+			l.RUnlock()
+		}()
+		waitUntil(t, func() bool { return atomic.LoadUint32(&l.rin)/pfReaderInc == 1 })
+		l.WUnlock()
+		wg.Wait()
+
+		got := log.get()
+		if len(got) != writers+1 || got[0] != "r" {
+			t.Fatalf("round %d: acquisition order = %v, want the reader before every queued writer", round, got)
+		}
+		for i, name := range got[1:] {
+			if want := fmt.Sprint("w", i); name != want {
+				t.Fatalf("round %d: acquisition order = %v, want writers in arrival order", round, got)
+			}
+		}
+	}
+}
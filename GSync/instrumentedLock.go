@@ -0,0 +1,452 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"expvar"
+	"math"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// lockStatsVars 所有命名的LockStats都发布在expvar的GSyncLocks下
+var (
+	lockStatsOnce sync.Once
+	lockStatsVars *expvar.Map
+)
+
+func publishedLockStats() *expvar.Map {
+	lockStatsOnce.Do(func() {
+		lockStatsVars = expvar.NewMap("GSyncLocks")
+	})
+
+	return lockStatsVars
+}
+
+// histogram 以2的幂次纳秒为桶边界的时间直方图，第i个桶记录bits.Len64(ns) == i的样本
+type histogram struct {
+	buckets [64]uint64
+	count   uint64
+	sum     uint64
+	max     uint64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	ns := uint64(d)
+
+	atomic.AddUint64(&h.buckets[bits.Len64(ns)], 1)
+	atomic.AddUint64(&h.count, 1)
+	atomic.AddUint64(&h.sum, ns)
+	for {
+		old := atomic.LoadUint64(&h.max)
+		if ns <= old || atomic.CompareAndSwapUint64(&h.max, old, ns) {
+			return
+		}
+	}
+}
+
+func (h *histogram) snapshot() Histogram {
+	s := Histogram{
+		Count: atomic.LoadUint64(&h.count),
+		Sum:   time.Duration(atomic.LoadUint64(&h.sum)),
+		Max:   time.Duration(atomic.LoadUint64(&h.max)),
+	}
+	for i := range h.buckets {
+		if c := atomic.LoadUint64(&h.buckets[i]); c > 0 {
+			bound := time.Duration(math.MaxInt64)
+			if i < 63 {
+				bound = time.Duration(1) << uint(i)
+			}
+			s.Buckets = append(s.Buckets, HistogramBucket{UpperBound: bound, Count: c})
+		}
+	}
+
+	return s
+}
+
+// HistogramBucket 直方图中的一个桶，记录小于UpperBound且不小于上一个桶UpperBound的样本数量
+type HistogramBucket struct {
+	UpperBound time.Duration
+	Count      uint64
+}
+
+// Histogram 时间直方图的快照，Buckets只包含非空的桶
+type Histogram struct {
+	Count   uint64
+	Sum     time.Duration
+	Max     time.Duration
+	Buckets []HistogramBucket
+}
+
+// Mean 返回平均值
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile 返回q分位数所在桶的上界，q取值为[0, 1]
+func (h Histogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+
+	rank := uint64(q * float64(h.Count))
+	var seen uint64
+	for _, b := range h.Buckets {
+		seen += b.Count
+		if seen > rank {
+			return b.UpperBound
+		}
+	}
+
+	return h.Max
+}
+
+// LockStatsSnapshot 某一时刻锁的统计信息
+// ReadHold统计的是读阶段的长度，即从第一个读者加锁到最后一个读者解锁的时间，而不是单个读者持有锁的时间
+type LockStatsSnapshot struct {
+	Name string
+
+	ReadAcquisitions  uint64
+	WriteAcquisitions uint64
+
+	ReadWait  Histogram
+	WriteWait Histogram
+	ReadHold  Histogram
+	WriteHold Histogram
+
+	// MaxReaders 同时持有读锁的读者数量的最大值
+	MaxReaders int64
+
+	// WriterStarvations 写者等待时间超过阈值的次数
+	WriterStarvations uint64
+}
+
+// LockStats 收集一把锁的统计信息，所有方法都可以并发调用
+type LockStats struct {
+	name string
+
+	readAcquisitions  uint64
+	writeAcquisitions uint64
+	writerStarvations uint64
+
+	readWait  histogram
+	writeWait histogram
+	readHold  histogram
+	writeHold histogram
+
+	readers        int64
+	maxReaders     int64
+	readPhaseStart int64
+	writeStart     int64
+
+	// starvation 写者等待超过这个时间记为一次饥饿，单位为纳秒
+	starvation int64
+}
+
+// NewLockStats 返回新的统计，name不为空时发布到expvar的GSyncLocks下，同名的统计会被替换
+func NewLockStats(name string) *LockStats {
+	s := &LockStats{
+		name:       name,
+		starvation: int64(10 * time.Millisecond),
+	}
+
+	if name != "" {
+		publishedLockStats().Set(name, expvar.Func(func() interface{} {
+			return s.Snapshot()
+		}))
+	}
+
+	return s
+}
+
+// SetStarvationThreshold 设置写者饥饿的阈值，默认为10ms
+func (s *LockStats) SetStarvationThreshold(d time.Duration) {
+	atomic.StoreInt64(&s.starvation, int64(d))
+}
+
+// Unpublish 从expvar中移除
+func (s *LockStats) Unpublish() {
+	if s.name != "" {
+		publishedLockStats().Delete(s.name)
+	}
+}
+
+func (s *LockStats) Name() string {
+	return s.name
+}
+
+// Snapshot 返回当前的统计信息
+func (s *LockStats) Snapshot() LockStatsSnapshot {
+	return LockStatsSnapshot{
+		Name:              s.name,
+		ReadAcquisitions:  atomic.LoadUint64(&s.readAcquisitions),
+		WriteAcquisitions: atomic.LoadUint64(&s.writeAcquisitions),
+		ReadWait:          s.readWait.snapshot(),
+		WriteWait:         s.writeWait.snapshot(),
+		ReadHold:          s.readHold.snapshot(),
+		WriteHold:         s.writeHold.snapshot(),
+		MaxReaders:        atomic.LoadInt64(&s.maxReaders),
+		WriterStarvations: atomic.LoadUint64(&s.writerStarvations),
+	}
+}
+
+// readAcquired 读者在start开始等待，现在获得了读锁
+func (s *LockStats) readAcquired(start time.Time) {
+	now := time.Now()
+	s.readWait.observe(now.Sub(start))
+	atomic.AddUint64(&s.readAcquisitions, 1)
+
+	n := atomic.AddInt64(&s.readers, 1)
+	if n == 1 {
+		atomic.StoreInt64(&s.readPhaseStart, now.UnixNano())
+	}
+	for {
+		old := atomic.LoadInt64(&s.maxReaders)
+		if n <= old || atomic.CompareAndSwapInt64(&s.maxReaders, old, n) {
+			return
+		}
+	}
+}
+
+// readReleasing 读者即将释放读锁
+// 统计是在持有锁的过程中才开启的读者没有被计数，因此读者数量为0时不再减少
+func (s *LockStats) readReleasing() {
+	start := atomic.LoadInt64(&s.readPhaseStart)
+	for {
+		n := atomic.LoadInt64(&s.readers)
+		if n == 0 {
+			return
+		}
+		if atomic.CompareAndSwapInt64(&s.readers, n, n-1) {
+			if n == 1 {
+				s.readHold.observe(time.Duration(time.Now().UnixNano() - start))
+			}
+			return
+		}
+	}
+}
+
+// writeAcquired 写者在start开始等待，现在获得了写锁
+func (s *LockStats) writeAcquired(start time.Time) {
+	now := time.Now()
+	wait := now.Sub(start)
+	s.writeWait.observe(wait)
+	atomic.AddUint64(&s.writeAcquisitions, 1)
+	if int64(wait) > atomic.LoadInt64(&s.starvation) {
+		atomic.AddUint64(&s.writerStarvations, 1)
+	}
+
+	atomic.StoreInt64(&s.writeStart, now.UnixNano())
+}
+
+// writeReleasing 写者即将释放写锁，加锁时没有开启统计的写者不记录持有时间
+func (s *LockStats) writeReleasing() {
+	start := atomic.SwapInt64(&s.writeStart, 0)
+	if start != 0 {
+		s.writeHold.observe(time.Duration(time.Now().UnixNano() - start))
+	}
+}
+
+// InstrumentedRWLock 统计任意RWLocker的加锁次数、等待时间和持有时间
+type InstrumentedRWLock struct {
+	l     RWLocker
+	stats *LockStats
+}
+
+// NewInstrumentedRWLock 包装l，name不为空时统计信息发布到expvar
+func NewInstrumentedRWLock(name string, l RWLocker) *InstrumentedRWLock {
+	return &InstrumentedRWLock{l: l, stats: NewLockStats(name)}
+}
+
+func (l *InstrumentedRWLock) RLock() {
+	start := time.Now()
+	l.l.RLock()
+	l.stats.readAcquired(start)
+}
+
+func (l *InstrumentedRWLock) RUnlock() {
+	l.stats.readReleasing()
+	l.l.RUnlock()
+}
+
+func (l *InstrumentedRWLock) WLock() {
+	start := time.Now()
+	l.l.WLock()
+	l.stats.writeAcquired(start)
+}
+
+func (l *InstrumentedRWLock) WUnlock() {
+	l.stats.writeReleasing()
+	l.l.WUnlock()
+}
+
+// LockStats 返回收集统计信息的LockStats
+func (l *InstrumentedRWLock) LockStats() *LockStats {
+	return l.stats
+}
+
+// Stats 返回当前的统计信息
+func (l *InstrumentedRWLock) Stats() LockStatsSnapshot {
+	return l.stats.Snapshot()
+}
+
+// RWMutex 可以在运行时开启统计的sync.RWMutex，零值可以直接使用
+// 没有开启统计时每次加锁和解锁只多一次原子读，safe容器都使用它作为内部的锁
+type RWMutex struct {
+	rw sync.RWMutex
+
+	// l 不为nil时代替rw作为实际加锁的锁
+	l RWLocker
+
+	// stats 指向*LockStats，为nil时不统计
+	stats unsafe.Pointer
+}
+
+// NewRWMutexWithLocker 返回使用l加锁的RWMutex，统计仍然由RWMutex完成
+// safe容器的WithLocker构造函数接受这样的RWMutex，从而可以使用UpgradeableRWLock、PhaseFairRWLock等锁；
+// l必须在不同的go程之间互斥，因此不能使用ReentrantMutex.ForOwner返回的锁
+func NewRWMutexWithLocker(l RWLocker) *RWMutex {
+	return &RWMutex{l: l}
+}
+
+func (m *RWMutex) loadStats() *LockStats {
+	return (*LockStats)(atomic.LoadPointer(&m.stats))
+}
+
+func (m *RWMutex) wlock() {
+	if m.l != nil {
+		m.l.WLock()
+	} else {
+		m.rw.Lock()
+	}
+}
+
+func (m *RWMutex) wunlock() {
+	if m.l != nil {
+		m.l.WUnlock()
+	} else {
+		m.rw.Unlock()
+	}
+}
+
+func (m *RWMutex) rlock() {
+	if m.l != nil {
+		m.l.RLock()
+	} else {
+		m.rw.RLock()
+	}
+}
+
+func (m *RWMutex) runlock() {
+	if m.l != nil {
+		m.l.RUnlock()
+	} else {
+		m.rw.RUnlock()
+	}
+}
+
+func (m *RWMutex) Lock() {
+	debugAcquire(m, true)
+	if s := m.loadStats(); s == nil {
+		m.wlock()
+	} else {
+		start := time.Now()
+		m.wlock()
+		s.writeAcquired(start)
+	}
+	debugAcquired(m, true)
+}
+
+func (m *RWMutex) Unlock() {
+	debugRelease(m, true)
+	if s := m.loadStats(); s != nil {
+		s.writeReleasing()
+	}
+	m.wunlock()
+}
+
+func (m *RWMutex) RLock() {
+	debugAcquire(m, false)
+	if s := m.loadStats(); s == nil {
+		m.rlock()
+	} else {
+		start := time.Now()
+		m.rlock()
+		s.readAcquired(start)
+	}
+	debugAcquired(m, false)
+}
+
+func (m *RWMutex) RUnlock() {
+	debugRelease(m, false)
+	if s := m.loadStats(); s != nil {
+		s.readReleasing()
+	}
+	m.runlock()
+}
+
+// WLock 与Lock相同，使RWMutex实现RWLocker
+func (m *RWMutex) WLock() {
+	m.Lock()
+}
+
+// WUnlock 与Unlock相同
+func (m *RWMutex) WUnlock() {
+	m.Unlock()
+}
+
+// Instrument 开启统计并返回新的LockStats，name不为空时发布到expvar，已经开启时替换原来的统计
+func (m *RWMutex) Instrument(name string) *LockStats {
+	s := NewLockStats(name)
+	if old := (*LockStats)(atomic.SwapPointer(&m.stats, unsafe.Pointer(s))); old != nil && old.name != name {
+		old.Unpublish()
+	}
+
+	return s
+}
+
+// Uninstrument 关闭统计并从expvar中移除
+func (m *RWMutex) Uninstrument() {
+	if old := (*LockStats)(atomic.SwapPointer(&m.stats, nil)); old != nil {
+		old.Unpublish()
+	}
+}
+
+// LockStats 返回当前的统计，没有开启统计时返回nil
+func (m *RWMutex) LockStats() *LockStats {
+	return m.loadStats()
+}
+
+// Stats 返回当前的统计信息，没有开启统计时返回零值
+func (m *RWMutex) Stats() LockStatsSnapshot {
+	if s := m.loadStats(); s != nil {
+		return s.Snapshot()
+	}
+
+	return LockStatsSnapshot{}
+}
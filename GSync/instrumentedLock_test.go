@@ -0,0 +1,317 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"encoding/json"
+	"expvar"
+	"math"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 使用其他锁的RWMutex通过该锁加锁，统计仍然有效
+func TestRWMutexWithLocker(t *testing.T) {
+	l := NewPhaseFairRWLock()
+	m := NewRWMutexWithLocker(l)
+	m.Instrument("")
+	defer m.Uninstrument()
+
+	l.WLock()
+	acquired := make(chan struct{})
+	go func() {
+		m.RLock()
+		close(acquired)
+		m.RUnlock()
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("RLock succeeded while the backing lock was held for writing")
+	case <-time.After(50 * time.Millisecond):
+	}
+	l.WUnlock()
+	<-acquired
+
+	m.Lock()
+	m.Unlock()
+
+	stats := m.Stats()
+	if stats.ReadAcquisitions != 1 || stats.WriteAcquisitions != 1 {
+		t.Fatalf("acquisitions = %d reads, %d writes, want 1, 1", stats.ReadAcquisitions, stats.WriteAcquisitions)
+	}
+}
+
+// 样本按bits.Len64(ns)分桶，分位数返回所在桶的上界，超过所有桶时返回最大值
+func TestHistogram(t *testing.T) {
+	var h histogram
+	if s := h.snapshot(); s.Mean() != 0 || s.Quantile(0.5) != 0 || s.Buckets != nil {
+		t.Fatalf("empty snapshot = %+v", s)
+	}
+
+	for _, d := range []time.Duration{0, -5, 1, 3, 1000} {
+		h.observe(d)
+	}
+	s := h.snapshot()
+	want := []HistogramBucket{
+		{UpperBound: 1, Count: 2},
+		{UpperBound: 2, Count: 1},
+		{UpperBound: 4, Count: 1},
+		{UpperBound: 1024, Count: 1},
+	}
+	if !reflect.DeepEqual(s.Buckets, want) {
+		t.Fatalf("Buckets = %v, want %v", s.Buckets, want)
+	}
+	if s.Count != 5 || s.Sum != 1004 || s.Max != 1000 || s.Mean() != 200 {
+		t.Fatalf("Count, Sum, Max, Mean = %d, %v, %v, %v, want 5, 1004ns, 1µs, 200ns", s.Count, s.Sum, s.Max, s.Mean())
+	}
+	for q, want := range map[float64]time.Duration{0: 1, 0.5: 2, 0.7: 4, 0.9: 1024, 1: 1000} {
+		if got := s.Quantile(q); got != want {
+			t.Fatalf("Quantile(%v) = %v, want %v", q, got, want)
+		}
+	}
+
+	// 最大的桶没有上界
+	h.observe(time.Duration(math.MaxInt64))
+	s = h.snapshot()
+	if last := s.Buckets[len(s.Buckets)-1]; last.UpperBound != time.Duration(math.MaxInt64) || last.Count != 1 {
+		t.Fatalf("last bucket = %+v, want an unbounded bucket with 1 sample", last)
+	}
+}
+
+// MaxReaders记录同时持有读锁的读者数量，ReadHold每个读阶段只记录一次
+func TestInstrumentedRWLockReaders(t *testing.T) {
+	l := NewInstrumentedRWLock("", NewPhaseFairRWLock())
+
+	const readers = 3
+	var held, release sync.WaitGroup
+	held.Add(readers)
+	release.Add(1)
+	done := make(chan struct{})
+	for i := 0; i < readers; i++ {
+		go func() {
+			l.RLock()
+			held.Done()
+			release.Wait()
+			l.RUnlock()
+			done <- struct{}{}
+		}()
+	}
+	held.Wait()
+
+	s := l.Stats()
+	if s.MaxReaders != readers || s.ReadAcquisitions != readers || s.ReadHold.Count != 0 {
+		t.Fatalf("while held: MaxReaders %d, ReadAcquisitions %d, ReadHold.Count %d, want %d, %d, 0",
+			s.MaxReaders, s.ReadAcquisitions, s.ReadHold.Count, readers, readers)
+	}
+	release.Done()
+	for i := 0; i < readers; i++ {
+		<-done
+	}
+
+	l.RLock()
+	l.RUnlock()
+	s = l.Stats()
+	if s.MaxReaders != readers || s.ReadAcquisitions != readers+1 || s.ReadHold.Count != 2 || s.ReadWait.Count != readers+1 {
+		t.Fatalf("after release: MaxReaders %d, ReadAcquisitions %d, ReadHold.Count %d, ReadWait.Count %d, want %d, %d, 2, %d",
+			s.MaxReaders, s.ReadAcquisitions, s.ReadHold.Count, s.ReadWait.Count, readers, readers+1, readers+1)
+	}
+}
+
+// 写者等待时间超过阈值时记为一次饥饿
+func TestInstrumentedRWLockWriterStarvation(t *testing.T) {
+	pf := NewPhaseFairRWLock()
+	l := NewInstrumentedRWLock("", pf)
+	l.LockStats().SetStarvationThreshold(5 * time.Millisecond)
+
+	l.WLock()
+	l.WUnlock()
+
+	l.RLock()
+	done := make(chan struct{})
+	go func() {
+		l.WLock()
+		l.WUnlock()
+		close(done)
+	}()
+	// 写者设置标志之后才开始计时，保证它至少等待了20ms
+	waitUntil(t, func() bool {
+		return atomic.LoadUint32(&pf.rin)&pfPresent != 0
+	})
+	time.Sleep(20 * time.Millisecond)
+	l.RUnlock()
+	<-done
+
+	s := l.Stats()
+	if s.WriterStarvations != 1 || s.WriteAcquisitions != 2 {
+		t.Fatalf("WriterStarvations, WriteAcquisitions = %d, %d, want 1, 2", s.WriterStarvations, s.WriteAcquisitions)
+	}
+	if s.WriteWait.Max < 20*time.Millisecond || s.WriteHold.Count != 2 {
+		t.Fatalf("WriteWait.Max, WriteHold.Count = %v, %d, want >= 20ms, 2", s.WriteWait.Max, s.WriteHold.Count)
+	}
+}
+
+// lockStatsVar 返回expvar中GSyncLocks下名为name的统计，不存在时返回nil
+func lockStatsVar(t *testing.T, name string) *LockStatsSnapshot {
+	t.Helper()
+	locks, ok := expvar.Get("GSyncLocks").(*expvar.Map)
+	if !ok {
+		t.Fatal("GSyncLocks is not published")
+	}
+	v := locks.Get(name)
+	if v == nil {
+		return nil
+	}
+
+	var s LockStatsSnapshot
+	if err := json.Unmarshal([]byte(v.String()), &s); err != nil {
+		t.Fatal(err)
+	}
+
+	return &s
+}
+
+// 命名的统计发布到expvar，改名、关闭统计时从expvar中移除
+func TestRWMutexExpvar(t *testing.T) {
+	var m RWMutex
+	m.Instrument("expvar-test-a")
+	defer m.Uninstrument()
+
+	m.Lock()
+	m.Unlock()
+	m.RLock()
+	m.RUnlock()
+	s := lockStatsVar(t, "expvar-test-a")
+	if s == nil || s.Name != "expvar-test-a" || s.WriteAcquisitions != 1 || s.ReadAcquisitions != 1 {
+		t.Fatalf("published stats = %+v, want 1 write and 1 read", s)
+	}
+
+	// 同名替换时仍然发布，且指向新的统计
+	m.Instrument("expvar-test-a")
+	if s := lockStatsVar(t, "expvar-test-a"); s == nil || s.WriteAcquisitions != 0 {
+		t.Fatalf("stats after re-instrumenting = %+v, want fresh stats", s)
+	}
+
+	m.Instrument("expvar-test-b")
+	if lockStatsVar(t, "expvar-test-a") != nil {
+		t.Fatal("old name still published after renaming")
+	}
+	if lockStatsVar(t, "expvar-test-b") == nil {
+		t.Fatal("new name not published")
+	}
+
+	m.Uninstrument()
+	if lockStatsVar(t, "expvar-test-b") != nil {
+		t.Fatal("stats still published after Uninstrument")
+	}
+
+	// 匿名的统计不发布
+	m.Instrument("")
+	m.Lock()
+	m.Unlock()
+	if lockStatsVar(t, "") != nil {
+		t.Fatal("anonymous stats were published")
+	}
+}
+
+// 持有锁时开启或关闭统计，解锁不会记录错误的持有时间，读者数量也不会变成负数
+func TestRWMutexInstrumentWhileHeld(t *testing.T) {
+	var m RWMutex
+
+	m.Lock()
+	s := m.Instrument("")
+	m.Unlock()
+	if snap := s.Snapshot(); snap.WriteAcquisitions != 0 || snap.WriteHold.Count != 0 {
+		t.Fatalf("write lock taken before Instrument was recorded: %+v", snap)
+	}
+
+	m.RLock()
+	m.RLock()
+	s = m.Instrument("")
+	m.RLock()
+	m.RUnlock()
+	m.RUnlock()
+	m.RUnlock()
+	m.RLock()
+	m.RUnlock()
+	if snap := s.Snapshot(); snap.ReadAcquisitions != 2 || snap.MaxReaders != 1 || snap.ReadHold.Count != 2 {
+		t.Fatalf("ReadAcquisitions, MaxReaders, ReadHold.Count = %d, %d, %d, want 2, 1, 2",
+			snap.ReadAcquisitions, snap.MaxReaders, snap.ReadHold.Count)
+	}
+	if s.readers != 0 {
+		t.Fatalf("readers = %d after all readers left, want 0", s.readers)
+	}
+
+	// 关闭统计之后解锁不再更新原来的统计
+	m.Lock()
+	m.Uninstrument()
+	m.Unlock()
+	if snap := s.Snapshot(); snap.WriteAcquisitions != 1 || snap.WriteHold.Count != 0 {
+		t.Fatalf("WriteAcquisitions, WriteHold.Count = %d, %d, want 1, 0", snap.WriteAcquisitions, snap.WriteHold.Count)
+	}
+	if m.LockStats() != nil || m.Stats().WriteAcquisitions != 0 {
+		t.Fatal("stats still reported after Uninstrument")
+	}
+}
+
+// 并发加锁时反复开启与关闭统计，锁仍然互斥
+func TestRWMutexInstrumentConcurrent(t *testing.T) {
+	var m RWMutex
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			m.Instrument("")
+			m.Uninstrument()
+		}
+	}()
+
+	counter := 0
+	var workers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for j := 0; j < 500; j++ {
+				m.Lock()
+				counter++
+				m.Unlock()
+				m.RLock()
+				_ = counter
+				m.RUnlock()
+			}
+		}()
+	}
+	workers.Wait()
+	close(stop)
+	wg.Wait()
+
+	if counter != 2000 {
+		t.Fatalf("counter = %d, want 2000", counter)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+使用gsyncdebug构建标签编译时（go build -tags gsyncdebug），GSync中的锁会记录每个go程持有的锁和锁之间的获取顺序，
+发现以下问题时通过SetDebugReporter设置的函数报告，默认输出到标准错误：
+	- 两把锁在不同的地方以相反的顺序获取，可能形成死锁
+	- 同一个go程重复获取已经持有的锁，并且其中有写锁
+	- 解锁没有被持有的锁
+	- 由没有加锁的go程解锁
+	- 持有锁或者等待锁超过SetDebugThreshold设置的时间
+不使用该标签时所有检查都被编译为空函数，没有任何开销。
+ReentrantMutex和UpgradeableRWLock有自己的持有者语义，不参与检查。
+*/
+
+// DebugReportKind 调试模式下发现的问题类型
+type DebugReportKind int
+
+const (
+	// DebugLockOrderInversion 两把锁在不同的地方以相反的顺序获取
+	DebugLockOrderInversion DebugReportKind = iota
+
+	// DebugRecursiveLock 同一个go程重复获取已经持有的锁
+	DebugRecursiveLock
+
+	// DebugDoubleUnlock 解锁没有被持有的锁
+	DebugDoubleUnlock
+
+	// DebugForeignUnlock 由没有加锁的go程解锁
+	DebugForeignUnlock
+
+	// DebugHeldTooLong 持有锁的时间超过阈值
+	DebugHeldTooLong
+
+	// DebugWaitTooLong 等待锁的时间超过阈值
+	DebugWaitTooLong
+)
+
+func (k DebugReportKind) String() string {
+	switch k {
+	case DebugLockOrderInversion:
+		return "lock order inversion"
+	case DebugRecursiveLock:
+		return "recursive lock"
+	case DebugDoubleUnlock:
+		return "double unlock"
+	case DebugForeignUnlock:
+		return "foreign unlock"
+	case DebugHeldTooLong:
+		return "lock held too long"
+	case DebugWaitTooLong:
+		return "lock wait too long"
+	default:
+		return fmt.Sprintf("DebugReportKind(%d)", int(k))
+	}
+}
+
+// DebugReport 调试模式下发现的一个问题
+type DebugReport struct {
+	Kind DebugReportKind
+
+	// Lock 出问题的锁，格式为类型和地址
+	Lock string
+
+	Message string
+
+	// Goroutine 发现问题的go程，Stack为它的调用栈
+	Goroutine int64
+	Stack     string
+
+	// Related 相关的其他调用栈，例如相反顺序获取锁的位置、锁的持有者
+	Related []string
+}
+
+func (r *DebugReport) String() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("GSync: %v on %s: %s\n", r.Kind, r.Lock, r.Message))
+	b.WriteString(r.Stack)
+	for _, s := range r.Related {
+		b.WriteString("\nrelated:\n")
+		b.WriteString(s)
+	}
+
+	return b.String()
+}
+
+func describeLock(l interface{}) string {
+	return fmt.Sprintf("%T(%p)", l, l)
+}
@@ -0,0 +1,35 @@
+//go:build !gsyncdebug
+// +build !gsyncdebug
+
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import "time"
+
+func debugAcquire(l interface{}, write bool) {}
+
+func debugAcquired(l interface{}, write bool) {}
+
+func debugRelease(l interface{}, write bool) {}
+
+// SetDebugReporter 设置调试模式下报告问题的函数，只在使用gsyncdebug构建时生效
+func SetDebugReporter(f func(r *DebugReport)) {}
+
+// SetDebugThreshold 设置调试模式下持有和等待锁的时间阈值，只在使用gsyncdebug构建时生效
+func SetDebugThreshold(d time.Duration) {}
@@ -0,0 +1,388 @@
+//go:build gsyncdebug
+// +build gsyncdebug
+
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type debugHeld struct {
+	lock     interface{}
+	write    bool
+	since    time.Time
+	stack    string
+	reported bool
+}
+
+type debugGoroutine struct {
+	held []*debugHeld
+
+	// waiting 正在等待的锁
+	waiting *debugHeld
+}
+
+// debugState 调试模式的全局状态，锁之间的顺序图会让参与过的锁一直不被回收
+var debugState = struct {
+	sync.Mutex
+
+	goroutines map[int64]*debugGoroutine
+
+	// edges[a][b] 持有a时获取b的调用栈，只记录第一次
+	edges map[interface{}]map[interface{}]string
+
+	// inversions 已经报告过的相反顺序
+	inversions map[[2]interface{}]bool
+
+	threshold time.Duration
+	reporter  func(r *DebugReport)
+	watchdog  bool
+}{
+	goroutines: make(map[int64]*debugGoroutine),
+	edges:      make(map[interface{}]map[interface{}]string),
+	inversions: make(map[[2]interface{}]bool),
+	threshold:  time.Second,
+	reporter: func(r *DebugReport) {
+		fmt.Fprintln(os.Stderr, r)
+	},
+}
+
+// SetDebugReporter 设置报告问题的函数，默认输出到标准错误，f会在发现问题的go程或者后台检查的go程中调用
+func SetDebugReporter(f func(r *DebugReport)) {
+	debugState.Lock()
+	defer debugState.Unlock()
+
+	debugState.reporter = f
+}
+
+// SetDebugThreshold 设置持有和等待锁的时间阈值，默认为1s，为0时不检查
+func SetDebugThreshold(d time.Duration) {
+	debugState.Lock()
+	defer debugState.Unlock()
+
+	debugState.threshold = d
+}
+
+// currentStack 返回当前go程的调用栈和go程编号
+func currentStack() (string, int64) {
+	buf := make([]byte, 4096)
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	// 第一行的格式为"goroutine 123 [running]:"
+	id := bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(id, ' '); i >= 0 {
+		id = id[:i]
+	}
+	gid, _ := strconv.ParseInt(string(id), 10, 64)
+
+	return string(buf), gid
+}
+
+func sendDebugReports(reports []*DebugReport) {
+	if len(reports) == 0 {
+		return
+	}
+
+	debugState.Lock()
+	reporter := debugState.reporter
+	debugState.Unlock()
+
+	if reporter == nil {
+		return
+	}
+	for _, r := range reports {
+		reporter(r)
+	}
+}
+
+// debugGoroutineOf 返回gid的状态，没有时创建，调用时必须持有debugState的锁
+func debugGoroutineOf(gid int64) *debugGoroutine {
+	g, ok := debugState.goroutines[gid]
+	if !ok {
+		g = &debugGoroutine{}
+		debugState.goroutines[gid] = g
+	}
+
+	return g
+}
+
+// debugPath 在顺序图中查找从from到to的路径，返回路径上每条边的调用栈，调用时必须持有debugState的锁
+func debugPath(from, to interface{}) ([]string, bool) {
+	type step struct {
+		lock interface{}
+		prev *step
+		// stack 从prev到lock的边
+		stack string
+	}
+
+	visited := map[interface{}]bool{from: true}
+	queue := []*step{{lock: from}}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for next, stack := range debugState.edges[s.lock] {
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+
+			n := &step{lock: next, prev: s, stack: stack}
+			if next == to {
+				var stacks []string
+				for p := n; p.prev != nil; p = p.prev {
+					stacks = append([]string{p.stack}, stacks...)
+				}
+				return stacks, true
+			}
+			queue = append(queue, n)
+		}
+	}
+
+	return nil, false
+}
+
+// debugAcquire 在获取锁之前检查获取顺序和重复加锁
+func debugAcquire(l interface{}, write bool) {
+	stack, gid := currentStack()
+	var reports []*DebugReport
+
+	debugState.Lock()
+	g := debugGoroutineOf(gid)
+	for _, h := range g.held {
+		if h.lock == l {
+			if h.write || write {
+				reports = append(reports, &DebugReport{
+					Kind:      DebugRecursiveLock,
+					Lock:      describeLock(l),
+					Message:   "goroutine already holds this lock",
+					Goroutine: gid,
+					Stack:     stack,
+					Related:   []string{h.stack},
+				})
+			}
+			continue
+		}
+
+		if stacks, ok := debugPath(l, h.lock); ok {
+			key := [2]interface{}{h.lock, l}
+			if !debugState.inversions[key] {
+				debugState.inversions[key] = true
+				reports = append(reports, &DebugReport{
+					Kind: DebugLockOrderInversion,
+					Lock: describeLock(l),
+					Message: fmt.Sprintf("acquired while holding %s, which was acquired after it elsewhere",
+						describeLock(h.lock)),
+					Goroutine: gid,
+					Stack:     stack,
+					Related:   stacks,
+				})
+			}
+		}
+
+		out, ok := debugState.edges[h.lock]
+		if !ok {
+			out = make(map[interface{}]string)
+			debugState.edges[h.lock] = out
+		}
+		if _, ok := out[l]; !ok {
+			out[l] = stack
+		}
+	}
+
+	g.waiting = &debugHeld{lock: l, write: write, since: time.Now(), stack: stack}
+	if !debugState.watchdog {
+		debugState.watchdog = true
+		go debugWatchdog()
+	}
+	debugState.Unlock()
+
+	sendDebugReports(reports)
+}
+
+// debugAcquired 获取锁之后记录持有者
+func debugAcquired(l interface{}, write bool) {
+	stack, gid := currentStack()
+
+	debugState.Lock()
+	defer debugState.Unlock()
+
+	g := debugGoroutineOf(gid)
+	h := g.waiting
+	if h == nil || h.lock != l {
+		h = &debugHeld{lock: l, write: write, stack: stack}
+	}
+	h.since = time.Now()
+	h.reported = false
+	g.held = append(g.held, h)
+	g.waiting = nil
+}
+
+// debugRelease 释放锁之前检查当前go程是否持有该锁
+func debugRelease(l interface{}, write bool) {
+	stack, gid := currentStack()
+	var reports []*DebugReport
+
+	debugState.Lock()
+	if !debugRemoveHeld(gid, l, write) {
+		found := false
+		for other := range debugState.goroutines {
+			if other != gid {
+				if h := debugFindHeld(other, l, write); h != nil {
+					found = true
+					reports = append(reports, &DebugReport{
+						Kind:      DebugForeignUnlock,
+						Lock:      describeLock(l),
+						Message:   fmt.Sprintf("unlocked by a goroutine other than the locker (goroutine %d)", other),
+						Goroutine: gid,
+						Stack:     stack,
+						Related:   []string{h.stack},
+					})
+					debugRemoveHeld(other, l, write)
+					break
+				}
+			}
+		}
+
+		if !found {
+			reports = append(reports, &DebugReport{
+				Kind:      DebugDoubleUnlock,
+				Lock:      describeLock(l),
+				Message:   "unlock of a lock that is not held",
+				Goroutine: gid,
+				Stack:     stack,
+			})
+		}
+	}
+	debugState.Unlock()
+
+	sendDebugReports(reports)
+}
+
+// debugFindHeld 返回gid最后一次以write模式持有l的记录，调用时必须持有debugState的锁
+func debugFindHeld(gid int64, l interface{}, write bool) *debugHeld {
+	g, ok := debugState.goroutines[gid]
+	if !ok {
+		return nil
+	}
+
+	for i := len(g.held) - 1; i >= 0; i-- {
+		if h := g.held[i]; h.lock == l && h.write == write {
+			return h
+		}
+	}
+
+	return nil
+}
+
+// debugRemoveHeld 删除gid最后一次以write模式持有l的记录，调用时必须持有debugState的锁
+func debugRemoveHeld(gid int64, l interface{}, write bool) bool {
+	g, ok := debugState.goroutines[gid]
+	if !ok {
+		return false
+	}
+
+	for i := len(g.held) - 1; i >= 0; i-- {
+		if h := g.held[i]; h.lock == l && h.write == write {
+			g.held = append(g.held[:i], g.held[i+1:]...)
+			if len(g.held) == 0 && g.waiting == nil {
+				delete(debugState.goroutines, gid)
+			}
+			return true
+		}
+	}
+
+	return false
+}
+
+// debugWatchdog 定期检查持有或者等待锁超过阈值的go程，每次持有或者等待只报告一次
+func debugWatchdog() {
+	for {
+		debugState.Lock()
+		threshold := debugState.threshold
+		debugState.Unlock()
+
+		interval := threshold / 2
+		if interval < 10*time.Millisecond {
+			interval = 10 * time.Millisecond
+		}
+		time.Sleep(interval)
+		if threshold <= 0 {
+			continue
+		}
+
+		var reports []*DebugReport
+		now := time.Now()
+
+		debugState.Lock()
+		for gid, g := range debugState.goroutines {
+			for _, h := range g.held {
+				if !h.reported && now.Sub(h.since) > threshold {
+					h.reported = true
+					reports = append(reports, &DebugReport{
+						Kind:      DebugHeldTooLong,
+						Lock:      describeLock(h.lock),
+						Message:   fmt.Sprintf("held for more than %v", threshold),
+						Goroutine: gid,
+						Stack:     h.stack,
+					})
+				}
+			}
+
+			if h := g.waiting; h != nil && !h.reported && now.Sub(h.since) > threshold {
+				h.reported = true
+				var holders []string
+				for other, og := range debugState.goroutines {
+					if other == gid {
+						continue
+					}
+					for _, oh := range og.held {
+						if oh.lock == h.lock {
+							holders = append(holders, oh.stack)
+						}
+					}
+				}
+				reports = append(reports, &DebugReport{
+					Kind:      DebugWaitTooLong,
+					Lock:      describeLock(h.lock),
+					Message:   fmt.Sprintf("waiting for more than %v, %d holder(s)", threshold, len(holders)),
+					Goroutine: gid,
+					Stack:     h.stack,
+					Related:   holders,
+				})
+			}
+		}
+		debugState.Unlock()
+
+		sendDebugReports(reports)
+	}
+}
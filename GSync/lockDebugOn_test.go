@@ -0,0 +1,215 @@
+//go:build gsyncdebug
+// +build gsyncdebug
+
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// reportLog 收集调试报告，报告可能来自后台检查的go程
+type reportLog struct {
+	mu      sync.Mutex
+	reports []*DebugReport
+}
+
+func (l *reportLog) add(r *DebugReport) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.reports = append(l.reports, r)
+}
+
+// of 返回关于锁lock、类型为kind的报告，其他测试的锁产生的报告被忽略
+func (l *reportLog) of(kind DebugReportKind, lock interface{}) []*DebugReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var ret []*DebugReport
+	for _, r := range l.reports {
+		if r.Kind == kind && r.Lock == describeLock(lock) {
+			ret = append(ret, r)
+		}
+	}
+
+	return ret
+}
+
+// collectReports 将报告收集到新的reportLog中，测试结束后不再报告
+func collectReports(t *testing.T) *reportLog {
+	log := &reportLog{}
+	SetDebugReporter(log.add)
+	t.Cleanup(func() {
+		SetDebugReporter(nil)
+	})
+
+	return log
+}
+
+// 以相反的顺序获取两把锁时报告一次，经过第三把锁形成的环也能发现
+func TestDebugLockOrderInversion(t *testing.T) {
+	log := collectReports(t)
+	a, b, c := new(RWMutex), new(RWMutex), NewTicketLock()
+
+	a.Lock()
+	b.RLock()
+	b.RUnlock()
+	a.Unlock()
+	if n := len(log.of(DebugLockOrderInversion, a)) + len(log.of(DebugLockOrderInversion, b)); n != 0 {
+		t.Fatalf("%d inversions reported for a consistent order", n)
+	}
+
+	for i := 0; i < 2; i++ {
+		b.Lock()
+		a.RLock()
+		a.RUnlock()
+		b.Unlock()
+	}
+	reports := log.of(DebugLockOrderInversion, a)
+	if len(reports) != 1 {
+		t.Fatalf("%d inversions reported on a, want 1", len(reports))
+	}
+	if r := reports[0]; len(r.Related) != 1 || !strings.Contains(r.Related[0], "TestDebugLockOrderInversion") ||
+		!strings.Contains(r.Message, describeLock(b)) {
+		t.Fatalf("inversion report = %v, want b in the message and the a -> b stack", r)
+	}
+
+	// b -> c 之后在持有c时获取a，环为a -> b -> c -> a
+	b.Lock()
+	c.Lock()
+	c.Unlock()
+	b.Unlock()
+	c.Lock()
+	a.Lock()
+	a.Unlock()
+	c.Unlock()
+	if n := len(log.of(DebugLockOrderInversion, a)); n != 2 {
+		t.Fatalf("%d inversions reported on a after the a -> b -> c -> a cycle, want 2", n)
+	}
+	if r := log.of(DebugLockOrderInversion, a)[1]; len(r.Related) != 2 {
+		t.Fatalf("cycle report has %d related stacks, want one per edge of a -> b -> c", len(r.Related))
+	}
+}
+
+// 由其他go程解锁时报告加锁的位置，解锁没有被持有的锁时报告double unlock
+func TestDebugForeignAndDoubleUnlock(t *testing.T) {
+	log := collectReports(t)
+	m := new(RWMutex)
+
+	m.Lock()
+	done := make(chan struct{})
+	go func() {
+		m.Unlock()
+		close(done)
+	}()
+	<-done
+	reports := log.of(DebugForeignUnlock, m)
+	if len(reports) != 1 || len(reports[0].Related) != 1 ||
+		!strings.Contains(reports[0].Related[0], "TestDebugForeignAndDoubleUnlock") {
+		t.Fatalf("foreign unlock reports = %v, want one pointing at the locker", reports)
+	}
+
+	// 解锁之后记录已经清除，可以正常地再次加锁和解锁
+	m.RLock()
+	m.RUnlock()
+	if n := len(log.of(DebugDoubleUnlock, m)) + len(log.of(DebugForeignUnlock, m)); n != 1 {
+		t.Fatalf("%d unlock reports after a clean lock/unlock, want only the earlier one", n)
+	}
+
+	// 真正的锁重复解锁会使程序崩溃，所以直接调用检查函数
+	x := new(int)
+	debugAcquire(x, true)
+	debugAcquired(x, true)
+	debugRelease(x, true)
+	debugRelease(x, true)
+	// 持有读锁时按写锁解锁同样没有匹配的记录
+	debugAcquire(x, false)
+	debugAcquired(x, false)
+	debugRelease(x, true)
+	debugRelease(x, false)
+	if reports := log.of(DebugDoubleUnlock, x); len(reports) != 2 {
+		t.Fatalf("%d double unlocks reported, want 2", len(reports))
+	}
+}
+
+// 同一个go程重复获取锁，只有全部是读锁时不报告
+func TestDebugRecursiveLock(t *testing.T) {
+	log := collectReports(t)
+	m := new(RWMutex)
+
+	m.RLock()
+	m.RLock()
+	m.RUnlock()
+	m.RUnlock()
+	if n := len(log.of(DebugRecursiveLock, m)); n != 0 {
+		t.Fatalf("%d recursive locks reported for nested read locks", n)
+	}
+
+	// 真正的锁会死锁，所以直接调用检查函数
+	x := new(int)
+	debugAcquire(x, false)
+	debugAcquired(x, false)
+	debugAcquire(x, true)
+	debugAcquired(x, true)
+	debugRelease(x, true)
+	debugRelease(x, false)
+	if reports := log.of(DebugRecursiveLock, x); len(reports) != 1 || len(reports[0].Related) != 1 {
+		t.Fatalf("recursive lock reports = %v, want one with the first acquisition", reports)
+	}
+}
+
+// 持有或者等待锁超过阈值时各报告一次，等待的报告附带持有者的调用栈
+func TestDebugWatchdog(t *testing.T) {
+	log := collectReports(t)
+	SetDebugThreshold(20 * time.Millisecond)
+	t.Cleanup(func() {
+		SetDebugThreshold(time.Second)
+	})
+
+	m := new(RWMutex)
+	m.Lock()
+	acquired := make(chan struct{})
+	go func() {
+		m.Lock()
+		close(acquired)
+		m.Unlock()
+	}()
+
+	waitUntil(t, func() bool {
+		return len(log.of(DebugHeldTooLong, m)) > 0 && len(log.of(DebugWaitTooLong, m)) > 0
+	})
+	wait := log.of(DebugWaitTooLong, m)[0]
+	if len(wait.Related) != 1 || !strings.Contains(wait.Related[0], "TestDebugWatchdog") ||
+		!strings.Contains(wait.Message, "1 holder(s)") {
+		t.Fatalf("wait report = %v, want the holder's stack", wait)
+	}
+
+	// 每次持有或者等待只报告一次
+	time.Sleep(100 * time.Millisecond)
+	if held, waited := len(log.of(DebugHeldTooLong, m)), len(log.of(DebugWaitTooLong, m)); held != 1 || waited != 1 {
+		t.Fatalf("%d held and %d wait reports, want 1 each", held, waited)
+	}
+
+	m.Unlock()
+	<-acquired
+}
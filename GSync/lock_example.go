@@ -0,0 +1,225 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+func UpgradeableRWLockExample() {
+	l := NewUpgradeableRWLock()
+	cache := make(map[string]int)
+
+	load := func(key string) int {
+		l.ULock()
+		defer l.UUnlock()
+
+		if v, ok := cache[key]; ok {
+			return v
+		}
+
+		// 缓存未命中时升级为写锁，检查和写入之间不会有其他写者修改cache
+		l.Upgrade()
+		cache[key] = len(key)
+		l.Downgrade()
+
+		return cache[key]
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			load("hello")
+		}()
+		go func() {
+			defer wg.Done()
+			l.RLock()
+			_ = cache["hello"]
+			l.RUnlock()
+		}()
+	}
+	wg.Wait()
+
+	fmt.Println(cache)
+}
+
+func ReentrantMutexExample() {
+	m := NewReentrantMutex()
+
+	type task struct{ name string }
+	t := &task{name: "walk"}
+
+	// 同一个owner在递归中重复加锁不会死锁
+	var walk func(depth int) int
+	walk = func(depth int) int {
+		m.Lock(t)
+		defer m.Unlock(t)
+
+		if depth == 0 {
+			return 0
+		}
+		return walk(depth-1) + 1
+	}
+	fmt.Println(walk(3), m.HeldBy(t))
+
+	// 通过ForOwner作为RWLocker使用
+	var l RWLocker = m.ForOwner(t)
+	l.WLock()
+	l.RLock()
+	l.RUnlock()
+	l.WUnlock()
+}
+
+func PhaseFairRWLockExample() {
+	var l RWLocker = NewPhaseFairRWLock()
+	var t sync.Locker = NewTicketLock()
+	stop := make(chan struct{})
+	reads, writes := 0, 0
+
+	// 读者源源不断地到来，写者仍然能在一个读阶段之后获得锁
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				l.RLock()
+				t.Lock()
+				reads++
+				t.Unlock()
+				l.RUnlock()
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		l.WLock()
+		writes++
+		l.WUnlock()
+	}
+	close(stop)
+	wg.Wait()
+
+	fmt.Println(writes, reads > 0)
+}
+
+func QueueLockExample() {
+	locks := []sync.Locker{NewMCSLock(), NewCLHLock()}
+
+	for _, l := range locks {
+		n := 0
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					l.Lock()
+					n++
+					l.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		fmt.Println(n)
+	}
+
+	// 读多写少时读者之间几乎没有争用
+	var rw RWLocker = NewDistributedRWLock()
+	rw.RLock()
+	rw.RUnlock()
+	rw.WLock()
+	rw.WUnlock()
+}
+
+func SeqValueExample() {
+	type point struct {
+		X, Y int64
+	}
+
+	v, err := NewSeqValue(point{X: 1, Y: -1})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := int64(2); i <= 100; i++ {
+			_ = v.Store(point{X: i, Y: -i})
+		}
+	}()
+
+	// 读者不加锁，也不会读到写了一半的值
+	for i := 0; i < 100; i++ {
+		var p point
+		_ = v.Load(&p)
+		if p.X != -p.Y {
+			fmt.Println("torn read", p)
+		}
+	}
+	wg.Wait()
+
+	v.Update(func(ptr interface{}) {
+		ptr.(*point).X++
+	})
+	var p point
+	_ = v.Load(&p)
+	fmt.Println(p)
+}
+
+func InstrumentedLockExample() {
+	// 包装任意RWLocker
+	l := NewInstrumentedRWLock("", NewWritePreferFastRWLock())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.RLock()
+			l.RUnlock()
+		}()
+	}
+	l.WLock()
+	l.WUnlock()
+	wg.Wait()
+
+	s := l.Stats()
+	fmt.Println(s.ReadAcquisitions, s.WriteAcquisitions, s.WriteHold.Count)
+
+	// RWMutex在开启统计之前只有一次原子读的开销，开启后统计信息同时发布在expvar的GSyncLocks下
+	var m RWMutex
+	stats := m.Instrument("example")
+	stats.SetStarvationThreshold(time.Millisecond)
+	m.Lock()
+	m.Unlock()
+	fmt.Println(m.Stats().WriteAcquisitions, m.Stats().WriteWait.Quantile(0.99) > 0)
+	m.Uninstrument()
+}
@@ -0,0 +1,89 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// SyncRWLocker 方法与sync.RWMutex相同的读写锁，*sync.RWMutex和*RWMutex都实现了它
+type SyncRWLocker interface {
+	sync.Locker
+	RLock()
+	RUnlock()
+}
+
+type multiLockEntry struct {
+	l     SyncRWLocker
+	addr  uintptr
+	write bool
+}
+
+// MultiLock 同时锁住多把读写锁
+// 加锁前按照锁的地址排序，所有go程都以相同的顺序加锁，因此同时锁住多把锁时不会互相等待形成死锁；
+// 同一把锁被多次加入时只会加锁一次，只要有一次要求写锁就加写锁
+type MultiLock struct {
+	entries []multiLockEntry
+}
+
+func NewMultiLock() *MultiLock {
+	return &MultiLock{entries: nil}
+}
+
+// Add 加入一把锁，write为true时加写锁，否则加读锁，必须在Lock之前调用
+// l必须是指针类型
+func (m *MultiLock) Add(l SyncRWLocker, write bool) *MultiLock {
+	for i := range m.entries {
+		if m.entries[i].l == l {
+			m.entries[i].write = m.entries[i].write || write
+			return m
+		}
+	}
+
+	m.entries = append(m.entries, multiLockEntry{l: l, addr: reflect.ValueOf(l).Pointer(), write: write})
+
+	return m
+}
+
+// Lock 按照地址从小到大的顺序加锁
+func (m *MultiLock) Lock() {
+	sort.Slice(m.entries, func(i, j int) bool {
+		return m.entries[i].addr < m.entries[j].addr
+	})
+
+	for _, e := range m.entries {
+		if e.write {
+			e.l.Lock()
+		} else {
+			e.l.RLock()
+		}
+	}
+}
+
+// Unlock 按照加锁的相反顺序解锁
+func (m *MultiLock) Unlock() {
+	for i := len(m.entries) - 1; i >= 0; i-- {
+		if e := m.entries[i]; e.write {
+			e.l.Unlock()
+		} else {
+			e.l.RUnlock()
+		}
+	}
+}
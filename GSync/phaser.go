@@ -0,0 +1,227 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Phaser 可以动态增减参与者的多阶段屏障
+// 每个阶段所有已注册的参与者都到达后进入下一阶段，参与者可以只到达而不等待，也可以在任意阶段注册或者注销。
+// 进入下一阶段时调用onAdvance，返回true时Phaser终止，之后所有的到达和等待都会返回错误。
+type Phaser struct {
+	m          sync.Mutex
+	phase      int
+	parties    int
+	arrived    int
+	terminated bool
+
+	// onAdvance 参数为刚结束的阶段和当前注册的参与者数量
+	onAdvance func(phase, parties int) bool
+
+	// done 当前阶段结束或者Phaser终止时关闭
+	done chan struct{}
+}
+
+// NewPhaser 返回初始有parties个参与者的Phaser，onAdvance为nil时在注册的参与者数量减为0时终止
+func NewPhaser(parties int, onAdvance func(phase, parties int) bool) (*Phaser, error) {
+	if parties < 0 {
+		return nil, errors.New("Parties can not be negative.")
+	}
+
+	if onAdvance == nil {
+		onAdvance = func(phase, parties int) bool {
+			return parties == 0
+		}
+	}
+
+	return &Phaser{
+		parties:   parties,
+		onAdvance: onAdvance,
+		done:      make(chan struct{}),
+	}, nil
+}
+
+// Register 注册一个新的参与者，返回当前阶段
+func (p *Phaser) Register() (int, error) {
+	return p.BulkRegister(1)
+}
+
+// BulkRegister 注册n个新的参与者，返回当前阶段，新的参与者从当前阶段开始需要到达
+func (p *Phaser) BulkRegister(n int) (int, error) {
+	if n < 0 {
+		return -1, errors.New("Parties can not be negative.")
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.terminated {
+		return -1, errors.New("Phaser is terminated.")
+	}
+	p.parties += n
+
+	return p.phase, nil
+}
+
+// Arrive 到达当前阶段但不等待其他参与者，返回到达的阶段
+func (p *Phaser) Arrive() (int, error) {
+	return p.arrive(false)
+}
+
+// ArriveAndDeregister 到达当前阶段并注销，返回到达的阶段
+func (p *Phaser) ArriveAndDeregister() (int, error) {
+	return p.arrive(true)
+}
+
+func (p *Phaser) arrive(deregister bool) (int, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.terminated {
+		return -1, errors.New("Phaser is terminated.")
+	}
+	if p.arrived == p.parties {
+		return -1, errors.New("All registered parties have arrived.")
+	}
+
+	phase := p.phase
+	if deregister {
+		p.parties--
+	} else {
+		p.arrived++
+	}
+	if p.arrived == p.parties {
+		p.advance()
+	}
+
+	return phase, nil
+}
+
+// advance 结束当前阶段，调用时必须持有锁
+func (p *Phaser) advance() {
+	p.terminated = p.onAdvance(p.phase, p.parties)
+	p.phase++
+	p.arrived = 0
+	close(p.done)
+	if !p.terminated {
+		p.done = make(chan struct{})
+	}
+}
+
+// AwaitAdvance 等待phase阶段结束，返回结束后所在的阶段，当前阶段不是phase时直接返回当前阶段
+func (p *Phaser) AwaitAdvance(ctx context.Context, phase int) (int, error) {
+	p.m.Lock()
+	if p.terminated {
+		p.m.Unlock()
+		return -1, errors.New("Phaser is terminated.")
+	}
+	if p.phase != phase {
+		p.m.Unlock()
+		return p.phase, nil
+	}
+	done := p.done
+	p.m.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		select {
+		case <-done:
+		default:
+			return -1, ctx.Err()
+		}
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.terminated {
+		return -1, errors.New("Phaser is terminated.")
+	}
+
+	return p.phase, nil
+}
+
+// AwaitAdvanceTimeout 最多等待d
+func (p *Phaser) AwaitAdvanceTimeout(d time.Duration, phase int) (int, error) {
+	var next int
+	err := waitTimeout(d, func(ctx context.Context) error {
+		var err error
+		next, err = p.AwaitAdvance(ctx, phase)
+		return err
+	})
+
+	return next, err
+}
+
+// ArriveAndAwaitAdvance 到达当前阶段并等待其他参与者，返回下一阶段
+// ctx被取消时已经完成的到达不会被撤销
+func (p *Phaser) ArriveAndAwaitAdvance(ctx context.Context) (int, error) {
+	phase, err := p.Arrive()
+	if err != nil {
+		return -1, err
+	}
+
+	return p.AwaitAdvance(ctx, phase)
+}
+
+// ForceTermination 强制终止Phaser，唤醒所有等待者
+func (p *Phaser) ForceTermination() {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.terminated {
+		return
+	}
+	p.terminated = true
+	close(p.done)
+}
+
+func (p *Phaser) Phase() int {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	return p.phase
+}
+
+// RegisteredParties 返回已注册的参与者数量
+func (p *Phaser) RegisteredParties() int {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	return p.parties
+}
+
+// ArrivedParties 返回当前阶段已到达的参与者数量
+func (p *Phaser) ArrivedParties() int {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	return p.arrived
+}
+
+func (p *Phaser) IsTerminated() bool {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	return p.terminated
+}
@@ -0,0 +1,166 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Arrive不等待其他参与者，ArriveAndDeregister到达的同时减少参与者，所有剩余参与者到达后进入下一阶段
+func TestPhaserArriveAndDeregister(t *testing.T) {
+	var advanced []int
+	p, err := NewPhaser(3, func(phase, parties int) bool {
+		advanced = append(advanced, phase)
+		return parties == 0
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if phase, err := p.Arrive(); err != nil || phase != 0 {
+		t.Fatalf("Arrive() = %d, %v, want 0, nil", phase, err)
+	}
+	if phase, err := p.ArriveAndDeregister(); err != nil || phase != 0 {
+		t.Fatalf("ArriveAndDeregister() = %d, %v, want 0, nil", phase, err)
+	}
+	if p.Phase() != 0 || p.RegisteredParties() != 2 || p.ArrivedParties() != 1 {
+		t.Fatalf("phase %d, registered %d, arrived %d, want 0, 2, 1",
+			p.Phase(), p.RegisteredParties(), p.ArrivedParties())
+	}
+
+	// 最后一个参与者注销也会结束当前阶段
+	if _, err := p.ArriveAndDeregister(); err != nil {
+		t.Fatal(err)
+	}
+	if p.Phase() != 1 || p.RegisteredParties() != 1 || p.ArrivedParties() != 0 {
+		t.Fatalf("phase %d, registered %d, arrived %d, want 1, 1, 0",
+			p.Phase(), p.RegisteredParties(), p.ArrivedParties())
+	}
+
+	// 新注册的参与者从当前阶段开始需要到达
+	if phase, err := p.Register(); err != nil || phase != 1 {
+		t.Fatalf("Register() = %d, %v, want 1, nil", phase, err)
+	}
+	if _, err := p.Arrive(); err != nil {
+		t.Fatal(err)
+	}
+	if p.Phase() != 1 {
+		t.Fatalf("phase advanced to %d before the new party arrived", p.Phase())
+	}
+	if _, err := p.Arrive(); err != nil {
+		t.Fatal(err)
+	}
+	if p.Phase() != 2 || p.ArrivedParties() != 0 {
+		t.Fatalf("phase %d, arrived %d, want 2, 0", p.Phase(), p.ArrivedParties())
+	}
+
+	// 所有参与者注销后默认的onAdvance终止Phaser
+	for i := 0; i < 2; i++ {
+		if _, err := p.ArriveAndDeregister(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !p.IsTerminated() {
+		t.Fatal("IsTerminated() = false after every party deregistered")
+	}
+	if _, err := p.Arrive(); err == nil {
+		t.Fatal("Arrive succeeded on a terminated phaser")
+	}
+	if _, err := p.Register(); err == nil {
+		t.Fatal("Register succeeded on a terminated phaser")
+	}
+	if want := []int{0, 1, 2}; len(advanced) != len(want) || advanced[0] != 0 || advanced[1] != 1 || advanced[2] != 2 {
+		t.Fatalf("onAdvance called for phases %v, want %v", advanced, want)
+	}
+
+	// 没有注册的参与者时不能到达
+	empty, err := NewPhaser(0, func(phase, parties int) bool {
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := empty.Arrive(); err == nil {
+		t.Fatal("Arrive succeeded on a phaser without registered parties")
+	}
+}
+
+// 等待者在所有参与者到达之前阻塞，参与者在中途注销后剩余的参与者继续推进阶段
+func TestPhaserAwaitAdvance(t *testing.T) {
+	const (
+		parties = 4
+		phases  = 5
+	)
+	p, err := NewPhaser(parties, func(phase, parties int) bool {
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(parties)
+	for i := 0; i < parties; i++ {
+		// 第i个参与者在第i阶段注销，第0个参与者一直参与
+		leaveAt := i
+		if i == 0 {
+			leaveAt = phases
+		}
+		go func() {
+			defer wg.Done()
+			for phase := 0; phase < phases; phase++ {
+				if phase == leaveAt {
+					if _, err := p.ArriveAndDeregister(); err != nil {
+						t.Error(err)
+					}
+					return
+				}
+				next, err := p.ArriveAndAwaitAdvance(context.Background())
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if next != phase+1 {
+					t.Errorf("ArriveAndAwaitAdvance in phase %d returned %d", phase, next)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if p.Phase() != phases || p.RegisteredParties() != 1 {
+		t.Fatalf("phase %d, registered %d, want %d, 1", p.Phase(), p.RegisteredParties(), phases)
+	}
+
+	// 阶段未结束时AwaitAdvance超时，已经过去的阶段直接返回当前阶段
+	if _, err := p.AwaitAdvanceTimeout(10*time.Millisecond, phases); err != context.DeadlineExceeded {
+		t.Fatalf("AwaitAdvanceTimeout returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	if next, err := p.AwaitAdvance(context.Background(), 0); err != nil || next != phases {
+		t.Fatalf("AwaitAdvance of a past phase = %d, %v, want %d, nil", next, err, phases)
+	}
+
+	p.ForceTermination()
+	if _, err := p.AwaitAdvance(context.Background(), phases); err == nil {
+		t.Fatal("AwaitAdvance succeeded on a terminated phaser")
+	}
+}
@@ -0,0 +1,246 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+/*
+普通的自旋锁让所有等待者都在同一个变量上自旋，锁每次被释放都会让所有核心上的缓存行失效。
+队列锁让每个等待者在不同的结点上自旋，释放锁时只通知下一个等待者：
+MCSLock 每个等待者在自己的结点上自旋，由前驱在释放锁时修改；
+CLHLock 每个等待者在前驱的结点上自旋，前驱释放锁时修改自己的结点。
+两种锁都按照到达顺序持有锁，结点被填充到一个缓存行的大小并通过sync.Pool复用。
+*/
+
+// cacheLineSize 缓存行的大小，用于填充避免伪共享
+const cacheLineSize = 64
+
+type mcsNode struct {
+	next   unsafe.Pointer
+	locked uint32
+	_      [cacheLineSize - unsafe.Sizeof(uintptr(0)) - 4]byte
+}
+
+var mcsNodePool = sync.Pool{New: func() interface{} { return new(mcsNode) }}
+
+// MCSLock MCS队列锁，实现了sync.Locker
+// tail指向队尾的结点，加锁时把自己的结点放到队尾，再把前驱的next指向自己，然后在自己的locked上自旋
+type MCSLock struct {
+	tail unsafe.Pointer
+
+	// holder 持有锁的结点，只有持有者会访问
+	holder *mcsNode
+}
+
+// NewMCSLock 返回一个新的MCSLock指针
+func NewMCSLock() *MCSLock {
+	return &MCSLock{}
+}
+
+func (l *MCSLock) Lock() {
+	debugAcquire(l, true)
+	n := mcsNodePool.Get().(*mcsNode)
+	atomic.StorePointer(&n.next, nil)
+	atomic.StoreUint32(&n.locked, 1)
+
+	pred := (*mcsNode)(atomic.SwapPointer(&l.tail, unsafe.Pointer(n)))
+	if pred != nil {
+		atomic.StorePointer(&pred.next, unsafe.Pointer(n))
+		for atomic.LoadUint32(&n.locked) == 1 {
+			runtime.Gosched()
+		}
+	}
+
+	l.holder = n
+	debugAcquired(l, true)
+}
+
+func (l *MCSLock) Unlock() {
+	debugRelease(l, true)
+	n := l.holder
+	if n == nil {
+		panic("Unlock of unlocked MCSLock")
+	}
+	l.holder = nil
+
+	next := atomic.LoadPointer(&n.next)
+	if next == nil {
+		// 没有后继时把队尾置空，失败说明有后继正在入队，等待它设置next
+		if atomic.CompareAndSwapPointer(&l.tail, unsafe.Pointer(n), nil) {
+			mcsNodePool.Put(n)
+			return
+		}
+		for next = atomic.LoadPointer(&n.next); next == nil; next = atomic.LoadPointer(&n.next) {
+			runtime.Gosched()
+		}
+	}
+
+	atomic.StoreUint32(&(*mcsNode)(next).locked, 0)
+	mcsNodePool.Put(n)
+}
+
+type clhNode struct {
+	locked uint32
+	_      [cacheLineSize - 4]byte
+}
+
+var clhNodePool = sync.Pool{New: func() interface{} { return new(clhNode) }}
+
+// CLHLock CLH队列锁，实现了sync.Locker
+// tail指向队尾的结点，加锁时把自己的结点放到队尾，然后在前驱结点的locked上自旋；
+// 释放锁时前驱的结点已经没有其他人使用，可以回收
+type CLHLock struct {
+	tail unsafe.Pointer
+
+	// holder 持有锁的结点，pred 它的前驱，只有持有者会访问
+	holder *clhNode
+	pred   *clhNode
+}
+
+// NewCLHLock 返回一个新的CLHLock指针
+func NewCLHLock() *CLHLock {
+	return &CLHLock{}
+}
+
+func (l *CLHLock) Lock() {
+	debugAcquire(l, true)
+	n := clhNodePool.Get().(*clhNode)
+	atomic.StoreUint32(&n.locked, 1)
+
+	pred := (*clhNode)(atomic.SwapPointer(&l.tail, unsafe.Pointer(n)))
+	if pred != nil {
+		for atomic.LoadUint32(&pred.locked) == 1 {
+			runtime.Gosched()
+		}
+	}
+
+	l.holder = n
+	l.pred = pred
+	debugAcquired(l, true)
+}
+
+func (l *CLHLock) Unlock() {
+	debugRelease(l, true)
+	n, pred := l.holder, l.pred
+	if n == nil {
+		panic("Unlock of unlocked CLHLock")
+	}
+	l.holder, l.pred = nil, nil
+
+	atomic.StoreUint32(&n.locked, 0)
+	if pred != nil {
+		clhNodePool.Put(pred)
+	}
+}
+
+type paddedCounter struct {
+	n int64
+	_ [cacheLineSize - 8]byte
+}
+
+// DistributedRWLock 读者计数分散在多个缓存行上的读写锁，适用于读操作非常频繁的场景
+// 读者根据go程栈的地址选择计数器，不同go程的读者大多落在不同的缓存行上；写者设置标志后等待所有计数器之和为0。
+// 计数器并不与P绑定：Go没有公开当前P的编号，同一个P上的go程可能使用不同的计数器，不同P上的go程也可能共用一个计数器。
+// 栈扩容后地址会改变，同一个go程的加锁和解锁可能落在不同的计数器上，但写者只关心计数器之和，所以不影响正确性。
+type DistributedRWLock struct {
+	counters []paddedCounter
+	mask     uintptr
+
+	// writer 为1表示有写者持有锁或者在等待读者离开
+	writer int32
+
+	// gate 写者之间互斥，读者遇到写者时在gate上等待写者结束
+	gate sync.RWMutex
+}
+
+// NewDistributedRWLock 返回一个新的DistributedRWLock指针，计数器数量为不小于GOMAXPROCS的2的幂
+func NewDistributedRWLock() *DistributedRWLock {
+	n := 1
+	for n < runtime.GOMAXPROCS(0) {
+		n <<= 1
+	}
+
+	return &DistributedRWLock{
+		counters: make([]paddedCounter, n),
+		mask:     uintptr(n - 1),
+	}
+}
+
+// counter 根据go程栈的地址选择计数器
+func (l *DistributedRWLock) counter() *int64 {
+	var x byte
+	h := uintptr(unsafe.Pointer(&x)) >> 10
+	h ^= h >> 7
+
+	return &l.counters[h&l.mask].n
+}
+
+func (l *DistributedRWLock) RLock() {
+	debugAcquire(l, false)
+	for {
+		c := l.counter()
+		atomic.AddInt64(c, 1)
+		if atomic.LoadInt32(&l.writer) == 0 {
+			break
+		}
+
+		// 有写者，撤销计数并等待写者结束
+		atomic.AddInt64(c, -1)
+		l.gate.RLock()
+		l.gate.RUnlock()
+	}
+	debugAcquired(l, false)
+}
+
+func (l *DistributedRWLock) RUnlock() {
+	debugRelease(l, false)
+	atomic.AddInt64(l.counter(), -1)
+}
+
+func (l *DistributedRWLock) readers() int64 {
+	var sum int64
+	for i := range l.counters {
+		sum += atomic.LoadInt64(&l.counters[i].n)
+	}
+
+	return sum
+}
+
+func (l *DistributedRWLock) WLock() {
+	debugAcquire(l, true)
+	l.gate.Lock()
+	atomic.StoreInt32(&l.writer, 1)
+	for l.readers() != 0 {
+		runtime.Gosched()
+	}
+	debugAcquired(l, true)
+}
+
+func (l *DistributedRWLock) WUnlock() {
+	debugRelease(l, true)
+	if atomic.LoadInt32(&l.writer) == 0 {
+		panic("WUnlock of unlocked DistributedRWLock")
+	}
+	atomic.StoreInt32(&l.writer, 0)
+	l.gate.Unlock()
+}
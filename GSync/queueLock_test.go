@@ -0,0 +1,317 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// stdRWLock 把sync.RWMutex包装成RWLocker
+type stdRWLock struct {
+	sync.RWMutex
+}
+
+func (l *stdRWLock) WLock() {
+	l.Lock()
+}
+
+func (l *stdRWLock) WUnlock() {
+	l.Unlock()
+}
+
+// exclusiveRWLock 把互斥锁包装成RWLocker，读操作也独占锁
+type exclusiveRWLock struct {
+	sync.Locker
+}
+
+func (l exclusiveRWLock) RLock() {
+	l.Lock()
+}
+
+func (l exclusiveRWLock) RUnlock() {
+	l.Unlock()
+}
+
+func (l exclusiveRWLock) WLock() {
+	l.Lock()
+}
+
+func (l exclusiveRWLock) WUnlock() {
+	l.Unlock()
+}
+
+// benchSink 保存读操作的结果，避免读操作被编译器优化掉
+var benchSink int64
+
+var benchLocks = []struct {
+	name string
+	new  func() RWLocker
+}{
+	{"sync.RWMutex", func() RWLocker { return &stdRWLock{} }},
+	{"WritePreferFastRWLock", func() RWLocker { return NewWritePreferFastRWLock() }},
+	{"DistributedRWLock", func() RWLocker { return NewDistributedRWLock() }},
+	{"MCSLock", func() RWLocker { return exclusiveRWLock{NewMCSLock()} }},
+	{"CLHLock", func() RWLocker { return exclusiveRWLock{NewCLHLock()} }},
+}
+
+// benchmarkLocks 在所有锁上运行并行的读写混合负载，每writeEvery次操作中有一次写操作，writeEvery为0时只读
+// 使用-cpu参数观察不同核心数量下的表现，例如go test -bench Locks -cpu 1,4,16
+func benchmarkLocks(b *testing.B, writeEvery int) {
+	for _, bl := range benchLocks {
+		bl := bl
+		b.Run(bl.name, func(b *testing.B) {
+			l := bl.new()
+			var data [4]int64
+
+			b.RunParallel(func(pb *testing.PB) {
+				var local int64
+				for i := 1; pb.Next(); i++ {
+					if writeEvery > 0 && i%writeEvery == 0 {
+						l.WLock()
+						for j := range data {
+							data[j]++
+						}
+						l.WUnlock()
+					} else {
+						l.RLock()
+						for j := range data {
+							local += data[j]
+						}
+						l.RUnlock()
+					}
+				}
+
+				atomic.AddInt64(&benchSink, local)
+			})
+		})
+	}
+}
+
+func BenchmarkLocksReadOnly(b *testing.B) {
+	benchmarkLocks(b, 0)
+}
+
+func BenchmarkLocksRead99Write1(b *testing.B) {
+	benchmarkLocks(b, 100)
+}
+
+func BenchmarkLocksRead90Write10(b *testing.B) {
+	benchmarkLocks(b, 10)
+}
+
+func BenchmarkLocksRead50Write50(b *testing.B) {
+	benchmarkLocks(b, 2)
+}
+
+func BenchmarkLocksWriteOnly(b *testing.B) {
+	benchmarkLocks(b, 1)
+}
+
+// queueLocks 队列锁以及读取它们队尾指针的函数
+var queueLocks = []struct {
+	name string
+	new  func() (sync.Locker, func() unsafe.Pointer)
+}{
+	{"MCSLock", func() (sync.Locker, func() unsafe.Pointer) {
+		l := NewMCSLock()
+		return l, func() unsafe.Pointer { return atomic.LoadPointer(&l.tail) }
+	}},
+	{"CLHLock", func() (sync.Locker, func() unsafe.Pointer) {
+		l := NewCLHLock()
+		return l, func() unsafe.Pointer { return atomic.LoadPointer(&l.tail) }
+	}},
+}
+
+// 同一时刻只有一个go程持有队列锁，临界区内的普通读写不会产生数据竞争
+func TestQueueLocksMutualExclusion(t *testing.T) {
+	const (
+		goroutines = 8
+		rounds     = 500
+	)
+
+	for _, ql := range queueLocks {
+		ql := ql
+		t.Run(ql.name, func(t *testing.T) {
+			l, _ := ql.new()
+			var inside int32
+			count := 0
+
+			var wg sync.WaitGroup
+			wg.Add(goroutines)
+			for i := 0; i < goroutines; i++ {
+				go func() {
+					defer wg.Done()
+					for j := 0; j < rounds; j++ {
+						l.Lock()
+						if n := atomic.AddInt32(&inside, 1); n != 1 {
+							t.Errorf("%d goroutines inside the critical section", n)
+						}
+						count++
+						atomic.AddInt32(&inside, -1)
+						l.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			if count != goroutines*rounds {
+				t.Fatalf("count = %d, want %d", count, goroutines*rounds)
+			}
+		})
+	}
+}
+
+// 等待者按照进入队列的顺序获得队列锁
+func TestQueueLocksFIFO(t *testing.T) {
+	const waiters = 8
+
+	for _, ql := range queueLocks {
+		ql := ql
+		t.Run(ql.name, func(t *testing.T) {
+			l, tail := ql.new()
+			var log orderLog
+			var wg sync.WaitGroup
+
+			l.Lock()
+			for i := 0; i < waiters; i++ {
+				name := fmt.Sprint("g", i)
+				old := tail()
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					l.Lock()
+					log.add(name)
+					l.Unlock()
+				}()
+				// 等这个go程的结点进入队尾后再启动下一个，使到达顺序确定
+				waitUntil(t, func() bool { return tail() != old })
+			}
+			l.Unlock()
+			wg.Wait()
+
+			want := make([]string, waiters)
+			for i := range want {
+				want[i] = fmt.Sprint("g", i)
+			}
+			if got := log.get(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("acquisition order = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// 写者与所有读者互斥，读者之间可以同时持有锁
+func TestDistributedRWLockExclusion(t *testing.T) {
+	const (
+		readers = 6
+		writers = 2
+		rounds  = 300
+	)
+
+	l := NewDistributedRWLock()
+	var readersInside, writersInside int32
+	data := 0
+
+	var wg sync.WaitGroup
+	wg.Add(readers + writers)
+	for i := 0; i < readers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				l.RLock()
+				atomic.AddInt32(&readersInside, 1)
+				if n := atomic.LoadInt32(&writersInside); n != 0 {
+					t.Errorf("reader entered while %d writers were inside", n)
+				}
+				_ = data
+				atomic.AddInt32(&readersInside, -1)
+				l.RUnlock()
+			}
+		}()
+	}
+	for i := 0; i < writers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				l.WLock()
+				if n := atomic.AddInt32(&writersInside, 1); n != 1 {
+					t.Errorf("%d writers inside the critical section", n)
+				}
+				if n := atomic.LoadInt32(&readersInside); n != 0 {
+					t.Errorf("writer entered while %d readers were inside", n)
+				}
+				data++
+				atomic.AddInt32(&writersInside, -1)
+				l.WUnlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if data != writers*rounds {
+		t.Fatalf("data = %d, want %d", data, writers*rounds)
+	}
+}
+
+// 写者等待已经进入的读者离开，写者等待期间到达的读者等待写者结束
+func TestDistributedRWLockWriterWaitsForReaders(t *testing.T) {
+	l := NewDistributedRWLock()
+	var log orderLog
+
+	l.RLock()
+	l.RLock()
+
+	wrote := make(chan struct{})
+	go func() {
+		l.WLock()
+		log.add("writer")
+		l.WUnlock()
+		close(wrote)
+	}()
+	waitUntil(t, func() bool { return atomic.LoadInt32(&l.writer) == 1 })
+
+	read := make(chan struct{})
+	go func() {
+		l.RLock()
+		log.add("reader")
+		l.RUnlock()
+		close(read)
+	}()
+
+	select {
+	case <-wrote:
+		t.Fatal("writer acquired the lock while readers held it")
+	case <-read:
+		t.Fatal("reader acquired the lock while a writer was waiting")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	l.RUnlock()
+	l.RUnlock()
+	<-wrote
+	<-read
+	if got := log.get(); !reflect.DeepEqual(got, []string{"writer", "reader"}) {
+		t.Fatalf("order = %v, want [writer reader]", got)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import "sync"
+
+// ReentrantMutex 可重入互斥锁
+// Go没有公开的go程ID，因此持有者由调用方显式传入的owner标识，owner必须是可比较的非nil值，
+// 例如每个请求或任务各自的指针。同一个owner可以多次加锁，加锁几次就需要解锁几次。
+type ReentrantMutex struct {
+	c *sync.Cond
+
+	// owner 当前的持有者，为nil表示没有被持有
+	owner interface{}
+
+	// count 当前持有者加锁的次数
+	count int
+}
+
+// NewReentrantMutex 返回一个新的ReentrantMutex指针
+func NewReentrantMutex() *ReentrantMutex {
+	return &ReentrantMutex{c: sync.NewCond(new(sync.Mutex))}
+}
+
+func (m *ReentrantMutex) Lock(owner interface{}) {
+	if owner == nil {
+		panic("Lock of ReentrantMutex with nil owner")
+	}
+
+	m.c.L.Lock()
+	for m.owner != nil && m.owner != owner {
+		m.c.Wait()
+	}
+	m.owner = owner
+	m.count++
+	m.c.L.Unlock()
+}
+
+// TryLock 尝试加锁，锁被其他owner持有时立即返回false
+func (m *ReentrantMutex) TryLock(owner interface{}) bool {
+	if owner == nil {
+		panic("Lock of ReentrantMutex with nil owner")
+	}
+
+	m.c.L.Lock()
+	defer m.c.L.Unlock()
+
+	if m.owner != nil && m.owner != owner {
+		return false
+	}
+	m.owner = owner
+	m.count++
+
+	return true
+}
+
+func (m *ReentrantMutex) Unlock(owner interface{}) {
+	m.c.L.Lock()
+	if m.owner == nil || m.owner != owner {
+		m.c.L.Unlock()
+		panic("Unlock of ReentrantMutex not held by owner")
+	}
+
+	m.count--
+	if m.count == 0 {
+		m.owner = nil
+		m.c.Signal()
+	}
+	m.c.L.Unlock()
+}
+
+// HeldBy 判断锁是否被owner持有
+func (m *ReentrantMutex) HeldBy(owner interface{}) bool {
+	m.c.L.Lock()
+	defer m.c.L.Unlock()
+
+	return owner != nil && m.owner == owner
+}
+
+// ForOwner 返回以owner身份加锁的RWLocker，读锁和写锁都是互斥锁
+// 同一个owner可以重复加锁，所以返回值不能用来保护被多个go程共享的safe容器
+func (m *ReentrantMutex) ForOwner(owner interface{}) RWLocker {
+	return &ownedReentrantMutex{m: m, owner: owner}
+}
+
+type ownedReentrantMutex struct {
+	m     *ReentrantMutex
+	owner interface{}
+}
+
+func (o *ownedReentrantMutex) RLock() {
+	o.m.Lock(o.owner)
+}
+
+func (o *ownedReentrantMutex) RUnlock() {
+	o.m.Unlock(o.owner)
+}
+
+func (o *ownedReentrantMutex) WLock() {
+	o.m.Lock(o.owner)
+}
+
+func (o *ownedReentrantMutex) WUnlock() {
+	o.m.Unlock(o.owner)
+}
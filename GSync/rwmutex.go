@@ -53,6 +53,7 @@ type ReaderCountRWLock struct {
 
 // RLock 读进入的时候对readerCount变量进行访问控制
 func (l *ReaderCountRWLock) RLock() {
+	debugAcquire(l, false)
 	// 读的时候给readerCount上锁
 	l.m.Lock()
 
@@ -61,10 +62,12 @@ func (l *ReaderCountRWLock) RLock() {
 
 	// 给readerCount解锁
 	l.m.Unlock()
+	debugAcquired(l, false)
 }
 
 // RUnlock 读退出的时候对readerCount变量进行访问控制
 func (l *ReaderCountRWLock) RUnlock() {
+	debugRelease(l, false)
 	l.m.Lock()
 	l.readerCount--
 	l.m.Unlock()
@@ -72,6 +75,7 @@ func (l *ReaderCountRWLock) RUnlock() {
 
 // WLock 写者对mutex上锁，同时检查是否存在读者持有锁，如果存在，写者释放mutex并再次尝试，这叫自旋。
 func (l *ReaderCountRWLock) WLock() {
+	debugAcquire(l, true)
 	for {
 		l.m.Lock()
 		if l.readerCount > 0 {
@@ -80,10 +84,12 @@ func (l *ReaderCountRWLock) WLock() {
 			break
 		}
 	}
+	debugAcquired(l, true)
 }
 
 // WUnlock 读者释放锁
 func (l *ReaderCountRWLock) WUnlock() {
+	debugRelease(l, true)
 	l.m.Unlock()
 }
 
@@ -99,12 +105,15 @@ func NewReaderCountCondRWLock() *ReaderCountCondRWLock {
 }
 
 func (l *ReaderCountCondRWLock) RLock() {
+	debugAcquire(l, false)
 	l.c.L.Lock()
 	l.readerCount++
 	l.c.L.Unlock()
+	debugAcquired(l, false)
 }
 
 func (l *ReaderCountCondRWLock) RUnlock() {
+	debugRelease(l, false)
 	l.c.L.Lock()
 	l.readerCount--
 	if l.readerCount < 0 {
@@ -116,15 +125,18 @@ func (l *ReaderCountCondRWLock) RUnlock() {
 }
 
 func (l *ReaderCountCondRWLock) WLock() {
+	debugAcquire(l, true)
 	l.c.L.Lock()
 	// 还有读者时
 	for l.readerCount > 0 {
 		// Wait过程仍然处于循环中，因为极有可能在读者发出信号之后、写者获取锁之前，另一个读者先拿到锁。
 		l.c.Wait()
 	}
+	debugAcquired(l, true)
 }
 
 func (l *ReaderCountCondRWLock) WUnlock() {
+	debugRelease(l, true)
 	// 唤醒一个等待的线程
 	l.c.Signal()
 
@@ -147,6 +159,7 @@ func NewSemaRWLock() *SemaRWLock {
 }
 
 func (l *SemaRWLock) RLock() {
+	debugAcquire(l, false)
 	// 阻塞的获取指定权重的资源
 	// 因为可以同时有多个读者线程进行读操作，所以每个读者线程就获取1权重的资源
 	err := l.s.Acquire(context.Background(), 1)
@@ -154,22 +167,27 @@ func (l *SemaRWLock) RLock() {
 		fmt.Println(err)
 		return
 	}
+	debugAcquired(l, false)
 }
 
 func (l *SemaRWLock) RUnlock() {
+	debugRelease(l, false)
 	l.s.Release(1)
 }
 
 func (l *SemaRWLock) WLock() {
+	debugAcquire(l, true)
 	// 因为同一时刻只能有一个写者线程进行写操作，所以每个写者线程获取maxWeight权重的资源
 	err := l.s.Acquire(context.Background(), maxWeight)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
+	debugAcquired(l, true)
 }
 
 func (l *SemaRWLock) WUnlock() {
+	debugRelease(l, true)
 	l.s.Release(maxWeight)
 }
 
@@ -194,6 +212,7 @@ func NewWritePreferRWLock() *WritePreferRWLock {
 }
 
 func (l *WritePreferRWLock) RLock() {
+	debugAcquire(l, false)
 	// 读者获取锁
 	l.c.L.Lock()
 
@@ -206,9 +225,11 @@ func (l *WritePreferRWLock) RLock() {
 	// 读者自己获取锁
 	l.readerCount++
 	l.c.L.Unlock()
+	debugAcquired(l, false)
 }
 
 func (l *WritePreferRWLock) RUnlock() {
+	debugRelease(l, false)
 	l.c.L.Lock()
 	l.readerCount--
 	if l.readerCount == 0 {
@@ -220,6 +241,7 @@ func (l *WritePreferRWLock) RUnlock() {
 
 // WLock 写者在WLock与WUnlock之间不再持有mutex，取而代之，mutex仅用于控制共享结构的访问
 func (l *WritePreferRWLock) WLock() {
+	debugAcquire(l, true)
 	l.c.L.Lock()
 
 	for l.hasWriter {
@@ -232,9 +254,11 @@ func (l *WritePreferRWLock) WLock() {
 
 	// 因为有hasWriter变量为true保证其他线程都被阻塞，所以此时可以释放锁
 	l.c.L.Unlock()
+	debugAcquired(l, true)
 }
 
 func (l *WritePreferRWLock) WUnlock() {
+	debugRelease(l, true)
 	l.c.L.Lock()
 	l.hasWriter = false
 
@@ -262,12 +286,14 @@ const maxReaders int32 = 1 << 30
 
 func NewWritePreferFastRWLock() *WritePreferFastRWLock {
 	var l WritePreferFastRWLock
+	l.w = new(sync.Mutex)
 	l.writerWait = make(chan struct{})
 	l.readerWait = make(chan struct{})
 	return &l
 }
 
 func (l *WritePreferFastRWLock) RLock() {
+	debugAcquire(l, false)
 	// 通过原子操作atomic包中的操作访问该字段，因此不再需要锁
 	// 如果numPending是非负数，表明没有写者等待持有锁或正持有锁，因此读者可以继续操作
 	// 如果numPending是负数，表明写者正在等待获取锁或已经获取锁，因此读者将会让出权限，保持等待
@@ -275,9 +301,11 @@ func (l *WritePreferFastRWLock) RLock() {
 		// 保持等待是通过在一个无缓冲channel上等待实现的。
 		<-l.readerWait
 	}
+	debugAcquired(l, false)
 }
 
 func (l *WritePreferFastRWLock) RUnlock() {
+	debugRelease(l, false)
 	// 读者释放锁时，将numPending减1
 	if r := atomic.AddInt32(&l.numPending, -1); r < 0 {
 		// unlock情况下的numPending应该是等于0的，此时再对numPending进行减1的话就会让它变为-1，这是非法情况
@@ -295,6 +323,7 @@ func (l *WritePreferFastRWLock) RUnlock() {
 }
 
 func (l *WritePreferFastRWLock) WLock() {
+	debugAcquire(l, true)
 	l.w.Lock()
 	// 通过执行numPending减maxReaders操作来告知读者有一个写者在申请临界区
 	r := atomic.AddInt32(&l.numPending, -maxReaders) + maxReaders
@@ -303,9 +332,11 @@ func (l *WritePreferFastRWLock) WLock() {
 	if r != 0 && atomic.AddInt32(&l.readersDeparting, r) != 0 {
 		<-l.writerWait
 	}
+	debugAcquired(l, true)
 }
 
 func (l *WritePreferFastRWLock) WUnlock() {
+	debugRelease(l, true)
 	// 告知读者，写者已经占用完了临界区
 	r := atomic.AddInt32(&l.numPending, maxReaders)
 
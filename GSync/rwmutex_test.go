@@ -0,0 +1,77 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// NewWritePreferFastRWLock返回的锁可以直接加写锁，写者之间、写者与读者之间互斥
+func TestWritePreferFastRWLockExclusion(t *testing.T) {
+	const (
+		readers = 4
+		writers = 2
+		rounds  = 300
+	)
+
+	l := NewWritePreferFastRWLock()
+	var readersInside, writersInside int32
+	data := 0
+
+	var wg sync.WaitGroup
+	wg.Add(readers + writers)
+	for i := 0; i < readers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				l.RLock()
+				atomic.AddInt32(&readersInside, 1)
+				if n := atomic.LoadInt32(&writersInside); n != 0 {
+					t.Errorf("reader entered while %d writers were inside", n)
+				}
+				_ = data
+				atomic.AddInt32(&readersInside, -1)
+				l.RUnlock()
+			}
+		}()
+	}
+	for i := 0; i < writers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				l.WLock()
+				if n := atomic.AddInt32(&writersInside, 1); n != 1 {
+					t.Errorf("%d writers inside the critical section", n)
+				}
+				if n := atomic.LoadInt32(&readersInside); n != 0 {
+					t.Errorf("writer entered while %d readers were inside", n)
+				}
+				data++
+				atomic.AddInt32(&writersInside, -1)
+				l.WUnlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if data != writers*rounds {
+		t.Fatalf("data = %d, want %d", data, writers*rounds)
+	}
+}
@@ -0,0 +1,155 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type semaphoreWaiter struct {
+	n int64
+
+	// ready 获取成功时关闭
+	ready chan struct{}
+}
+
+// Semaphore 带权重的公平信号量
+// 等待者按照到达顺序获取，队首的等待者无法满足时后面的等待者即使请求的数量更少也不会插队，避免大请求被饿死。
+type Semaphore struct {
+	m       sync.Mutex
+	size    int64
+	cur     int64
+	waiters list.List
+}
+
+// NewSemaphore 返回总量为size的信号量
+func NewSemaphore(size int64) (*Semaphore, error) {
+	if size <= 0 {
+		return nil, errors.New("Size must be positive.")
+	}
+
+	return &Semaphore{size: size}, nil
+}
+
+// Acquire 获取n个许可，ctx被取消时放弃等待并返回ctx.Err()
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	if n < 0 || n > s.size {
+		return errors.New("Weight is out of range.")
+	}
+
+	s.m.Lock()
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.m.Unlock()
+		return nil
+	}
+
+	ready := make(chan struct{})
+	elem := s.waiters.PushBack(&semaphoreWaiter{n: n, ready: ready})
+	s.m.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.m.Lock()
+		defer s.m.Unlock()
+
+		select {
+		case <-ready:
+			// 取消的同时已经获取成功，当作没有取消
+			return nil
+		default:
+		}
+
+		isFront := s.waiters.Front() == elem
+		s.waiters.Remove(elem)
+		// 队首的等待者离开后，后面的等待者可能已经可以满足
+		if isFront && s.size > s.cur {
+			s.notifyWaiters()
+		}
+		return ctx.Err()
+	}
+}
+
+// AcquireTimeout 获取n个许可，最多等待d
+func (s *Semaphore) AcquireTimeout(d time.Duration, n int64) error {
+	return waitTimeout(d, func(ctx context.Context) error {
+		return s.Acquire(ctx, n)
+	})
+}
+
+// TryAcquire 不等待地获取n个许可，有等待者时也会失败
+func (s *Semaphore) TryAcquire(n int64) bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	ok := n >= 0 && s.size-s.cur >= n && s.waiters.Len() == 0
+	if ok {
+		s.cur += n
+	}
+
+	return ok
+}
+
+// Release 归还n个许可
+func (s *Semaphore) Release(n int64) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if n < 0 || n > s.cur {
+		panic("Release of unacquired Semaphore")
+	}
+	s.cur -= n
+	s.notifyWaiters()
+}
+
+// notifyWaiters 按顺序唤醒可以满足的等待者，调用时必须持有锁
+func (s *Semaphore) notifyWaiters() {
+	for {
+		front := s.waiters.Front()
+		if front == nil {
+			return
+		}
+
+		w := front.Value.(*semaphoreWaiter)
+		if s.size-s.cur < w.n {
+			return
+		}
+
+		s.cur += w.n
+		s.waiters.Remove(front)
+		close(w.ready)
+	}
+}
+
+// Available 返回当前剩余的许可数量
+func (s *Semaphore) Available() int64 {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.size - s.cur
+}
+
+func (s *Semaphore) Size() int64 {
+	return s.size
+}
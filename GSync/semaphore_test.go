@@ -0,0 +1,114 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// acquireAsync 在新的go程中获取n个许可，等它进入等待队列后返回结果通道
+func acquireAsync(t *testing.T, s *Semaphore, ctx context.Context, n int64) <-chan error {
+	t.Helper()
+	s.m.Lock()
+	queued := s.waiters.Len()
+	s.m.Unlock()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- s.Acquire(ctx, n)
+	}()
+	waitUntil(t, func() bool {
+		s.m.Lock()
+		defer s.m.Unlock()
+		return s.waiters.Len() == queued+1
+	})
+
+	return result
+}
+
+// 取消等待后不占用许可，也不留在等待队列中
+func TestSemaphoreCancel(t *testing.T) {
+	s, err := NewSemaphore(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Acquire(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := acquireAsync(t, s, ctx, 5)
+	cancel()
+	if err := <-result; err != context.Canceled {
+		t.Fatalf("cancelled Acquire returned %v, want %v", err, context.Canceled)
+	}
+	if err := s.AcquireTimeout(10*time.Millisecond, 1); err != context.DeadlineExceeded {
+		t.Fatalf("AcquireTimeout returned %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	s.Release(10)
+	if n := s.Available(); n != 10 {
+		t.Fatalf("Available() = %d after cancelled waiters, want 10", n)
+	}
+	if !s.TryAcquire(10) {
+		t.Fatal("TryAcquire failed although no waiter is queued")
+	}
+}
+
+// 队首的大请求取消后，排在后面的小请求立即获得许可
+func TestSemaphoreCancelFrontWakesNext(t *testing.T) {
+	s, err := NewSemaphore(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Acquire(context.Background(), 8); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	big := acquireAsync(t, s, ctx, 5)
+	small := acquireAsync(t, s, context.Background(), 1)
+
+	// 还有2个许可，但小请求不能插到队首的大请求前面
+	select {
+	case err := <-small:
+		t.Fatalf("small Acquire returned %v ahead of the queued big request", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if s.TryAcquire(1) {
+		t.Fatal("TryAcquire succeeded while waiters are queued")
+	}
+
+	cancel()
+	if err := <-big; err != context.Canceled {
+		t.Fatalf("cancelled Acquire returned %v, want %v", err, context.Canceled)
+	}
+	select {
+	case err := <-small:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("small Acquire was not woken after the front waiter cancelled")
+	}
+	if n := s.Available(); n != 1 {
+		t.Fatalf("Available() = %d, want 1", n)
+	}
+}
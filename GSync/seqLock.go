@@ -0,0 +1,217 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// SeqLock 顺序锁，适用于读非常频繁、写很少的小块数据
+// 写者在修改前后各把序号加一，修改期间序号为奇数；读者不加锁，读之前和读之后各读一次序号，
+// 如果序号为奇数或者前后不一致，说明读的过程中有写者修改了数据，需要重新读。
+// 按照Go的内存模型，读者和写者同时访问普通变量属于数据竞争，因此被保护的数据必须通过atomic包读写，
+// 一般情况下应该直接使用SeqValue。
+type SeqLock struct {
+	seq uint64
+
+	// m 写者之间互斥
+	m sync.Mutex
+}
+
+// ReadBegin 开始读，返回当前的序号，有写者正在修改时等待修改完成
+func (l *SeqLock) ReadBegin() uint64 {
+	for {
+		seq := atomic.LoadUint64(&l.seq)
+		if seq&1 == 0 {
+			return seq
+		}
+		runtime.Gosched()
+	}
+}
+
+// ReadRetry 结束读，返回true表示读的过程中数据被修改过，需要重新读
+func (l *SeqLock) ReadRetry(seq uint64) bool {
+	return atomic.LoadUint64(&l.seq) != seq
+}
+
+// Lock 写者开始修改
+func (l *SeqLock) Lock() {
+	debugAcquire(l, true)
+	l.m.Lock()
+	atomic.AddUint64(&l.seq, 1)
+	debugAcquired(l, true)
+}
+
+// Unlock 写者结束修改
+func (l *SeqLock) Unlock() {
+	debugRelease(l, true)
+	if atomic.LoadUint64(&l.seq)&1 == 0 {
+		panic("Unlock of unlocked SeqLock")
+	}
+	atomic.AddUint64(&l.seq, 1)
+	l.m.Unlock()
+}
+
+// SeqValue 由SeqLock保护的值
+// 值按8字节一组保存在通过原子操作读写的数组中，因此值的类型不能包含指针，
+// 否则垃圾回收器无法识别数组中的指针。
+type SeqValue struct {
+	l     SeqLock
+	t     reflect.Type
+	size  uintptr
+	words []uint64
+}
+
+// NewSeqValue 返回保存v的SeqValue，之后只能存取与v类型相同的值
+func NewSeqValue(v interface{}) (*SeqValue, error) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil, errors.New("Value of SeqValue can not be nil.")
+	}
+	if hasPointers(t) {
+		return nil, errors.New("Type of SeqValue can not contain pointers.")
+	}
+
+	s := &SeqValue{
+		t:     t,
+		size:  t.Size(),
+		words: make([]uint64, (t.Size()+7)/8),
+	}
+	if err := s.Store(v); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// hasPointers 判断类型中是否含有指针
+func hasPointers(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Array:
+		return t.Len() > 0 && hasPointers(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if hasPointers(t.Field(i).Type) {
+				return true
+			}
+		}
+		return false
+	case reflect.Ptr, reflect.UnsafePointer, reflect.Map, reflect.Slice, reflect.String,
+		reflect.Interface, reflect.Func, reflect.Chan:
+		return true
+	}
+
+	return false
+}
+
+// bytesAt 返回从p开始的size个字节
+func bytesAt(p unsafe.Pointer, size uintptr) []byte {
+	return (*[1 << 30]byte)(p)[:size:size]
+}
+
+// toWords 把p指向的值复制到buf中
+func (s *SeqValue) toWords(p unsafe.Pointer, buf []uint64) {
+	if s.size > 0 {
+		copy(bytesAt(unsafe.Pointer(&buf[0]), s.size), bytesAt(p, s.size))
+	}
+}
+
+// fromWords 把buf中的值复制到p指向的变量中
+func (s *SeqValue) fromWords(buf []uint64, p unsafe.Pointer) {
+	if s.size > 0 {
+		copy(bytesAt(p, s.size), bytesAt(unsafe.Pointer(&buf[0]), s.size))
+	}
+}
+
+// write 写入buf，调用时必须持有写者的互斥锁
+func (s *SeqValue) write(buf []uint64) {
+	atomic.AddUint64(&s.l.seq, 1)
+	for i := range buf {
+		atomic.StoreUint64(&s.words[i], buf[i])
+	}
+	atomic.AddUint64(&s.l.seq, 1)
+}
+
+// Load 把当前的值复制到ptr指向的变量中，ptr的类型必须是指向值类型的指针
+func (s *SeqValue) Load(ptr interface{}) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Type().Elem() != s.t {
+		return errors.New("Type of ptr does not match SeqValue.")
+	}
+
+	buf := make([]uint64, len(s.words))
+	for {
+		seq := s.l.ReadBegin()
+		for i := range s.words {
+			buf[i] = atomic.LoadUint64(&s.words[i])
+		}
+		if !s.l.ReadRetry(seq) {
+			break
+		}
+	}
+	s.fromWords(buf, unsafe.Pointer(v.Pointer()))
+
+	return nil
+}
+
+// Store 保存value，value的类型必须与创建时的值相同，也可以是指向该类型的指针
+func (s *SeqValue) Store(value interface{}) error {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr && v.Type().Elem() == s.t && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Type() != s.t {
+		return errors.New("Type of value does not match SeqValue.")
+	}
+
+	// 先把值复制到可寻址的临时变量中
+	tmp := reflect.New(s.t)
+	tmp.Elem().Set(v)
+	buf := make([]uint64, len(s.words))
+	s.toWords(unsafe.Pointer(tmp.Pointer()), buf)
+
+	s.l.m.Lock()
+	s.write(buf)
+	s.l.m.Unlock()
+
+	return nil
+}
+
+// Update 原子地读取并修改值，fn的参数是指向当前值副本的指针，fn返回后副本被写回
+// 写者之间互斥，fn执行期间读者仍然可以读到修改前的值
+func (s *SeqValue) Update(fn func(ptr interface{})) {
+	s.l.m.Lock()
+	defer s.l.m.Unlock()
+
+	buf := make([]uint64, len(s.words))
+	for i := range s.words {
+		buf[i] = atomic.LoadUint64(&s.words[i])
+	}
+	tmp := reflect.New(s.t)
+	s.fromWords(buf, unsafe.Pointer(tmp.Pointer()))
+
+	fn(tmp.Interface())
+
+	s.toWords(unsafe.Pointer(tmp.Pointer()), buf)
+	s.write(buf)
+}
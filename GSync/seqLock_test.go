@@ -0,0 +1,164 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// wideValue 跨越多个字的值，写者总是让所有字段相等，读者读到不相等的字段说明读到了撕裂的值
+type wideValue struct {
+	A, B, C, D int64
+	E          [4]int64
+}
+
+func newWideValue(n int64) wideValue {
+	return wideValue{A: n, B: n, C: n, D: n, E: [4]int64{n, n, n, n}}
+}
+
+func (v wideValue) consistent() bool {
+	for _, e := range v.E {
+		if e != v.A {
+			return false
+		}
+	}
+	return v.A == v.B && v.B == v.C && v.C == v.D
+}
+
+// seqValueReaders 启动readers个读者不断读取s直到stop被设置，返回等待读者结束并返回读到撕裂值次数的函数
+func seqValueReaders(t *testing.T, s *SeqValue, readers int, stop *int32, check func(prev, cur wideValue)) func() int64 {
+	var torn int64
+	var wg sync.WaitGroup
+	wg.Add(readers)
+	for i := 0; i < readers; i++ {
+		go func() {
+			defer wg.Done()
+			var prev wideValue
+			for atomic.LoadInt32(stop) == 0 {
+				var cur wideValue
+				if err := s.Load(&cur); err != nil {
+					t.Error(err)
+					return
+				}
+				if !cur.consistent() {
+					atomic.AddInt64(&torn, 1)
+				}
+				if check != nil {
+					check(prev, cur)
+				}
+				prev = cur
+			}
+		}()
+	}
+
+	return func() int64 {
+		wg.Wait()
+		return atomic.LoadInt64(&torn)
+	}
+}
+
+// 多个写者同时Store，读者永远不会读到撕裂的值
+func TestSeqValueConcurrentStore(t *testing.T) {
+	const (
+		writers = 4
+		readers = 4
+		stores  = 5000
+	)
+	s, err := NewSeqValue(newWideValue(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stop int32
+	wait := seqValueReaders(t, s, readers, &stop, nil)
+
+	var wg sync.WaitGroup
+	wg.Add(writers)
+	for w := 0; w < writers; w++ {
+		base := int64(w) * stores
+		go func() {
+			defer wg.Done()
+			for i := int64(1); i <= stores; i++ {
+				if err := s.Store(newWideValue(base + i)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	atomic.StoreInt32(&stop, 1)
+
+	if torn := wait(); torn != 0 {
+		t.Fatalf("readers observed %d torn values", torn)
+	}
+}
+
+// 多个写者同时Update，更新不会丢失，读者读到的值一致且不会倒退
+func TestSeqValueConcurrentUpdate(t *testing.T) {
+	const (
+		writers = 4
+		readers = 4
+		updates = 5000
+	)
+	s, err := NewSeqValue(newWideValue(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stop int32
+	var regressed int64
+	wait := seqValueReaders(t, s, readers, &stop, func(prev, cur wideValue) {
+		if cur.A < prev.A {
+			atomic.AddInt64(&regressed, 1)
+		}
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(writers)
+	for w := 0; w < writers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := 0; i < updates; i++ {
+				s.Update(func(ptr interface{}) {
+					v := ptr.(*wideValue)
+					*v = newWideValue(v.A + 1)
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	atomic.StoreInt32(&stop, 1)
+
+	if torn := wait(); torn != 0 {
+		t.Fatalf("readers observed %d torn values", torn)
+	}
+	if regressed != 0 {
+		t.Fatalf("readers observed the value going backwards %d times", regressed)
+	}
+
+	var final wideValue
+	if err := s.Load(&final); err != nil {
+		t.Fatal(err)
+	}
+	if want := newWideValue(writers * updates); final != want {
+		t.Fatalf("final value = %+v, want %+v", final, want)
+	}
+}
@@ -0,0 +1,213 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// flight 一次正在执行或者仍在宽限期内的调用
+type flight struct {
+	// done 执行结束时关闭，val和err在关闭之前写入
+	done chan struct{}
+	val  interface{}
+	err  error
+
+	// callers 得到这次结果的调用者数量，包括执行者
+	callers int
+
+	// shared 执行结束时是否有其他调用者在等待
+	shared bool
+
+	// retired 结果不再共享
+	retired bool
+}
+
+// Result DoChan返回的结果
+type Result struct {
+	Val    interface{}
+	Err    error
+	Shared bool
+}
+
+// Group 合并相同key的并发调用，同一时刻每个key只有一次调用在执行，其他调用者等待并共享它的结果
+// 设置了宽限期时，成功的结果在执行结束后的一段时间内继续共享，这段时间内的调用直接返回该结果
+type Group struct {
+	m       sync.Mutex
+	flights map[string]*flight
+	grace   time.Duration
+
+	// onCoalesced 结果不再共享时调用
+	onCoalesced func(key string, callers int)
+}
+
+// NewGroup grace为成功的结果在执行结束后继续共享的时间，为0时结果只共享给执行期间到达的调用者
+func NewGroup(grace time.Duration) *Group {
+	return &Group{flights: make(map[string]*flight), grace: grace}
+}
+
+// OnCoalesced 设置统计合并次数的回调，每次调用的结果不再共享时调用一次，
+// callers为得到这个结果的调用者数量，大于1说明有调用被合并
+func (g *Group) OnCoalesced(f func(key string, callers int)) {
+	g.m.Lock()
+	defer g.m.Unlock()
+
+	g.onCoalesced = f
+}
+
+// join 返回key正在共享的调用，没有时创建一个新的调用并返回true，调用时必须持有锁
+func (g *Group) join(key string) (*flight, bool) {
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+
+	if f, ok := g.flights[key]; ok {
+		f.callers++
+		return f, false
+	}
+
+	f := &flight{done: make(chan struct{}), callers: 1}
+	g.flights[key] = f
+
+	return f, true
+}
+
+// run 执行fn并唤醒所有等待者，fn中的panic或者runtime.Goexit会被转换为错误返回给所有调用者
+func (g *Group) run(key string, f *flight, fn func() (interface{}, error)) {
+	normalReturn := false
+	defer func() {
+		// fn调用runtime.Goexit时recover返回nil，只能通过normalReturn区分，这时的结果不能当作成功共享
+		if !normalReturn {
+			if r := recover(); r != nil {
+				f.val, f.err = nil, fmt.Errorf("singleflight: %v panicked: %v", key, r)
+			} else {
+				f.val, f.err = nil, fmt.Errorf("singleflight: %v called runtime.Goexit", key)
+			}
+		}
+
+		g.m.Lock()
+		f.shared = f.callers > 1
+		close(f.done)
+		grace := g.grace > 0 && f.err == nil && g.flights[key] == f
+		g.m.Unlock()
+
+		if grace {
+			time.AfterFunc(g.grace, func() {
+				g.retire(key, f)
+			})
+		} else {
+			g.retire(key, f)
+		}
+	}()
+
+	f.val, f.err = fn()
+	normalReturn = true
+}
+
+// retire 停止共享f的结果，第一次停止时调用回调
+func (g *Group) retire(key string, f *flight) {
+	g.m.Lock()
+	if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+	if f.retired {
+		g.m.Unlock()
+		return
+	}
+	f.retired = true
+	callers, hook := f.callers, g.onCoalesced
+	g.m.Unlock()
+
+	if hook != nil {
+		hook(key, callers)
+	}
+}
+
+// Do 执行fn并返回结果，key相同的调用正在执行或者仍在宽限期内时等待并返回它的结果
+// shared表示结果是否与其他调用者共享
+func (g *Group) Do(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.m.Lock()
+	f, first := g.join(key)
+	g.m.Unlock()
+
+	if first {
+		g.run(key, f, fn)
+		return f.val, f.err, f.shared
+	}
+
+	<-f.done
+
+	return f.val, f.err, true
+}
+
+// DoChan 与Do相同，但是不阻塞，结果通过返回的通道发送
+// ctx被取消时通道中收到ctx.Err()，fn不会因此停止，仍会把结果共享给其他调用者
+func (g *Group) DoChan(ctx context.Context, key string, fn func() (interface{}, error)) <-chan Result {
+	ch := make(chan Result, 1)
+
+	g.m.Lock()
+	f, first := g.join(key)
+	g.m.Unlock()
+
+	if first {
+		go g.run(key, f, fn)
+	}
+
+	go func() {
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			select {
+			case <-f.done:
+			default:
+				ch <- Result{Err: ctx.Err()}
+				return
+			}
+		}
+
+		shared := true
+		if first {
+			shared = f.shared
+		}
+		ch <- Result{Val: f.val, Err: f.err, Shared: shared}
+	}()
+
+	return ch
+}
+
+// Forget 让之后key相同的调用重新执行fn，正在等待的调用者仍然得到原来的结果
+func (g *Group) Forget(key string) {
+	g.m.Lock()
+	f, ok := g.flights[key]
+	if !ok {
+		g.m.Unlock()
+		return
+	}
+	delete(g.flights, key)
+	g.m.Unlock()
+
+	// 已经执行结束的调用处于宽限期内，直接停止共享；正在执行的调用在结束时停止共享
+	select {
+	case <-f.done:
+		g.retire(key, f)
+	default:
+	}
+}
@@ -0,0 +1,268 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitCallers 等待key正在共享的调用有n个调用者
+func waitCallers(t *testing.T, g *Group, key string, n int) {
+	t.Helper()
+	waitUntil(t, func() bool {
+		g.m.Lock()
+		defer g.m.Unlock()
+
+		f, ok := g.flights[key]
+		return ok && f.callers == n
+	})
+}
+
+// coalesceLog 记录OnCoalesced的回调
+type coalesceLog struct {
+	m     sync.Mutex
+	calls []int
+}
+
+func (l *coalesceLog) hook(key string, callers int) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	l.calls = append(l.calls, callers)
+}
+
+func (l *coalesceLog) get() []int {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	return append([]int(nil), l.calls...)
+}
+
+// 执行期间到达的调用等待并共享同一个结果，结果不再共享时回调一次，callers包括执行者
+func TestGroupDoCoalesces(t *testing.T) {
+	g := NewGroup(0)
+	var log coalesceLog
+	g.OnCoalesced(log.hook)
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "v", nil
+	}
+
+	const waiters = 4
+	var wg sync.WaitGroup
+	results := make(chan bool, waiters+1)
+	wg.Add(waiters + 1)
+	go func() {
+		defer wg.Done()
+		v, err, shared := g.Do("k", fn)
+		if v != "v" || err != nil {
+			t.Errorf("Do() = %v, %v", v, err)
+		}
+		results <- shared
+	}()
+	waitCallers(t, g, "k", 1)
+	for i := 0; i < waiters; i++ {
+		go func() {
+			defer wg.Done()
+			v, err, shared := g.Do("k", fn)
+			if v != "v" || err != nil {
+				t.Errorf("Do() = %v, %v", v, err)
+			}
+			results <- shared
+		}()
+	}
+	waitCallers(t, g, "k", waiters+1)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for shared := range results {
+		if !shared {
+			t.Fatal("Do() reported an unshared result while others were waiting")
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("fn called %d times, want 1", n)
+	}
+	if got := log.get(); len(got) != 1 || got[0] != waiters+1 {
+		t.Fatalf("OnCoalesced calls = %v, want [%d]", got, waiters+1)
+	}
+
+	// 没有其他调用者时结果不共享，callers为1
+	if _, _, shared := g.Do("k", func() (interface{}, error) { return nil, nil }); shared {
+		t.Fatal("Do() without waiters reported a shared result")
+	}
+	if got := log.get(); len(got) != 2 || got[1] != 1 {
+		t.Fatalf("OnCoalesced calls = %v, want [%d 1]", got, waiters+1)
+	}
+}
+
+// DoChan的ctx被取消时只有这个调用者收到ctx.Err()，fn继续执行并把结果交给其他调用者
+func TestGroupDoChanCancel(t *testing.T) {
+	g := NewGroup(0)
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-release
+		return 1, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := g.DoChan(ctx, "k", fn)
+	waitCallers(t, g, "k", 1)
+	other := g.DoChan(context.Background(), "k", fn)
+	waitCallers(t, g, "k", 2)
+
+	cancel()
+	select {
+	case r := <-cancelled:
+		if r.Err != context.Canceled {
+			t.Fatalf("cancelled DoChan got %+v, want context.Canceled", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled DoChan did not return")
+	}
+
+	close(release)
+	r := <-other
+	if r.Val != 1 || r.Err != nil || !r.Shared {
+		t.Fatalf("DoChan() = %+v, want shared 1", r)
+	}
+}
+
+// Forget之后key相同的调用重新执行fn，已经在等待的调用者仍然得到原来的结果
+func TestGroupForget(t *testing.T) {
+	g := NewGroup(0)
+	release := make(chan struct{})
+	first := g.DoChan(context.Background(), "k", func() (interface{}, error) {
+		<-release
+		return "old", nil
+	})
+	waitCallers(t, g, "k", 1)
+	waiter := g.DoChan(context.Background(), "k", func() (interface{}, error) {
+		return "unused", nil
+	})
+	waitCallers(t, g, "k", 2)
+
+	g.Forget("k")
+	v, err, shared := g.Do("k", func() (interface{}, error) {
+		return "new", nil
+	})
+	if v != "new" || err != nil || shared {
+		t.Fatalf("Do() after Forget = %v, %v, %v, want new, nil, false", v, err, shared)
+	}
+
+	close(release)
+	for _, ch := range []<-chan Result{first, waiter} {
+		if r := <-ch; r.Val != "old" || r.Err != nil {
+			t.Fatalf("DoChan() = %+v, want old", r)
+		}
+	}
+}
+
+// 宽限期内成功的结果直接返回，错误不缓存，Forget结束宽限期
+func TestGroupGrace(t *testing.T) {
+	g := NewGroup(time.Hour)
+	var log coalesceLog
+	g.OnCoalesced(log.hook)
+
+	var calls int32
+	fn := func() (interface{}, error) {
+		return atomic.AddInt32(&calls, 1), nil
+	}
+
+	if v, _, shared := g.Do("k", fn); v != int32(1) || shared {
+		t.Fatalf("first Do() = %v, shared %v, want 1, false", v, shared)
+	}
+	for i := 0; i < 3; i++ {
+		if v, _, shared := g.Do("k", fn); v != int32(1) || !shared {
+			t.Fatalf("Do() within grace = %v, shared %v, want cached 1", v, shared)
+		}
+	}
+	if got := log.get(); len(got) != 0 {
+		t.Fatalf("OnCoalesced called during grace: %v", got)
+	}
+
+	g.Forget("k")
+	if got := log.get(); len(got) != 1 || got[0] != 4 {
+		t.Fatalf("OnCoalesced calls = %v, want [4]", got)
+	}
+	if v, _, _ := g.Do("k", fn); v != int32(2) {
+		t.Fatalf("Do() after Forget = %v, want 2", v)
+	}
+
+	fail := errors.New("fail")
+	for i := 0; i < 2; i++ {
+		_, err, shared := g.Do("e", func() (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, fail
+		})
+		if err != fail || shared {
+			t.Fatalf("Do() = %v, shared %v, want fresh error", err, shared)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 4 {
+		t.Fatalf("fn called %d times, want 4", n)
+	}
+}
+
+// fn panic或者调用runtime.Goexit时所有调用者收到错误，结果不进入宽限期
+func TestGroupPanicAndGoexit(t *testing.T) {
+	g := NewGroup(time.Hour)
+
+	_, err, _ := g.Do("p", func() (interface{}, error) {
+		panic("boom")
+	})
+	if err == nil {
+		t.Fatal("Do() with panicking fn returned no error")
+	}
+
+	release := make(chan struct{})
+	first := g.DoChan(context.Background(), "g", func() (interface{}, error) {
+		<-release
+		runtime.Goexit()
+		return "unreachable", nil
+	})
+	waitCallers(t, g, "g", 1)
+	waiter := g.DoChan(context.Background(), "g", nil)
+	waitCallers(t, g, "g", 2)
+	close(release)
+	for _, ch := range []<-chan Result{first, waiter} {
+		if r := <-ch; r.Err == nil || r.Val != nil {
+			t.Fatalf("DoChan() after Goexit = %+v, want an error", r)
+		}
+	}
+
+	for _, key := range []string{"p", "g"} {
+		v, err, shared := g.Do(key, func() (interface{}, error) {
+			return "fresh", nil
+		})
+		if v != "fresh" || err != nil || shared {
+			t.Fatalf("Do(%q) after failure = %v, %v, %v, want a fresh call", key, v, err, shared)
+		}
+	}
+}
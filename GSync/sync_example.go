@@ -0,0 +1,177 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+func BarrierExample() {
+	round := 0
+	b, err := NewBarrier(3, func() {
+		round++
+		fmt.Println("round", round, "finished")
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// 屏障可以重复使用，每一轮都等待3个参与者
+			for r := 0; r < 2; r++ {
+				if _, err := b.Await(context.Background()); err != nil {
+					fmt.Println(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 等待超时会破坏屏障，Reset之后才能继续使用
+	_, err = b.AwaitTimeout(10 * time.Millisecond)
+	fmt.Println(err, b.IsBroken())
+	b.Reset()
+	fmt.Println(b.IsBroken())
+}
+
+func CountDownLatchExample() {
+	l, err := NewCountDownLatch(3)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	for i := 0; i < 3; i++ {
+		go func() {
+			l.CountDown()
+		}()
+	}
+
+	fmt.Println(l.WaitTimeout(time.Second), l.Count())
+}
+
+func PhaserExample() {
+	p, err := NewPhaser(1, nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		// 参与者可以随时注册，从注册时的阶段开始参与
+		_, _ = p.Register()
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for phase := 0; phase < i+1; phase++ {
+				_, _ = p.ArriveAndAwaitAdvance(context.Background())
+			}
+			_, _ = p.ArriveAndDeregister()
+		}(i)
+	}
+
+	// 主协程只到达而不等待，然后注销
+	for p.RegisteredParties() > 1 {
+		phase, _ := p.Arrive()
+		_, _ = p.AwaitAdvance(context.Background(), phase)
+	}
+	_, _ = p.ArriveAndDeregister()
+	wg.Wait()
+
+	fmt.Println(p.IsTerminated())
+}
+
+func SemaphoreExample() {
+	s, err := NewSemaphore(10)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	_ = s.Acquire(context.Background(), 8)
+
+	// 大请求排在队首时小请求也不能插队
+	done := make(chan struct{})
+	go func() {
+		_ = s.Acquire(context.Background(), 5)
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	fmt.Println(s.TryAcquire(1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	fmt.Println(s.Acquire(ctx, 1))
+
+	s.Release(8)
+	<-done
+	fmt.Println(s.Available())
+	s.Release(5)
+}
+
+func GroupExample() {
+	g := NewGroup(50 * time.Millisecond)
+	g.OnCoalesced(func(key string, callers int) {
+		fmt.Println(key, "shared by", callers, "callers")
+	})
+
+	var calls int32
+	load := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return "value", nil
+	}
+
+	// 并发的相同请求只会执行一次
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, _ = g.Do("config", load)
+		}()
+	}
+	wg.Wait()
+
+	// 宽限期内直接返回上一次的结果
+	v, err, shared := g.Do("config", load)
+	fmt.Println(v, err, shared, atomic.LoadInt32(&calls))
+
+	// 调用者可以放弃等待，执行不会被打断
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	r := <-g.DoChan(ctx, "slow", func() (interface{}, error) {
+		time.Sleep(20 * time.Millisecond)
+		return nil, nil
+	})
+	fmt.Println(r.Err)
+
+	g.Forget("config")
+	time.Sleep(50 * time.Millisecond)
+}
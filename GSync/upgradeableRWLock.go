@@ -0,0 +1,143 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package GSync
+
+import "sync"
+
+// UpgradeableRWLock 支持可升级读锁的读写锁
+// 可升级读锁可以和普通读锁同时持有，但同一时刻最多只有一个持有者，持有者可以调用Upgrade在不释放锁的情况下升级为写锁。
+// 如果允许多个读者同时升级，它们会互相等待对方释放读锁而死锁，因此只有可升级读锁的持有者可以升级。
+// 有写者或升级者在等待时，新的读者会被阻塞，保证写者不会饥饿。
+type UpgradeableRWLock struct {
+	c *sync.Cond
+
+	// readers 持有普通读锁的读者数量
+	readers int
+
+	// upgradeable 是否有可升级读锁的持有者
+	upgradeable bool
+
+	// writer 是否有写者持有锁
+	writer bool
+
+	// writersWaiting 等待获取写锁或者等待升级的数量
+	writersWaiting int
+}
+
+// NewUpgradeableRWLock 返回一个新的UpgradeableRWLock指针
+func NewUpgradeableRWLock() *UpgradeableRWLock {
+	return &UpgradeableRWLock{c: sync.NewCond(new(sync.Mutex))}
+}
+
+func (l *UpgradeableRWLock) RLock() {
+	l.c.L.Lock()
+	for l.writer || l.writersWaiting > 0 {
+		l.c.Wait()
+	}
+	l.readers++
+	l.c.L.Unlock()
+}
+
+func (l *UpgradeableRWLock) RUnlock() {
+	l.c.L.Lock()
+	if l.readers == 0 {
+		l.c.L.Unlock()
+		panic("RUnlock of unlocked UpgradeableRWLock")
+	}
+	l.readers--
+	if l.readers == 0 {
+		l.c.Broadcast()
+	}
+	l.c.L.Unlock()
+}
+
+// ULock 获取可升级读锁
+func (l *UpgradeableRWLock) ULock() {
+	l.c.L.Lock()
+	for l.writer || l.upgradeable || l.writersWaiting > 0 {
+		l.c.Wait()
+	}
+	l.upgradeable = true
+	l.c.L.Unlock()
+}
+
+// UUnlock 释放可升级读锁
+func (l *UpgradeableRWLock) UUnlock() {
+	l.c.L.Lock()
+	if !l.upgradeable {
+		l.c.L.Unlock()
+		panic("UUnlock of unlocked UpgradeableRWLock")
+	}
+	l.upgradeable = false
+	l.c.Broadcast()
+	l.c.L.Unlock()
+}
+
+// Upgrade 把持有的可升级读锁升级为写锁，等待其他读者释放锁期间不会有新的读者进入
+func (l *UpgradeableRWLock) Upgrade() {
+	l.c.L.Lock()
+	if !l.upgradeable {
+		l.c.L.Unlock()
+		panic("Upgrade without holding upgradeable lock")
+	}
+
+	l.writersWaiting++
+	for l.readers > 0 {
+		l.c.Wait()
+	}
+	l.writersWaiting--
+	l.upgradeable = false
+	l.writer = true
+	l.c.L.Unlock()
+}
+
+func (l *UpgradeableRWLock) WLock() {
+	l.c.L.Lock()
+	l.writersWaiting++
+	for l.writer || l.upgradeable || l.readers > 0 {
+		l.c.Wait()
+	}
+	l.writersWaiting--
+	l.writer = true
+	l.c.L.Unlock()
+}
+
+func (l *UpgradeableRWLock) WUnlock() {
+	l.c.L.Lock()
+	if !l.writer {
+		l.c.L.Unlock()
+		panic("WUnlock of unlocked UpgradeableRWLock")
+	}
+	l.writer = false
+	l.c.Broadcast()
+	l.c.L.Unlock()
+}
+
+// Downgrade 把持有的写锁降级为可升级读锁，之后需要调用UUnlock释放
+// 降级过程中锁不会被释放，其他写者不能在这期间修改数据，等待的读者会被唤醒
+func (l *UpgradeableRWLock) Downgrade() {
+	l.c.L.Lock()
+	if !l.writer {
+		l.c.L.Unlock()
+		panic("Downgrade without holding write lock")
+	}
+	l.writer = false
+	l.upgradeable = true
+	l.c.Broadcast()
+	l.c.L.Unlock()
+}
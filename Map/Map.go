@@ -0,0 +1,45 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Map
+
+type Map interface {
+	// Load 返回key对应的值
+	Load(key interface{}) (interface{}, bool)
+
+	// Store 设置key对应的值
+	Store(key, value interface{})
+
+	// LoadOrStore key存在时返回已有的值，否则存入value，loaded表示key是否已经存在
+	LoadOrStore(key, value interface{}) (actual interface{}, loaded bool)
+
+	// LoadAndDelete 删除key并返回删除前的值
+	LoadAndDelete(key interface{}) (interface{}, bool)
+
+	Delete(key interface{})
+
+	// Compute 原子地读取并修改key对应的值
+	// fn的参数为key当前的值以及key是否存在，返回新的值以及key之后是否存在
+	Compute(key interface{}, fn func(value interface{}, loaded bool) (interface{}, bool)) (interface{}, bool)
+
+	// Range 遍历所有的键值对，f返回false时停止遍历
+	Range(f func(key, value interface{}) bool)
+
+	Len() int
+
+	Clear()
+}
@@ -0,0 +1,76 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Map
+
+import (
+	"fmt"
+	"sync"
+)
+
+func ShardedMapExample() {
+	m, err := NewShardedMap(16)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// 多个go程并发计数
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.Compute(j%10, func(value interface{}, loaded bool) (interface{}, bool) {
+					if !loaded {
+						return 1, true
+					}
+					return value.(int) + 1, true
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	v, ok := m.Load(3)
+	fmt.Println("m.Load =", v, ok)
+	actual, loaded := m.LoadOrStore("new", 1)
+	fmt.Println("m.LoadOrStore =", actual, loaded)
+
+	other, _ := NewShardedMap(4)
+	other.StoreAll(map[interface{}]interface{}{3: 1000, "other": true})
+	m.Merge(other, func(key, old, new interface{}) interface{} {
+		return old.(int) + new.(int)
+	})
+	fmt.Println("m.Len =", m.Len())
+	fmt.Println("m.String =", m.String())
+}
+
+func PersistentMapExample() {
+	v1 := NewPersistentMap().Store("a", 1).Store("b", 2)
+	v2 := v1.Store("b", 20).Store("c", 3).Delete("a")
+
+	// 每次修改都返回新版本，旧版本保持不变
+	fmt.Println(v1, v2)
+
+	v1.Diff(v2, func(key interface{}, t DiffType, oldValue, newValue interface{}) bool {
+		fmt.Println(key, t, oldValue, newValue)
+		return true
+	})
+	fmt.Println(v1.Equal(v2), v1.Equal(v2.Store("b", 2).Store("a", 1).Delete("c")))
+}
@@ -0,0 +1,568 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Map
+
+import (
+	"GTL/Container"
+	"fmt"
+	"math/bits"
+	"reflect"
+	"strings"
+)
+
+// 持久化Map，使用哈希数组映射前缀树（HAMT）实现
+// 键的64位哈希值按5位一组从低到高决定每一层的分支，每个结点用位图记录哪些分支存在，只保存存在的分支；
+// 哈希值完全相同的键保存在同一个冲突结点中。
+// 修改只复制从根到目标位置的路径，其余结点在新旧版本之间共享。
+// 只剩一个键值对的子树总会被收缩到父结点中，因此相同的键集合总是得到相同形状的树，
+// 比较两个版本时可以逐结点比较，遇到共享的子树直接跳过。
+
+const (
+	hamtBits  = 5
+	hamtWidth = 1 << hamtBits
+	hamtMask  = hamtWidth - 1
+)
+
+// DiffType 表示键在两个版本之间的变化
+type DiffType int
+
+const (
+	// DiffAdded 键只在新版本中存在
+	DiffAdded DiffType = iota
+
+	// DiffRemoved 键只在旧版本中存在
+	DiffRemoved
+
+	// DiffChanged 键在两个版本中都存在，但值不同
+	DiffChanged
+)
+
+func (t DiffType) String() string {
+	switch t {
+	case DiffAdded:
+		return "added"
+	case DiffRemoved:
+		return "removed"
+	case DiffChanged:
+		return "changed"
+	}
+
+	return fmt.Sprintf("DiffType(%d)", int(t))
+}
+
+type hamtEntry struct {
+	hash  uint64
+	key   interface{}
+	value interface{}
+}
+
+// hamtCollision 哈希值完全相同的多个键值对
+type hamtCollision struct {
+	hash    uint64
+	entries []*hamtEntry
+}
+
+// hamtNode 内部结点，children中的元素为*hamtEntry、*hamtCollision或*hamtNode
+type hamtNode struct {
+	bitmap   uint32
+	children []interface{}
+}
+
+var emptyHAMTNode = &hamtNode{}
+
+// PersistentMap 不可变的Map，修改操作返回新版本，旧版本保持不变，可以被多个go程同时读取
+// 键必须是可比较的类型
+type PersistentMap struct {
+	root *hamtNode
+	size int
+}
+
+func NewPersistentMap() *PersistentMap {
+	return &PersistentMap{root: emptyHAMTNode, size: 0}
+}
+
+// hashOf 返回键的哈希值，无法计算哈希值的键都使用0，它们会被放在同一个冲突结点中
+func hashOf(key interface{}) uint64 {
+	h, err := Container.Hash64(key)
+	if err != nil {
+		return 0
+	}
+
+	return h
+}
+
+// valueEqual 判断两个值是否相等，不可比较的类型使用reflect.DeepEqual
+// 可比较的结构体或数组中的接口字段可能保存切片等不可比较的值，此时==会panic，同样退回到reflect.DeepEqual
+func valueEqual(a, b interface{}) (equal bool) {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	t := reflect.TypeOf(a)
+	if t != reflect.TypeOf(b) {
+		return false
+	}
+	if t.Comparable() {
+		defer func() {
+			if recover() != nil {
+				equal = reflect.DeepEqual(a, b)
+			}
+		}()
+		return a == b
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func bitpos(hash uint64, shift uint) uint32 {
+	return 1 << ((hash >> shift) & hamtMask)
+}
+
+func (n *hamtNode) index(bit uint32) int {
+	return bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+// leafHash 返回叶子结点（键值对或冲突结点）的哈希值
+func leafHash(leaf interface{}) uint64 {
+	if e, ok := leaf.(*hamtEntry); ok {
+		return e.hash
+	}
+
+	return leaf.(*hamtCollision).hash
+}
+
+// isLeaf 判断子结点是否是键值对或冲突结点
+func isLeaf(child interface{}) bool {
+	_, ok := child.(*hamtNode)
+	return !ok
+}
+
+// newPair 在第shift层创建包含两个哈希值不同的叶子结点的子树
+func newPair(shift uint, a, b interface{}) *hamtNode {
+	ba, bb := bitpos(leafHash(a), shift), bitpos(leafHash(b), shift)
+	if ba == bb {
+		return &hamtNode{bitmap: ba, children: []interface{}{newPair(shift+hamtBits, a, b)}}
+	}
+	if ba > bb {
+		a, b = b, a
+	}
+
+	return &hamtNode{bitmap: ba | bb, children: []interface{}{a, b}}
+}
+
+func (n *hamtNode) get(hash uint64, key interface{}) (interface{}, bool) {
+	for shift := uint(0); ; shift += hamtBits {
+		bit := bitpos(hash, shift)
+		if n.bitmap&bit == 0 {
+			return nil, false
+		}
+
+		switch c := n.children[n.index(bit)].(type) {
+		case *hamtEntry:
+			if c.key == key {
+				return c.value, true
+			}
+			return nil, false
+		case *hamtCollision:
+			return c.get(key)
+		case *hamtNode:
+			n = c
+		}
+	}
+}
+
+// with 返回加入e之后的结点，键已经存在且值相等时返回n本身，added表示是否新增了键
+func (n *hamtNode) with(shift uint, e *hamtEntry) (ret *hamtNode, added bool) {
+	bit := bitpos(e.hash, shift)
+	i := n.index(bit)
+
+	if n.bitmap&bit == 0 {
+		children := make([]interface{}, len(n.children)+1)
+		copy(children, n.children[:i])
+		children[i] = e
+		copy(children[i+1:], n.children[i:])
+
+		return &hamtNode{bitmap: n.bitmap | bit, children: children}, true
+	}
+
+	var child interface{}
+	switch c := n.children[i].(type) {
+	case *hamtEntry:
+		if c.key == e.key {
+			if valueEqual(c.value, e.value) {
+				return n, false
+			}
+			child = e
+		} else if c.hash == e.hash {
+			child = &hamtCollision{hash: e.hash, entries: []*hamtEntry{c, e}}
+			added = true
+		} else {
+			child = newPair(shift+hamtBits, c, e)
+			added = true
+		}
+	case *hamtCollision:
+		if c.hash == e.hash {
+			var nc *hamtCollision
+			nc, added = c.with(e)
+			if nc == c {
+				return n, false
+			}
+			child = nc
+		} else {
+			child = newPair(shift+hamtBits, c, e)
+			added = true
+		}
+	case *hamtNode:
+		var nc *hamtNode
+		nc, added = c.with(shift+hamtBits, e)
+		if nc == c {
+			return n, false
+		}
+		child = nc
+	}
+
+	return n.replace(i, child), added
+}
+
+// replace 返回把第i个子结点替换为child之后的结点
+func (n *hamtNode) replace(i int, child interface{}) *hamtNode {
+	children := make([]interface{}, len(n.children))
+	copy(children, n.children)
+	children[i] = child
+
+	return &hamtNode{bitmap: n.bitmap, children: children}
+}
+
+// without 返回删除key之后这个位置上的结点
+// 子树为空时返回nil，只剩一个叶子结点时返回该叶子结点，由父结点收缩
+func (n *hamtNode) without(shift uint, hash uint64, key interface{}) (ret interface{}, removed bool) {
+	bit := bitpos(hash, shift)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+	i := n.index(bit)
+
+	var child interface{}
+	switch c := n.children[i].(type) {
+	case *hamtEntry:
+		if c.key != key {
+			return n, false
+		}
+	case *hamtCollision:
+		if c.hash != hash {
+			return n, false
+		}
+		child, removed = c.without(key)
+		if !removed {
+			return n, false
+		}
+	case *hamtNode:
+		child, removed = c.without(shift+hamtBits, hash, key)
+		if !removed {
+			return n, false
+		}
+	}
+
+	if child != nil {
+		if len(n.children) == 1 && isLeaf(child) {
+			return child, true
+		}
+		return n.replace(i, child), true
+	}
+
+	switch len(n.children) {
+	case 1:
+		return nil, true
+	case 2:
+		if other := n.children[1-i]; isLeaf(other) {
+			return other, true
+		}
+	}
+
+	children := make([]interface{}, len(n.children)-1)
+	copy(children, n.children[:i])
+	copy(children[i:], n.children[i+1:])
+
+	return &hamtNode{bitmap: n.bitmap &^ bit, children: children}, true
+}
+
+func (n *hamtNode) forEach(f func(e *hamtEntry) bool) bool {
+	for _, child := range n.children {
+		if !forEachLeaf(child, f) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func forEachLeaf(child interface{}, f func(e *hamtEntry) bool) bool {
+	switch c := child.(type) {
+	case *hamtEntry:
+		return f(c)
+	case *hamtCollision:
+		for _, e := range c.entries {
+			if !f(e) {
+				return false
+			}
+		}
+	case *hamtNode:
+		return c.forEach(f)
+	}
+
+	return true
+}
+
+func (c *hamtCollision) get(key interface{}) (interface{}, bool) {
+	for _, e := range c.entries {
+		if e.key == key {
+			return e.value, true
+		}
+	}
+
+	return nil, false
+}
+
+func (c *hamtCollision) with(e *hamtEntry) (*hamtCollision, bool) {
+	for i, old := range c.entries {
+		if old.key == e.key {
+			if valueEqual(old.value, e.value) {
+				return c, false
+			}
+
+			entries := make([]*hamtEntry, len(c.entries))
+			copy(entries, c.entries)
+			entries[i] = e
+			return &hamtCollision{hash: c.hash, entries: entries}, false
+		}
+	}
+
+	entries := make([]*hamtEntry, len(c.entries), len(c.entries)+1)
+	copy(entries, c.entries)
+
+	return &hamtCollision{hash: c.hash, entries: append(entries, e)}, true
+}
+
+// without 删除key，只剩一个键值对时返回该键值对
+func (c *hamtCollision) without(key interface{}) (interface{}, bool) {
+	for i, e := range c.entries {
+		if e.key != key {
+			continue
+		}
+
+		if len(c.entries) == 2 {
+			return c.entries[1-i], true
+		}
+
+		entries := make([]*hamtEntry, 0, len(c.entries)-1)
+		entries = append(entries, c.entries[:i]...)
+		entries = append(entries, c.entries[i+1:]...)
+		return &hamtCollision{hash: c.hash, entries: entries}, true
+	}
+
+	return c, false
+}
+
+func (m *PersistentMap) Load(key interface{}) (interface{}, bool) {
+	return m.root.get(hashOf(key), key)
+}
+
+// Store 返回设置key对应的值之后的新版本，值没有变化时返回m本身
+func (m *PersistentMap) Store(key, value interface{}) *PersistentMap {
+	return m.store(hashOf(key), key, value)
+}
+
+// store 使用哈希值hash设置key对应的值
+func (m *PersistentMap) store(hash uint64, key, value interface{}) *PersistentMap {
+	root, added := m.root.with(0, &hamtEntry{hash: hash, key: key, value: value})
+	if root == m.root {
+		return m
+	}
+
+	size := m.size
+	if added {
+		size++
+	}
+
+	return &PersistentMap{root: root, size: size}
+}
+
+// Delete 返回删除key之后的新版本，key不存在时返回m本身
+func (m *PersistentMap) Delete(key interface{}) *PersistentMap {
+	return m.delete(hashOf(key), key)
+}
+
+// delete 删除哈希值为hash的key
+func (m *PersistentMap) delete(hash uint64, key interface{}) *PersistentMap {
+	ret, removed := m.root.without(0, hash, key)
+	if !removed {
+		return m
+	}
+
+	var root *hamtNode
+	switch r := ret.(type) {
+	case nil:
+		root = emptyHAMTNode
+	case *hamtNode:
+		root = r
+	default:
+		// 根结点不收缩
+		root = &hamtNode{bitmap: bitpos(leafHash(r), 0), children: []interface{}{r}}
+	}
+
+	return &PersistentMap{root: root, size: m.size - 1}
+}
+
+// Range 遍历所有的键值对，f返回false时停止遍历，遍历顺序不固定
+func (m *PersistentMap) Range(f func(key, value interface{}) bool) {
+	m.root.forEach(func(e *hamtEntry) bool {
+		return f(e.key, e.value)
+	})
+}
+
+func (m *PersistentMap) Len() int {
+	return m.size
+}
+
+// Equal 判断两个版本的键值对是否完全相同，值使用==比较，不可比较的值使用reflect.DeepEqual
+// 共享的子树不需要比较，比较有共同祖先的两个版本时只需要访问修改过的路径
+func (m *PersistentMap) Equal(other *PersistentMap) bool {
+	if m.size != other.size {
+		return false
+	}
+
+	equal := true
+	diff(m.root, other.root, 0, func(key interface{}, t DiffType, oldValue, newValue interface{}) bool {
+		equal = false
+		return false
+	})
+
+	return equal
+}
+
+// Diff 把m作为旧版本，other作为新版本，对每个发生变化的键调用f，f返回false时停止
+// 新增的键oldValue为nil，删除的键newValue为nil；共享的子树会被直接跳过
+func (m *PersistentMap) Diff(other *PersistentMap, f func(key interface{}, t DiffType, oldValue, newValue interface{}) bool) {
+	diff(m.root, other.root, 0, f)
+}
+
+// diff 比较同一位置上的两个结点，f返回false时返回false
+func diff(a, b interface{}, shift uint, f func(key interface{}, t DiffType, oldValue, newValue interface{}) bool) bool {
+	if a == b {
+		return true
+	}
+
+	if a == nil {
+		return forEachLeaf(b, func(e *hamtEntry) bool {
+			return f(e.key, DiffAdded, nil, e.value)
+		})
+	}
+	if b == nil {
+		return forEachLeaf(a, func(e *hamtEntry) bool {
+			return f(e.key, DiffRemoved, e.value, nil)
+		})
+	}
+
+	na, aok := a.(*hamtNode)
+	nb, bok := b.(*hamtNode)
+	if aok && bok {
+		for bit := uint32(1); bit != 0; bit <<= 1 {
+			var ca, cb interface{}
+			if na.bitmap&bit != 0 {
+				ca = na.children[na.index(bit)]
+			}
+			if nb.bitmap&bit != 0 {
+				cb = nb.children[nb.index(bit)]
+			}
+			if (ca != nil || cb != nil) && !diff(ca, cb, shift+hamtBits, f) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if ea, ok := a.(*hamtEntry); ok {
+		if eb, ok := b.(*hamtEntry); ok && ea.key == eb.key {
+			if valueEqual(ea.value, eb.value) {
+				return true
+			}
+			return f(ea.key, DiffChanged, ea.value, eb.value)
+		}
+	}
+
+	// 结点类型不同，逐个查找
+	return diffLeaves(a, b, f)
+}
+
+// diffLeaves 逐个比较两棵子树中的键值对
+func diffLeaves(a, b interface{}, f func(key interface{}, t DiffType, oldValue, newValue interface{}) bool) bool {
+	get := func(child interface{}, key interface{}) (interface{}, bool) {
+		var value interface{}
+		found := false
+		forEachLeaf(child, func(e *hamtEntry) bool {
+			if e.key == key {
+				value, found = e.value, true
+				return false
+			}
+			return true
+		})
+		return value, found
+	}
+
+	ok := forEachLeaf(a, func(e *hamtEntry) bool {
+		value, found := get(b, e.key)
+		if !found {
+			return f(e.key, DiffRemoved, e.value, nil)
+		}
+		if !valueEqual(e.value, value) {
+			return f(e.key, DiffChanged, e.value, value)
+		}
+		return true
+	})
+	if !ok {
+		return false
+	}
+
+	return forEachLeaf(b, func(e *hamtEntry) bool {
+		if _, found := get(a, e.key); !found {
+			return f(e.key, DiffAdded, nil, e.value)
+		}
+		return true
+	})
+}
+
+// ToMap 将所有的键值对复制到内置map中返回
+func (m *PersistentMap) ToMap() map[interface{}]interface{} {
+	ret := make(map[interface{}]interface{}, m.size)
+	m.Range(func(key, value interface{}) bool {
+		ret[key] = value
+		return true
+	})
+
+	return ret
+}
+
+func (m *PersistentMap) String() string {
+	items := make([]string, 0, m.size)
+	m.Range(func(key, value interface{}) bool {
+		items = append(items, fmt.Sprintf("%v: %v", key, value))
+		return true
+	})
+
+	return fmt.Sprintf("PersistentMap{%s}", strings.Join(items, ", "))
+}
@@ -0,0 +1,201 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Map
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// checkShape 检查子树满足收缩规则：非根结点不会只有一个叶子结点，冲突结点至少有两个键，返回冲突结点的数量
+func checkShape(t *testing.T, n *hamtNode, root bool) int {
+	t.Helper()
+	if !root && len(n.children) == 1 && isLeaf(n.children[0]) {
+		t.Fatal("inner node holds a single leaf and was not collapsed")
+	}
+
+	collisions := 0
+	for _, child := range n.children {
+		switch c := child.(type) {
+		case *hamtCollision:
+			if len(c.entries) < 2 {
+				t.Fatalf("collision node holds %d entries", len(c.entries))
+			}
+			collisions++
+		case *hamtNode:
+			collisions += checkShape(t, c, false)
+		}
+	}
+
+	return collisions
+}
+
+// 哈希值完全相同的键放在同一个冲突结点中，删除到只剩一个键时冲突结点收缩
+func TestPersistentMapCollisions(t *testing.T) {
+	const hash = 0x0123456789abcdef
+	// near与hash只有最高的几位不同，需要一直分支到最深的一层
+	const near = hash ^ (1 << 62)
+
+	m := NewPersistentMap()
+	m = m.store(hash, "a", 1)
+	m = m.store(hash, "b", 2)
+	m = m.store(near, "n", 0)
+	m = m.store(hash, "c", 3)
+	base := m
+	if m.Len() != 4 || checkShape(t, m.root, true) != 1 {
+		t.Fatalf("Len() = %d, want 4 keys with one collision node", m.Len())
+	}
+	for key, want := range map[string]int{"a": 1, "b": 2, "c": 3, "n": 0} {
+		h := uint64(hash)
+		if key == "n" {
+			h = near
+		}
+		if v, ok := m.root.get(h, key); !ok || v != want {
+			t.Fatalf("get(%q) = %v, %v, want %d", key, v, ok, want)
+		}
+	}
+	if _, ok := m.root.get(hash, "n"); ok {
+		t.Fatal("key found under a colliding hash it was not stored with")
+	}
+
+	// 冲突结点中更新值不改变数量，值相同时返回原版本
+	if m.store(hash, "b", 2) != m {
+		t.Fatal("storing an equal value did not return the same version")
+	}
+	m = m.store(hash, "b", 20)
+	if m.Len() != 4 {
+		t.Fatalf("Len() after update = %d, want 4", m.Len())
+	}
+
+	m = m.delete(hash, "a")
+	if m.delete(hash, "a") != m {
+		t.Fatal("deleting a missing key did not return the same version")
+	}
+	m = m.delete(hash, "c")
+	if m.Len() != 2 || checkShape(t, m.root, true) != 0 {
+		t.Fatalf("Len() = %d, want 2 keys and no collision node left", m.Len())
+	}
+	if v, ok := m.root.get(hash, "b"); !ok || v != 20 {
+		t.Fatalf("get(b) = %v, %v, want 20", v, ok)
+	}
+
+	m = m.delete(near, "n")
+	m = m.delete(hash, "b")
+	if m.Len() != 0 || len(m.root.children) != 0 {
+		t.Fatalf("Len() = %d, want an empty root", m.Len())
+	}
+
+	// 旧版本不受影响
+	if v, ok := base.root.get(hash, "b"); base.Len() != 4 || !ok || v != 2 {
+		t.Fatalf("base version changed: Len() = %d, b = %v", base.Len(), v)
+	}
+}
+
+type diffRecord struct {
+	key      interface{}
+	t        DiffType
+	old, new interface{}
+}
+
+func collectDiff(a, b *PersistentMap) []diffRecord {
+	var ret []diffRecord
+	a.Diff(b, func(key interface{}, t DiffType, oldValue, newValue interface{}) bool {
+		ret = append(ret, diffRecord{key, t, oldValue, newValue})
+		return true
+	})
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].key.(int) < ret[j].key.(int)
+	})
+
+	return ret
+}
+
+// Diff报告新增、删除和修改的键，共享的子树与相同的值不报告
+func TestPersistentMapDiff(t *testing.T) {
+	base := NewPersistentMap()
+	for i := 0; i < 1000; i++ {
+		base = base.Store(i, i)
+	}
+
+	next := base.Store(1000, "new").Delete(3).Store(500, "changed").Store(7, 7)
+	want := []diffRecord{
+		{3, DiffRemoved, 3, nil},
+		{500, DiffChanged, 500, "changed"},
+		{1000, DiffAdded, nil, "new"},
+	}
+	if got := collectDiff(base, next); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Diff() = %v, want %v", got, want)
+	}
+	if got := collectDiff(base, base); len(got) != 0 {
+		t.Fatalf("Diff() of a version with itself = %v", got)
+	}
+
+	// f返回false时停止
+	calls := 0
+	base.Diff(NewPersistentMap(), func(interface{}, DiffType, interface{}, interface{}) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Fatalf("Diff() called f %d times after it returned false", calls)
+	}
+
+	// 一边是冲突结点、另一边是普通键值对时逐个比较
+	a := NewPersistentMap().store(42, 1, "x").store(42, 2, "y")
+	b := NewPersistentMap().store(42, 2, "z")
+	want = []diffRecord{
+		{1, DiffRemoved, "x", nil},
+		{2, DiffChanged, "y", "z"},
+	}
+	if got := collectDiff(a, b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Diff() between collision and entry = %v, want %v", got, want)
+	}
+}
+
+// holder 可比较的结构体，接口字段可能保存不可比较的值
+type holder struct {
+	V interface{}
+}
+
+// 插入顺序不同的两个版本相等；不可比较的值使用reflect.DeepEqual比较，不会panic
+func TestPersistentMapEqual(t *testing.T) {
+	a, b := NewPersistentMap(), NewPersistentMap()
+	for i := 0; i < 200; i++ {
+		a = a.Store(i, []int{i})
+		b = b.Store(199-i, []int{199 - i})
+	}
+	if !a.Equal(b) || !b.Equal(a) {
+		t.Fatal("versions with the same pairs in different insertion order are not equal")
+	}
+	if a.Equal(b.Store(5, []int{6})) || a.Equal(b.Delete(5)) {
+		t.Fatal("versions with different pairs are equal")
+	}
+
+	m := NewPersistentMap().Store("k", holder{V: []int{1}})
+	if m.Store("k", holder{V: []int{1}}) != m {
+		t.Fatal("storing a deeply equal struct value created a new version")
+	}
+	changed := m.Store("k", holder{V: map[string]int{"a": 1}})
+	if changed == m || m.Equal(changed) {
+		t.Fatal("storing a different struct value was ignored")
+	}
+	if !changed.Equal(NewPersistentMap().Store("k", holder{V: map[string]int{"a": 1}})) {
+		t.Fatal("independently built maps with equal struct values are not equal")
+	}
+}
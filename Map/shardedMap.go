@@ -0,0 +1,226 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Map
+
+import (
+	"GTL/Container"
+	"GTL/GSync"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+type mapShard struct {
+	m     sync.RWMutex
+	items map[interface{}]interface{}
+}
+
+// shardedMap 分片的并发Map
+// 键根据哈希值分配到不同的分片，每个分片有自己的读写锁，不同分片上的操作互不阻塞；
+// 涉及多个分片的批量操作按照固定顺序对分片加锁，避免死锁
+type shardedMap struct {
+	shards []*mapShard
+}
+
+// NewShardedMap 返回一个有shardCount个分片的Map
+func NewShardedMap(shardCount int) (*shardedMap, error) {
+	if shardCount <= 0 {
+		return nil, errors.New("Shard count must be positive.")
+	}
+
+	m := &shardedMap{shards: make([]*mapShard, shardCount)}
+	for i := range m.shards {
+		m.shards[i] = &mapShard{items: make(map[interface{}]interface{})}
+	}
+
+	return m, nil
+}
+
+// shardIndex 返回键所在分片的下标，无法计算哈希值的键放在第0个分片
+func (m *shardedMap) shardIndex(key interface{}) int {
+	h, err := Container.Hash64(key)
+	if err != nil {
+		return 0
+	}
+
+	return int(h % uint64(len(m.shards)))
+}
+
+func (m *shardedMap) shard(key interface{}) *mapShard {
+	return m.shards[m.shardIndex(key)]
+}
+
+func (m *shardedMap) Load(key interface{}) (interface{}, bool) {
+	sh := m.shard(key)
+	sh.m.RLock()
+	v, ok := sh.items[key]
+	sh.m.RUnlock()
+
+	return v, ok
+}
+
+func (m *shardedMap) Store(key, value interface{}) {
+	sh := m.shard(key)
+	sh.m.Lock()
+	sh.items[key] = value
+	sh.m.Unlock()
+}
+
+func (m *shardedMap) LoadOrStore(key, value interface{}) (interface{}, bool) {
+	sh := m.shard(key)
+	sh.m.Lock()
+	defer sh.m.Unlock()
+
+	if v, ok := sh.items[key]; ok {
+		return v, true
+	}
+	sh.items[key] = value
+
+	return value, false
+}
+
+func (m *shardedMap) LoadAndDelete(key interface{}) (interface{}, bool) {
+	sh := m.shard(key)
+	sh.m.Lock()
+	defer sh.m.Unlock()
+
+	v, ok := sh.items[key]
+	delete(sh.items, key)
+
+	return v, ok
+}
+
+func (m *shardedMap) Delete(key interface{}) {
+	sh := m.shard(key)
+	sh.m.Lock()
+	delete(sh.items, key)
+	sh.m.Unlock()
+}
+
+// Compute 在持有分片锁的情况下调用fn，因此fn中不能再访问该Map
+func (m *shardedMap) Compute(
+	key interface{},
+	fn func(value interface{}, loaded bool) (interface{}, bool),
+) (interface{}, bool) {
+	sh := m.shard(key)
+	sh.m.Lock()
+	defer sh.m.Unlock()
+
+	old, ok := sh.items[key]
+	v, keep := fn(old, ok)
+	if keep {
+		sh.items[key] = v
+	} else {
+		delete(sh.items, key)
+	}
+
+	return v, keep
+}
+
+// Range 依次遍历每个分片，遍历某个分片时持有该分片的读锁，因此f中不能修改该Map
+func (m *shardedMap) Range(f func(key, value interface{}) bool) {
+	for _, sh := range m.shards {
+		sh.m.RLock()
+		for k, v := range sh.items {
+			if !f(k, v) {
+				sh.m.RUnlock()
+				return
+			}
+		}
+		sh.m.RUnlock()
+	}
+}
+
+func (m *shardedMap) Len() int {
+	n := 0
+	for _, sh := range m.shards {
+		sh.m.RLock()
+		n += len(sh.items)
+		sh.m.RUnlock()
+	}
+
+	return n
+}
+
+// StoreAll 批量设置键值对，所有相关分片同时加锁，其他go程不会观察到只设置了一部分的状态
+func (m *shardedMap) StoreAll(items map[interface{}]interface{}) {
+	ml := GSync.NewMultiLock()
+	for k := range items {
+		ml.Add(&m.shard(k).m, true)
+	}
+
+	ml.Lock()
+	for k, v := range items {
+		m.shard(k).items[k] = v
+	}
+	ml.Unlock()
+}
+
+// Merge 将other中的所有键值对合并到m中，两者都存在的键由resolve决定最终的值，resolve为nil时使用other中的值
+// 合并期间m与other的所有分片都被锁住
+func (m *shardedMap) Merge(other *shardedMap, resolve func(key, old, new interface{}) interface{}) {
+	// 合并自身时没有需要修改的内容
+	if m == other {
+		return
+	}
+
+	ml := GSync.NewMultiLock()
+	for _, sh := range m.shards {
+		ml.Add(&sh.m, true)
+	}
+	for _, sh := range other.shards {
+		ml.Add(&sh.m, false)
+	}
+
+	ml.Lock()
+	defer ml.Unlock()
+
+	for _, sh := range other.shards {
+		for k, v := range sh.items {
+			dst := m.shard(k)
+			if old, ok := dst.items[k]; ok && resolve != nil {
+				v = resolve(k, old, v)
+			}
+			dst.items[k] = v
+		}
+	}
+}
+
+func (m *shardedMap) Clear() {
+	ml := GSync.NewMultiLock()
+	for _, sh := range m.shards {
+		ml.Add(&sh.m, true)
+	}
+
+	ml.Lock()
+	for _, sh := range m.shards {
+		sh.items = make(map[interface{}]interface{})
+	}
+	ml.Unlock()
+}
+
+func (m *shardedMap) String() string {
+	items := make([]string, 0, m.Len())
+	m.Range(func(key, value interface{}) bool {
+		items = append(items, fmt.Sprintf("%v: %v", key, value))
+		return true
+	})
+
+	return fmt.Sprintf("ShardedMap{%s}", strings.Join(items, ", "))
+}
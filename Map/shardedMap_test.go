@@ -0,0 +1,188 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Map
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitGroupTimeout 等待wg结束，超时则认为发生了死锁
+func waitGroupTimeout(t *testing.T, wg *sync.WaitGroup) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("operations deadlocked")
+	}
+}
+
+// 键分散到所有分片，每个键只在自己的分片中
+func TestShardedMapDistribution(t *testing.T) {
+	m, err := NewShardedMap(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewShardedMap(0); err == nil {
+		t.Fatal("NewShardedMap(0) succeeded")
+	}
+
+	for i := 0; i < 1000; i++ {
+		m.Store(i, i)
+	}
+	for i, sh := range m.shards {
+		if len(sh.items) == 0 {
+			t.Fatalf("shard %d is empty after storing 1000 keys", i)
+		}
+		for k := range sh.items {
+			if m.shardIndex(k) != i {
+				t.Fatalf("key %v stored in shard %d, belongs to %d", k, i, m.shardIndex(k))
+			}
+		}
+	}
+	if m.Len() != 1000 {
+		t.Fatalf("Len() = %d, want 1000", m.Len())
+	}
+}
+
+// 并发的LoadOrStore只有一个成功写入，所有调用者得到同一个值；Compute的读改写是原子的
+func TestShardedMapLoadOrStoreAndCompute(t *testing.T) {
+	const goroutines = 8
+	m, err := NewShardedMap(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	stored := make(chan int, goroutines)
+	values := make(chan interface{}, goroutines)
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		i := i
+		go func() {
+			defer wg.Done()
+			v, loaded := m.LoadOrStore("once", i)
+			if !loaded {
+				stored <- i
+			}
+			values <- v
+			for j := 0; j < 100; j++ {
+				m.Compute("counter", func(value interface{}, loaded bool) (interface{}, bool) {
+					if !loaded {
+						return 1, true
+					}
+					return value.(int) + 1, true
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	close(stored)
+	close(values)
+
+	if len(stored) != 1 {
+		t.Fatalf("%d LoadOrStore calls stored a value, want 1", len(stored))
+	}
+	winner := <-stored
+	for v := range values {
+		if v != winner {
+			t.Fatalf("LoadOrStore returned %v, want the stored %d", v, winner)
+		}
+	}
+	if v, _ := m.Load("counter"); v != goroutines*100 {
+		t.Fatalf("counter = %v, want %d", v, goroutines*100)
+	}
+
+	// keep为false时删除
+	if _, keep := m.Compute("counter", func(interface{}, bool) (interface{}, bool) { return nil, false }); keep {
+		t.Fatal("Compute reported keep for a deleted key")
+	}
+	if _, ok := m.Load("counter"); ok {
+		t.Fatal("Compute with keep false did not delete the key")
+	}
+	if v, ok := m.LoadAndDelete("once"); !ok || v != winner {
+		t.Fatalf("LoadAndDelete() = %v, %v, want %d", v, ok, winner)
+	}
+	if m.Len() != 0 {
+		t.Fatalf("Len() = %d, want 0", m.Len())
+	}
+}
+
+// Merge由resolve决定冲突键的值；两个Map互相合并时按照固定顺序加锁，不会死锁
+func TestShardedMapMerge(t *testing.T) {
+	a, _ := NewShardedMap(4)
+	b, _ := NewShardedMap(7)
+	a.StoreAll(map[interface{}]interface{}{1: 1, 2: 2})
+	b.StoreAll(map[interface{}]interface{}{2: 20, 3: 30})
+
+	sum := func(key, old, new interface{}) interface{} {
+		return old.(int) + new.(int)
+	}
+	a.Merge(b, sum)
+	for k, want := range map[int]int{1: 1, 2: 22, 3: 30} {
+		if v, _ := a.Load(k); v != want {
+			t.Fatalf("a[%d] = %v, want %d", k, v, want)
+		}
+	}
+	a.Merge(a, sum)
+	if v, _ := a.Load(2); v != 22 {
+		t.Fatalf("merging a map into itself changed a[2] to %v", v)
+	}
+	b.Merge(a, nil)
+	if v, _ := b.Load(2); v != 22 || b.Len() != 3 {
+		t.Fatalf("b[2] = %v, Len() = %d, want 22 and 3 keys", v, b.Len())
+	}
+
+	keep := func(key, old, new interface{}) interface{} {
+		return old
+	}
+	var wg sync.WaitGroup
+	wg.Add(4)
+	for i := 0; i < 2; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				a.Merge(b, keep)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				b.Merge(a, keep)
+				b.StoreAll(map[interface{}]interface{}{j: j, j + 1000: j})
+			}
+		}()
+	}
+	waitGroupTimeout(t, &wg)
+
+	a.Merge(b, keep)
+	b.Merge(a, keep)
+	if a.Len() != b.Len() {
+		t.Fatalf("after merging both ways Len() = %d and %d", a.Len(), b.Len())
+	}
+	a.Clear()
+	if a.Len() != 0 || b.Len() == 0 {
+		t.Fatal("Clear() did not empty only its own map")
+	}
+}
@@ -0,0 +1,455 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Pool
+
+import (
+	"GTL/PriorityQueue"
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// TaskFunc 提交给Pool执行的任务，ctx在提交时的ctx被取消或者Pool被强制关闭时取消
+type TaskFunc func(ctx context.Context) (interface{}, error)
+
+// PanicError 任务发生panic时Future返回的错误
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v\n%s", e.Value, e.Stack)
+}
+
+// Future 任务的结果，任务结束后Done返回的通道被关闭
+type Future struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+}
+
+func (f *Future) complete(val interface{}, err error) {
+	f.val, f.err = val, err
+	close(f.done)
+}
+
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Get 等待任务结束并返回它的结果，ctx被取消时返回ctx.Err()，任务不会因此取消
+func (f *Future) Get(ctx context.Context) (interface{}, error) {
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+		select {
+		case <-f.done:
+			return f.val, f.err
+		default:
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Latency 一组时间的统计
+type Latency struct {
+	Count uint64
+	Total time.Duration
+	Max   time.Duration
+}
+
+func (l *Latency) observe(d time.Duration) {
+	l.Count++
+	l.Total += d
+	if d > l.Max {
+		l.Max = d
+	}
+}
+
+// Mean 返回平均值
+func (l Latency) Mean() time.Duration {
+	if l.Count == 0 {
+		return 0
+	}
+
+	return l.Total / time.Duration(l.Count)
+}
+
+// Metrics 某一时刻Pool的统计信息
+type Metrics struct {
+	Workers       int
+	QueueDepth    int
+	MaxQueueDepth int
+	Running       int
+
+	Submitted uint64
+	Rejected  uint64
+
+	// Completed 成功结束的任务，Failed 返回错误或者panic的任务，Panicked是其中panic的任务
+	Completed uint64
+	Failed    uint64
+	Panicked  uint64
+
+	// Canceled 开始执行之前ctx被取消或者Pool被强制关闭的任务，排队时ctx被取消的任务会立即移出队列
+	Canceled uint64
+
+	// QueueLatency 任务从提交到开始执行的时间，RunLatency 任务执行的时间
+	QueueLatency Latency
+	RunLatency   Latency
+}
+
+type task struct {
+	ctx       context.Context
+	fn        TaskFunc
+	priority  int
+	seq       uint64
+	submitted time.Time
+	future    *Future
+	cancel    context.CancelFunc
+
+	// index 任务在队列中的下标，不在队列中时为-1
+	index int
+
+	// left 任务离开队列时关闭，为nil表示ctx不会被取消，没有监视它的go程
+	left chan struct{}
+}
+
+// dequeued 任务离开队列时调用，调用时必须持有锁
+func (t *task) dequeued() {
+	if t.left != nil {
+		close(t.left)
+	}
+}
+
+// Pool 固定数量的go程按优先级执行提交的任务
+// 任务按照priority从大到小执行，priority相同时按提交顺序执行；排队的任务数量达到上限时提交会阻塞
+type Pool struct {
+	m       sync.Mutex
+	ready   *sync.Cond
+	pq      PriorityQueue.PriorityQueue
+	maxSize int
+	seq     uint64
+
+	// space 队列中有空位时关闭并替换，唤醒等待提交的调用者
+	space        chan struct{}
+	spaceWaiters int
+
+	running map[*task]struct{}
+	closed  bool
+	metrics Metrics
+
+	wg   sync.WaitGroup
+	done chan struct{}
+}
+
+// NewPool 启动workers个go程，maxSize为排队任务数量的上限，为-1时不限制
+func NewPool(workers, maxSize int) (*Pool, error) {
+	if workers <= 0 {
+		return nil, errors.New("Workers must be positive.")
+	}
+	if maxSize != -1 && maxSize <= 0 {
+		return nil, errors.New("MaxSize must be positive or -1.")
+	}
+
+	pq, err := PriorityQueue.NewUnsafePriorityQueue(maxSize)
+	if err != nil {
+		return nil, err
+	}
+	pq.SetFunc(func(a, b interface{}) bool {
+		x, y := a.(*task), b.(*task)
+		if x.priority == y.priority {
+			return x.seq < y.seq
+		}
+		return x.priority > y.priority
+	})
+	pq.SetIndexFunc(func(value interface{}, index int) {
+		value.(*task).index = index
+	})
+
+	p := &Pool{
+		pq:      pq,
+		maxSize: maxSize,
+		space:   make(chan struct{}),
+		running: make(map[*task]struct{}),
+		done:    make(chan struct{}),
+	}
+	p.ready = sync.NewCond(&p.m)
+	p.metrics.Workers = workers
+
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.worker()
+	}
+	go func() {
+		p.wg.Wait()
+		close(p.done)
+	}()
+
+	return p, nil
+}
+
+// signalSpace 唤醒等待提交的调用者，调用时必须持有锁
+func (p *Pool) signalSpace() {
+	if p.spaceWaiters > 0 || p.closed {
+		close(p.space)
+		p.space = make(chan struct{})
+	}
+}
+
+// Submit 提交一个任务，队列已满时等待直到有空位、ctx被取消或者Pool被关闭
+// 任务排队期间ctx被取消时立即移出队列，Future返回ctx.Err()
+func (p *Pool) Submit(ctx context.Context, priority int, fn TaskFunc) (*Future, error) {
+	return p.submit(ctx, priority, fn, true)
+}
+
+// TrySubmit 提交一个任务，队列已满时直接返回错误
+func (p *Pool) TrySubmit(ctx context.Context, priority int, fn TaskFunc) (*Future, error) {
+	return p.submit(ctx, priority, fn, false)
+}
+
+func (p *Pool) submit(ctx context.Context, priority int, fn TaskFunc, block bool) (*Future, error) {
+	if fn == nil {
+		return nil, errors.New("Task can not be nil.")
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	for {
+		if p.closed {
+			p.metrics.Rejected++
+			return nil, errors.New("Pool is shut down.")
+		}
+		if !p.pq.Fill() {
+			break
+		}
+		if !block {
+			p.metrics.Rejected++
+			return nil, errors.New("This pool is full.")
+		}
+
+		space := p.space
+		p.spaceWaiters++
+		p.m.Unlock()
+		var err error
+		select {
+		case <-space:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		p.m.Lock()
+		p.spaceWaiters--
+		if err != nil {
+			p.metrics.Rejected++
+			return nil, err
+		}
+	}
+
+	p.seq++
+	t := &task{
+		ctx:       ctx,
+		fn:        fn,
+		priority:  priority,
+		seq:       p.seq,
+		submitted: time.Now(),
+		future:    &Future{done: make(chan struct{})},
+		index:     -1,
+	}
+	if err := p.pq.Push(t); err != nil {
+		p.metrics.Rejected++
+		return nil, err
+	}
+	if done := ctx.Done(); done != nil {
+		t.left = make(chan struct{})
+		go p.watch(t, ctx, done)
+	}
+
+	p.metrics.Submitted++
+	if size := p.pq.Size(); size > p.metrics.MaxQueueDepth {
+		p.metrics.MaxQueueDepth = size
+	}
+	p.ready.Signal()
+
+	return t.future, nil
+}
+
+// watch 任务排队期间ctx被取消时把它移出队列并完成它的Future，让出的位置可以被等待提交的调用者使用
+func (p *Pool) watch(t *task, ctx context.Context, done <-chan struct{}) {
+	select {
+	case <-t.left:
+		return
+	case <-done:
+	}
+
+	p.m.Lock()
+	if t.index < 0 {
+		// 工作go程或者Shutdown已经取走了任务
+		p.m.Unlock()
+		return
+	}
+	_, _ = p.pq.Remove(t.index)
+	p.signalSpace()
+	p.metrics.Canceled++
+	p.m.Unlock()
+
+	t.future.complete(nil, ctx.Err())
+}
+
+// next 取出优先级最高的任务，队列为空并且Pool已经关闭时返回false
+func (p *Pool) next() (*task, bool) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	for p.pq.Empty() {
+		if p.closed {
+			return nil, false
+		}
+		p.ready.Wait()
+	}
+
+	v, _ := p.pq.Pop()
+	t := v.(*task)
+	t.dequeued()
+	p.signalSpace()
+	p.metrics.QueueLatency.observe(time.Since(t.submitted))
+
+	var ctx context.Context
+	ctx, t.cancel = context.WithCancel(t.ctx)
+	t.ctx = ctx
+	p.running[t] = struct{}{}
+
+	return t, true
+}
+
+func (p *Pool) worker() {
+	defer p.wg.Done()
+
+	for {
+		t, ok := p.next()
+		if !ok {
+			return
+		}
+		p.run(t)
+	}
+}
+
+// run 执行任务并记录结果，任务中的panic被转换为PanicError
+func (p *Pool) run(t *task) {
+	if err := t.ctx.Err(); err != nil {
+		p.finish(t, nil, err, false, 0)
+		return
+	}
+
+	start := time.Now()
+	var val interface{}
+	var err error
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				val, err = nil, &PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
+		val, err = t.fn(t.ctx)
+	}()
+
+	p.finish(t, val, err, true, time.Since(start))
+}
+
+// finish 完成任务的Future并更新统计，started为false表示任务没有开始执行
+func (p *Pool) finish(t *task, val interface{}, err error, started bool, ran time.Duration) {
+	t.cancel()
+
+	p.m.Lock()
+	delete(p.running, t)
+	switch {
+	case !started:
+		p.metrics.Canceled++
+	case err == nil:
+		p.metrics.Completed++
+	default:
+		p.metrics.Failed++
+		if _, ok := err.(*PanicError); ok {
+			p.metrics.Panicked++
+		}
+	}
+	if started {
+		p.metrics.RunLatency.observe(ran)
+	}
+	p.m.Unlock()
+
+	t.future.complete(val, err)
+}
+
+// Shutdown 停止接受新任务，等待已经提交的任务全部执行完
+// ctx被取消时强制关闭：取消正在执行的任务的ctx，排队的任务不再执行，返回ctx.Err()
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.m.Lock()
+	if !p.closed {
+		p.closed = true
+		p.ready.Broadcast()
+		p.signalSpace()
+	}
+	p.m.Unlock()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	p.m.Lock()
+	var queued []*task
+	for !p.pq.Empty() {
+		v, _ := p.pq.Pop()
+		v.(*task).dequeued()
+		queued = append(queued, v.(*task))
+	}
+	for t := range p.running {
+		t.cancel()
+	}
+	p.metrics.Canceled += uint64(len(queued))
+	p.m.Unlock()
+
+	for _, t := range queued {
+		t.future.complete(nil, errors.New("Pool is shut down."))
+	}
+
+	return ctx.Err()
+}
+
+// Done 返回所有工作go程退出后关闭的通道
+func (p *Pool) Done() <-chan struct{} {
+	return p.done
+}
+
+// Metrics 返回当前的统计信息
+func (p *Pool) Metrics() Metrics {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	m := p.metrics
+	m.QueueDepth = p.pq.Size()
+	m.Running = len(p.running)
+
+	return m
+}
@@ -0,0 +1,292 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Pool
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitUntil 等待cond成立，超时则测试失败
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		runtime.Gosched()
+	}
+}
+
+// blockWorkers 提交n个阻塞到release被关闭的任务，并等待它们全部开始执行
+func blockWorkers(t *testing.T, p *Pool, n int) chan struct{} {
+	t.Helper()
+	release := make(chan struct{})
+	for i := 0; i < n; i++ {
+		_, err := p.Submit(context.Background(), 0, func(ctx context.Context) (interface{}, error) {
+			<-release
+			return nil, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitUntil(t, func() bool { return p.Metrics().Running == n })
+
+	return release
+}
+
+func getResult(t *testing.T, f *Future) (interface{}, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	v, err := f.Get(ctx)
+	if err == context.DeadlineExceeded {
+		t.Fatal("Future did not complete")
+	}
+
+	return v, err
+}
+
+// 任务按照优先级从大到小执行，优先级相同时按提交顺序执行
+func TestPoolPriorityOrder(t *testing.T) {
+	p, err := NewPool(1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := blockWorkers(t, p, 1)
+
+	var m sync.Mutex
+	var order []string
+	submit := func(name string, priority int) *Future {
+		f, err := p.Submit(context.Background(), priority, func(ctx context.Context) (interface{}, error) {
+			m.Lock()
+			order = append(order, name)
+			m.Unlock()
+			return name, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	submit("low", -1)
+	submit("mid1", 0)
+	submit("high", 5)
+	submit("mid2", 0)
+	last := submit("mid3", 0)
+
+	close(release)
+	if v, err := getResult(t, last); v != "mid3" || err != nil {
+		t.Fatalf("Get() = %v, %v", v, err)
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"high", "mid1", "mid2", "mid3", "low"}
+	if !reflect.DeepEqual(order, want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+}
+
+// 队列满时TrySubmit立即失败，Submit等待到有空位或者ctx被取消
+func TestPoolBackpressure(t *testing.T) {
+	p, err := NewPool(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := blockWorkers(t, p, 1)
+	noop := func(ctx context.Context) (interface{}, error) { return nil, nil }
+
+	if _, err := p.TrySubmit(context.Background(), 0, noop); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.TrySubmit(context.Background(), 0, noop); err == nil {
+		t.Fatal("TrySubmit on a full queue succeeded")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.Submit(ctx, 0, noop); err != context.DeadlineExceeded {
+		t.Fatalf("Submit() on a full queue = %v, want context.DeadlineExceeded", err)
+	}
+
+	submitted := make(chan error, 1)
+	go func() {
+		_, err := p.Submit(context.Background(), 0, noop)
+		submitted <- err
+	}()
+	select {
+	case err := <-submitted:
+		t.Fatalf("Submit() on a full queue returned %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	if err := <-submitted; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if m := p.Metrics(); m.Rejected != 2 || m.Submitted != 3 || m.MaxQueueDepth != 1 {
+		t.Fatalf("Metrics() = %+v, want 2 rejected, 3 submitted, max depth 1", m)
+	}
+}
+
+// 排队期间ctx被取消的任务立即移出队列，让出的位置可以再次提交
+func TestPoolCancelQueuedTask(t *testing.T) {
+	p, err := NewPool(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := blockWorkers(t, p, 1)
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan struct{}, 1)
+	f, err := p.Submit(ctx, 0, func(ctx context.Context) (interface{}, error) {
+		ran <- struct{}{}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	if _, err := getResult(t, f); err != context.Canceled {
+		t.Fatalf("Get() = %v, want context.Canceled", err)
+	}
+	if m := p.Metrics(); m.QueueDepth != 0 || m.Canceled != 1 {
+		t.Fatalf("Metrics() = %+v, want an empty queue and 1 canceled", m)
+	}
+	if _, err := p.TrySubmit(context.Background(), 0, func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	}); err != nil {
+		t.Fatalf("TrySubmit() after the queued task was canceled = %v", err)
+	}
+	select {
+	case <-ran:
+		t.Fatal("canceled task ran")
+	default:
+	}
+}
+
+// 任务中的panic被转换为PanicError，不影响工作go程
+func TestPoolPanic(t *testing.T) {
+	p, err := NewPool(1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := p.Submit(context.Background(), 0, func(ctx context.Context) (interface{}, error) {
+		panic("boom")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = getResult(t, f)
+	pe, ok := err.(*PanicError)
+	if !ok || pe.Value != "boom" || len(pe.Stack) == 0 {
+		t.Fatalf("Get() = %v, want a PanicError with value boom", err)
+	}
+
+	fail := errors.New("fail")
+	f, _ = p.Submit(context.Background(), 0, func(ctx context.Context) (interface{}, error) {
+		return nil, fail
+	})
+	if _, err := getResult(t, f); err != fail {
+		t.Fatalf("Get() = %v, want %v", err, fail)
+	}
+	f, _ = p.Submit(context.Background(), 0, func(ctx context.Context) (interface{}, error) {
+		return 42, nil
+	})
+	if v, err := getResult(t, f); v != 42 || err != nil {
+		t.Fatalf("Get() after a panic = %v, %v, want 42", v, err)
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	m := p.Metrics()
+	if m.Completed != 1 || m.Failed != 2 || m.Panicked != 1 {
+		t.Fatalf("Metrics() = %+v, want 1 completed, 2 failed, 1 panicked", m)
+	}
+	if m.QueueLatency.Count != 3 || m.RunLatency.Count != 3 || m.RunLatency.Max < m.RunLatency.Mean() {
+		t.Fatalf("latencies = %+v, %+v, want 3 observations each", m.QueueLatency, m.RunLatency)
+	}
+}
+
+// Shutdown的ctx被取消时强制关闭：正在执行的任务的ctx被取消，排队的任务不再执行
+func TestPoolShutdownForce(t *testing.T) {
+	p, err := NewPool(1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	running, err := p.Submit(context.Background(), 0, func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitUntil(t, func() bool { return p.Metrics().Running == 1 })
+	queued, err := p.Submit(context.Background(), 0, func(ctx context.Context) (interface{}, error) {
+		t.Error("queued task ran after a forced shutdown")
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Shutdown() = %v, want context.DeadlineExceeded", err)
+	}
+
+	if _, err := getResult(t, running); err != context.Canceled {
+		t.Fatalf("running task returned %v, want context.Canceled", err)
+	}
+	if _, err := getResult(t, queued); err == nil {
+		t.Fatal("queued task completed without an error")
+	}
+	select {
+	case <-p.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("workers did not exit")
+	}
+
+	if _, err := p.Submit(context.Background(), 0, func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	}); err == nil {
+		t.Fatal("Submit() after Shutdown succeeded")
+	}
+	if m := p.Metrics(); m.Canceled != 1 || m.Failed != 1 || m.Rejected != 1 {
+		t.Fatalf("Metrics() = %+v, want 1 canceled, 1 failed, 1 rejected", m)
+	}
+}
@@ -0,0 +1,76 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+func PoolExample() {
+	p, err := NewPool(2, 10)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// 优先级高的任务先执行
+	var futures []*Future
+	for i := 0; i < 5; i++ {
+		i := i
+		f, err := p.Submit(context.Background(), i, func(ctx context.Context) (interface{}, error) {
+			time.Sleep(time.Millisecond)
+			return i * i, nil
+		})
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		futures = append(futures, f)
+	}
+	for _, f := range futures {
+		v, err := f.Get(context.Background())
+		fmt.Println(v, err)
+	}
+
+	// 任务的panic和错误通过Future返回
+	f, _ := p.Submit(context.Background(), 0, func(ctx context.Context) (interface{}, error) {
+		panic("boom")
+	})
+	_, err = f.Get(context.Background())
+	var pe *PanicError
+	fmt.Println(errors.As(err, &pe))
+
+	// 任务可以通过ctx取消
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	f, _ = p.Submit(ctx, 0, func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	_, err = f.Get(context.Background())
+	fmt.Println(err)
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		fmt.Println(err)
+	}
+	m := p.Metrics()
+	fmt.Println(m.Submitted, m.Completed, m.Failed, m.Panicked, m.QueueLatency.Mean() >= 0)
+}
@@ -36,7 +36,17 @@ type PriorityQueue interface {
 
 	fix(index int)
 
+	// Fix 下标index处元素的优先级改变后重新调整它在堆中的位置
+	Fix(index int) error
+
+	// Remove 删除并返回下标index处的元素
+	Remove(index int) (interface{}, error)
+
 	SetFunc(less func(interface{}, interface{}) bool)
 
+	// SetIndexFunc 设置元素在堆中的下标改变时调用的函数，元素离开队列时下标为-1
+	// 元素可以借此记录自己的下标，以便之后调用Fix或者Remove
+	SetIndexFunc(f func(value interface{}, index int))
+
 	Container.Container
 }
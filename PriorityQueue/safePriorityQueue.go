@@ -17,19 +17,19 @@
 
 package PriorityQueue
 
-import "sync"
+import "GTL/GSync"
 
 type safePriorityQueue struct {
 	uq *unsafePriorityQueue
-	m  sync.RWMutex
+	m  *GSync.RWMutex
 }
 
 func NewSafePriorityQueue(maxSize int, values ...interface{}) (*safePriorityQueue, error) {
-	q, err := NewUnsafePriorityQueue(maxSize, values)
+	q, err := NewUnsafePriorityQueue(maxSize, values...)
 
 	return &safePriorityQueue{
 		uq: q,
-		m:  sync.RWMutex{},
+		m:  new(GSync.RWMutex),
 	}, err
 }
 
@@ -38,10 +38,20 @@ func NewSafePriorityQueueWithSlice(maxSize int, values []interface{}) (*safePrio
 
 	return &safePriorityQueue{
 		uq: q,
-		m:  sync.RWMutex{},
+		m:  new(GSync.RWMutex),
 	}, err
 }
 
+// NewSafePriorityQueueWithLocker 返回使用m保护的优先队列，m可以由GSync.NewRWMutexWithLocker创建，为nil时与NewSafePriorityQueueWithSlice相同
+func NewSafePriorityQueueWithLocker(m *GSync.RWMutex, maxSize int, values []interface{}) (*safePriorityQueue, error) {
+	q, err := NewSafePriorityQueueWithSlice(maxSize, values)
+	if m != nil {
+		q.m = m
+	}
+
+	return q, err
+}
+
 func (q *safePriorityQueue) Push(value interface{}) error {
 	q.m.Lock()
 	defer q.m.Unlock()
@@ -60,7 +70,7 @@ func (q *safePriorityQueue) Top() (interface{}, error) {
 	q.m.RLock()
 	defer q.m.RUnlock()
 
-	return q.Top()
+	return q.uq.Top()
 }
 
 func (q *safePriorityQueue) fix(index int) {
@@ -70,6 +80,27 @@ func (q *safePriorityQueue) fix(index int) {
 	q.uq.fix(index)
 }
 
+func (q *safePriorityQueue) Fix(index int) error {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	return q.uq.Fix(index)
+}
+
+func (q *safePriorityQueue) Remove(index int) (interface{}, error) {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	return q.uq.Remove(index)
+}
+
+func (q *safePriorityQueue) SetIndexFunc(f func(value interface{}, index int)) {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	q.uq.SetIndexFunc(f)
+}
+
 func (q *safePriorityQueue) SetFunc(less func(interface{}, interface{}) bool) {
 	q.m.Lock()
 	defer q.m.Unlock()
@@ -153,3 +184,32 @@ func (q *safePriorityQueue) UnmarshalJSON(b []byte) error {
 
 	return q.uq.UnmarshalJSON(b)
 }
+
+// Do 持有写锁执行f，f中对u的多次操作整体上是原子的
+// u是不加锁的内部优先队列，不能在f返回后继续使用；f返回错误时已经做出的修改不会回滚，需要回滚时使用Container.Transact
+func (q *safePriorityQueue) Do(f func(u PriorityQueue) error) error {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	return f(q.uq)
+}
+
+// Locker 返回保护优先队列的读写锁
+func (q *safePriorityQueue) Locker() *GSync.RWMutex {
+	return q.m
+}
+
+// UnsafeView 返回不加锁的内部优先队列，只能在持有锁时使用
+func (q *safePriorityQueue) UnsafeView() interface{} {
+	return q.uq
+}
+
+// Snapshot 保存优先队列当前的内容，返回的函数把优先队列恢复到保存时的状态，调用时必须持有写锁
+func (q *safePriorityQueue) Snapshot() func() {
+	old := q.uq.clone()
+
+	return func() {
+		*q.uq = *old
+		q.uq.reindex()
+	}
+}
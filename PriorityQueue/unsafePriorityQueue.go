@@ -30,6 +30,9 @@ type unsafePriorityQueue struct {
 	maxSize int
 	s       []interface{}
 	less    func(i, j interface{}) bool
+
+	// setIndex 元素在堆中的下标改变时调用，元素离开队列时下标为-1
+	setIndex func(value interface{}, index int)
 }
 
 func NewUnsafePriorityQueue(maxSize int, values ...interface{}) (*unsafePriorityQueue, error) {
@@ -38,11 +41,12 @@ func NewUnsafePriorityQueue(maxSize int, values ...interface{}) (*unsafePriority
 	}
 
 	q := &unsafePriorityQueue{
-		maxSize: maxSize,
-		s:       values,
-		less:    nil,
+		maxSize:  maxSize,
+		s:        values,
+		less:     nil,
+		setIndex: nil,
 	}
-	q.fix(0)
+	q.heapify()
 
 	return q, nil
 }
@@ -53,21 +57,47 @@ func NewUnsafePriorityQueueWithSlice(maxSize int, values []interface{}) (*unsafe
 	}
 
 	q := &unsafePriorityQueue{
-		maxSize: maxSize,
-		s:       []interface{}{},
-		less:    nil,
+		maxSize:  maxSize,
+		s:        []interface{}{},
+		less:     nil,
+		setIndex: nil,
 	}
 
 	for _, value := range values {
 		q.s = append(q.s, value)
 	}
-	q.fix(0)
+	q.heapify()
 
 	return q, nil
 }
 
+// lessAt 使用less函数比较下标i与j处的元素，less未设置时视为不小于
+func (q *unsafePriorityQueue) lessAt(i, j int) bool {
+	if q.less == nil {
+		return false
+	}
+
+	return q.less(q.s[i], q.s[j])
+}
+
+// moved 通知元素value它的新下标
+func (q *unsafePriorityQueue) moved(value interface{}, index int) {
+	if q.setIndex != nil {
+		q.setIndex(value, index)
+	}
+}
+
+// reindex 通知所有元素它们当前的下标
+func (q *unsafePriorityQueue) reindex() {
+	for i, value := range q.s {
+		q.moved(value, i)
+	}
+}
+
 func (q *unsafePriorityQueue) swap(i, j int) {
 	q.s[i], q.s[j] = q.s[j], q.s[i]
+	q.moved(q.s[i], i)
+	q.moved(q.s[j], j)
 }
 
 // up 将元素向上调整
@@ -75,7 +105,7 @@ func (q *unsafePriorityQueue) up(index int) {
 	for {
 		// i是该元素的父亲结点
 		i := (index - 1) / 2
-		if i == index || !q.less(index, i) {
+		if i == index || !q.lessAt(index, i) {
 			break
 		}
 		q.swap(i, index)
@@ -94,11 +124,11 @@ func (q *unsafePriorityQueue) down(start, end int) bool {
 		}
 		// 获取左孩子
 		j := j1
-		if j2 := j1 + 1; j2 < end && q.less(j2, j1) {
+		if j2 := j1 + 1; j2 < end && q.lessAt(j2, j1) {
 			// 获取右孩子
 			j = j2 // = 2*i + 2
 		}
-		if !q.less(j, i) {
+		if !q.lessAt(j, i) {
 			break
 		}
 		q.swap(i, j)
@@ -107,6 +137,14 @@ func (q *unsafePriorityQueue) down(start, end int) bool {
 	return i > start
 }
 
+// heapify 将整个切片调整为堆
+func (q *unsafePriorityQueue) heapify() {
+	n := q.Size()
+	for i := n/2 - 1; i >= 0; i-- {
+		q.down(i, n)
+	}
+}
+
 // fix 调整堆
 func (q *unsafePriorityQueue) fix(index int) {
 	if !q.down(index, q.Size()) {
@@ -120,6 +158,7 @@ func (q *unsafePriorityQueue) Push(value interface{}) error {
 	}
 
 	q.s = append(q.s, value)
+	q.moved(value, q.Size()-1)
 	q.up(q.Size() - 1)
 
 	return nil
@@ -131,16 +170,51 @@ func (q *unsafePriorityQueue) Pop() (interface{}, error) {
 		return nil, errors.New("This queue is empty")
 	}
 
-	value := q.s[len(q.s)-1]
 	n := q.Size() - 1
 	q.swap(0, n)
-	q.s = q.s[:q.Size()-1]
+	value := q.s[n]
+	q.s[n] = nil
+	q.s = q.s[:n]
+	q.moved(value, -1)
 
 	q.down(0, n)
 
 	return value, nil
 }
 
+// Fix 下标index处元素的优先级改变后重新调整它在堆中的位置
+func (q *unsafePriorityQueue) Fix(index int) error {
+	if index < 0 || index >= q.Size() {
+		return errors.New("Index out of range.")
+	}
+
+	q.fix(index)
+
+	return nil
+}
+
+// Remove 删除并返回下标index处的元素
+func (q *unsafePriorityQueue) Remove(index int) (interface{}, error) {
+	if index < 0 || index >= q.Size() {
+		return nil, errors.New("Index out of range.")
+	}
+
+	n := q.Size() - 1
+	if index != n {
+		q.swap(index, n)
+	}
+	value := q.s[n]
+	q.s[n] = nil
+	q.s = q.s[:n]
+	q.moved(value, -1)
+
+	if index != n {
+		q.fix(index)
+	}
+
+	return value, nil
+}
+
 func (q *unsafePriorityQueue) Top() (interface{}, error) {
 	if q.Empty() {
 		return nil, errors.New("This priority queue is empty")
@@ -151,7 +225,13 @@ func (q *unsafePriorityQueue) Top() (interface{}, error) {
 
 func (q *unsafePriorityQueue) SetFunc(less func(interface{}, interface{}) bool) {
 	q.less = less
-	q.fix(0)
+	q.heapify()
+}
+
+// SetIndexFunc 设置元素在堆中的下标改变时调用的函数，设置后立即通知所有元素当前的下标
+func (q *unsafePriorityQueue) SetIndexFunc(f func(value interface{}, index int)) {
+	q.setIndex = f
+	q.reindex()
 }
 
 func (q *unsafePriorityQueue) Fill() bool {
@@ -186,6 +266,9 @@ func (q *unsafePriorityQueue) SetMaxSize(maxSize int) error {
 }
 
 func (q *unsafePriorityQueue) Clear() {
+	for _, value := range q.s {
+		q.moved(value, -1)
+	}
 	q.s = nil
 }
 
@@ -206,7 +289,7 @@ func (q *unsafePriorityQueue) CatFromSlice(values []interface{}) error {
 		}
 	}
 
-	q.fix(0)
+	q.heapify()
 
 	return nil
 }
@@ -219,6 +302,16 @@ func (q *unsafePriorityQueue) ToSlice() []interface{} {
 	return b
 }
 
+// clone 复制出一个内容和比较函数都相同的优先队列，元素本身不会被复制
+func (q *unsafePriorityQueue) clone() *unsafePriorityQueue {
+	return &unsafePriorityQueue{
+		maxSize:  q.maxSize,
+		s:        q.ToSlice(),
+		less:     q.less,
+		setIndex: q.setIndex,
+	}
+}
+
 func (q *unsafePriorityQueue) MarshalJSON() ([]byte, error) {
 	items := make([]string, 0, q.Size())
 
@@ -260,7 +353,7 @@ func (q *unsafePriorityQueue) UnmarshalJSON(b []byte) error {
 		}
 	}
 
-	q.fix(0)
+	q.heapify()
 
 	return nil
 }
@@ -0,0 +1,86 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package PriorityQueue
+
+import (
+	"testing"
+)
+
+type indexedItem struct {
+	priority int
+	index    int
+}
+
+// 通过SetIndexFunc记录的下标原地调整和删除元素后，出队顺序仍然正确
+func TestUnsafePriorityQueueFixRemove(t *testing.T) {
+	q, err := NewUnsafePriorityQueue(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SetFunc(func(a, b interface{}) bool {
+		return a.(*indexedItem).priority < b.(*indexedItem).priority
+	})
+	q.SetIndexFunc(func(value interface{}, index int) {
+		value.(*indexedItem).index = index
+	})
+
+	items := make([]*indexedItem, 10)
+	for i := range items {
+		items[i] = &indexedItem{priority: i * 10, index: -1}
+		if err := q.Push(items[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items[9].priority = -1
+	if err := q.Fix(items[9].index); err != nil {
+		t.Fatal(err)
+	}
+	items[0].priority = 55
+	if err := q.Fix(items[0].index); err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{3, 7} {
+		value, err := q.Remove(items[i].index)
+		if err != nil || value != items[i] {
+			t.Fatalf("Remove(item %d) = %v, %v", i, value, err)
+		}
+		if items[i].index != -1 {
+			t.Fatalf("removed item %d has index %d, want -1", i, items[i].index)
+		}
+	}
+	if _, err := q.Remove(q.Size()); err == nil {
+		t.Fatal("Remove out of range succeeded")
+	}
+
+	want := []int{-1, 10, 20, 40, 50, 55, 60, 80}
+	for _, p := range want {
+		for i, value := range q.ToSlice() {
+			if value.(*indexedItem).index != i {
+				t.Fatalf("item at %d records index %d", i, value.(*indexedItem).index)
+			}
+		}
+		value, err := q.Pop()
+		if err != nil || value.(*indexedItem).priority != p {
+			t.Fatalf("Pop() = %v, %v, want priority %d", value, err, p)
+		}
+		if value.(*indexedItem).index != -1 {
+			t.Fatalf("popped item has index %d, want -1", value.(*indexedItem).index)
+		}
+	}
+}
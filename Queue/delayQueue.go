@@ -0,0 +1,198 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Queue
+
+import (
+	"GTL/PriorityQueue"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// delayedEntry 延迟队列中的元素，seq保证就绪时间相同的元素按加入顺序出队
+type delayedEntry struct {
+	value   interface{}
+	readyAt time.Time
+	seq     uint64
+}
+
+// DelayQueue 延迟队列，元素在就绪时间之前不能被取出
+// 内部使用按就绪时间排序的小顶堆，Take会阻塞到队首元素就绪为止
+type DelayQueue struct {
+	pq PriorityQueue.PriorityQueue
+	m  *sync.Mutex
+
+	// wakeup 队首元素发生变化时关闭并替换，通知所有等待的Take重新检查
+	wakeup chan struct{}
+	seq    uint64
+}
+
+func NewDelayQueue(maxSize int) (*DelayQueue, error) {
+	pq, err := PriorityQueue.NewUnsafePriorityQueue(maxSize)
+	if err != nil {
+		return nil, err
+	}
+	pq.SetFunc(func(a, b interface{}) bool {
+		x, y := a.(*delayedEntry), b.(*delayedEntry)
+		if x.readyAt.Equal(y.readyAt) {
+			return x.seq < y.seq
+		}
+		return x.readyAt.Before(y.readyAt)
+	})
+
+	return &DelayQueue{
+		pq:     pq,
+		m:      new(sync.Mutex),
+		wakeup: make(chan struct{}),
+		seq:    0,
+	}, nil
+}
+
+// Push 加入一个在readyAt时刻就绪的元素
+func (q *DelayQueue) Push(value interface{}, readyAt time.Time) error {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	q.seq++
+	e := &delayedEntry{value: value, readyAt: readyAt, seq: q.seq}
+	if err := q.pq.Push(e); err != nil {
+		return err
+	}
+
+	// 新元素成为队首时唤醒等待者，因为它们等待的时间可能太长了
+	if top, _ := q.pq.Top(); top == e {
+		close(q.wakeup)
+		q.wakeup = make(chan struct{})
+	}
+
+	return nil
+}
+
+// PushDelay 加入一个在d之后就绪的元素
+func (q *DelayQueue) PushDelay(value interface{}, d time.Duration) error {
+	return q.Push(value, time.Now().Add(d))
+}
+
+// Take 取出队首元素，队列为空或队首元素尚未就绪时阻塞，ctx被取消时返回ctx.Err()
+func (q *DelayQueue) Take(ctx context.Context) (interface{}, error) {
+	for {
+		q.m.Lock()
+		wakeup := q.wakeup
+
+		var timer *time.Timer
+		if !q.pq.Empty() {
+			top, _ := q.pq.Top()
+			d := time.Until(top.(*delayedEntry).readyAt)
+			if d <= 0 {
+				_, _ = q.pq.Pop()
+				q.m.Unlock()
+				return top.(*delayedEntry).value, nil
+			}
+			timer = time.NewTimer(d)
+		}
+		q.m.Unlock()
+
+		if timer == nil {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-wakeup:
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-wakeup:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// Poll 取出已经就绪的队首元素，没有就绪元素时立即返回错误
+func (q *DelayQueue) Poll() (interface{}, error) {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	if q.pq.Empty() {
+		return nil, errors.New("This queue is empty.")
+	}
+
+	top, _ := q.pq.Top()
+	if time.Now().Before(top.(*delayedEntry).readyAt) {
+		return nil, errors.New("No element is ready.")
+	}
+	_, _ = q.pq.Pop()
+
+	return top.(*delayedEntry).value, nil
+}
+
+// Peek 返回队首元素及其就绪时间但不取出，无论元素是否就绪
+func (q *DelayQueue) Peek() (interface{}, time.Time, error) {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	if q.pq.Empty() {
+		return nil, time.Time{}, errors.New("This queue is empty.")
+	}
+
+	top, _ := q.pq.Top()
+	e := top.(*delayedEntry)
+
+	return e.value, e.readyAt, nil
+}
+
+// DrainReady 取出所有已经就绪的元素，按就绪时间排列
+func (q *DelayQueue) DrainReady() []interface{} {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	now := time.Now()
+	ret := make([]interface{}, 0)
+	for !q.pq.Empty() {
+		top, _ := q.pq.Top()
+		if now.Before(top.(*delayedEntry).readyAt) {
+			break
+		}
+		_, _ = q.pq.Pop()
+		ret = append(ret, top.(*delayedEntry).value)
+	}
+
+	return ret
+}
+
+// Len 返回队列中的元素数量，包括尚未就绪的元素
+func (q *DelayQueue) Len() int {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	return q.pq.Size()
+}
+
+func (q *DelayQueue) Clear() {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	q.pq.Clear()
+	close(q.wakeup)
+	q.wakeup = make(chan struct{})
+}
@@ -0,0 +1,210 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Queue
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// takeAsync 在新的go程中调用q.Take，返回接收结果的通道
+func takeAsync(q *DelayQueue, ctx context.Context) <-chan interface{} {
+	ch := make(chan interface{}, 1)
+	go func() {
+		v, err := q.Take(ctx)
+		if err != nil {
+			ch <- err
+			return
+		}
+		ch <- v
+	}()
+
+	return ch
+}
+
+// 元素按就绪时间出队，就绪时间相同时按加入顺序出队，未就绪的元素不能被Poll取出
+func TestDelayQueueOrder(t *testing.T) {
+	q, err := NewDelayQueue(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	_ = q.Push("c", now.Add(-1*time.Second))
+	_ = q.Push("a", now.Add(-3*time.Second))
+	_ = q.Push("b1", now.Add(-2*time.Second))
+	_ = q.Push("b2", now.Add(-2*time.Second))
+	_ = q.Push("later", now.Add(time.Hour))
+
+	if v, readyAt, err := q.Peek(); err != nil || v != "a" || !readyAt.Equal(now.Add(-3*time.Second)) {
+		t.Fatalf("Peek() = %v, %v, %v, want a", v, readyAt, err)
+	}
+	if v, err := q.Poll(); err != nil || v != "a" {
+		t.Fatalf("Poll() = %v, %v, want a", v, err)
+	}
+	if got := q.DrainReady(); !reflect.DeepEqual(got, []interface{}{"b1", "b2", "c"}) {
+		t.Fatalf("DrainReady() = %v, want [b1 b2 c]", got)
+	}
+
+	// 只剩下未就绪的元素
+	if _, err := q.Poll(); err == nil {
+		t.Fatal("Poll() returned an element that is not ready")
+	}
+	if got := q.DrainReady(); len(got) != 0 {
+		t.Fatalf("DrainReady() = %v, want nothing", got)
+	}
+	if v, _, err := q.Peek(); err != nil || v != "later" || q.Len() != 1 {
+		t.Fatalf("Peek() = %v, %v with Len() %d, want later with 1", v, err, q.Len())
+	}
+
+	q.Clear()
+	if _, err := q.Poll(); err == nil {
+		t.Fatal("Poll() on an empty queue succeeded")
+	}
+	if _, _, err := q.Peek(); err == nil {
+		t.Fatal("Peek() on an empty queue succeeded")
+	}
+
+	bounded, _ := NewDelayQueue(1)
+	_ = bounded.PushDelay(1, 0)
+	if err := bounded.PushDelay(2, 0); err == nil {
+		t.Fatal("Push into a full queue succeeded")
+	}
+}
+
+// Take阻塞到队首元素就绪，空队列上的Take在Push之后返回
+func TestDelayQueueTakeWaits(t *testing.T) {
+	q, _ := NewDelayQueue(-1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_ = q.PushDelay("x", 50*time.Millisecond)
+	if v, err := q.Take(ctx); err != nil || v != "x" {
+		t.Fatalf("Take() = %v, %v, want x", v, err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("Take() returned after %v, before the element was ready", elapsed)
+	}
+
+	ch := takeAsync(q, ctx)
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case v := <-ch:
+		t.Fatalf("Take() on an empty queue returned %v", v)
+	default:
+	}
+	_ = q.Push("y", time.Now())
+	if v := <-ch; v != "y" {
+		t.Fatalf("Take() = %v, want y", v)
+	}
+}
+
+// 等待过程中加入了更早就绪的元素时，Take不会继续等待原来的队首元素
+func TestDelayQueueTakeEarlierPush(t *testing.T) {
+	q, _ := NewDelayQueue(-1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_ = q.PushDelay("late", time.Hour)
+	ch := takeAsync(q, ctx)
+	time.Sleep(20 * time.Millisecond)
+
+	_ = q.PushDelay("early", 10*time.Millisecond)
+	if v := <-ch; v != "early" {
+		t.Fatalf("Take() = %v, want early", v)
+	}
+	if v, _, _ := q.Peek(); v != "late" || q.Len() != 1 {
+		t.Fatalf("Peek() = %v with Len() %d, want late with 1", v, q.Len())
+	}
+}
+
+// ctx取消或超时时Take返回ctx.Err()，队列中的元素保持不变
+func TestDelayQueueTakeContext(t *testing.T) {
+	q, _ := NewDelayQueue(-1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := takeAsync(q, ctx)
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if v := <-ch; v != context.Canceled {
+		t.Fatalf("Take() on an empty queue after cancel = %v, want context.Canceled", v)
+	}
+
+	_ = q.PushDelay("x", time.Hour)
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := q.Take(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Take() before the element is ready = %v, want context.DeadlineExceeded", err)
+	}
+	if q.Len() != 1 {
+		t.Fatalf("Len() = %d after a timed out Take, want 1", q.Len())
+	}
+
+	// 已经取消的ctx在有就绪元素时仍然可以取出
+	_ = q.Push("ready", time.Now())
+	if v, err := q.Take(ctx); err != nil || v != "ready" {
+		t.Fatalf("Take() with a ready element = %v, %v, want ready", v, err)
+	}
+}
+
+// 多个Take并发等待时，每个元素只被取出一次
+func TestDelayQueueConcurrentTake(t *testing.T) {
+	q, _ := NewDelayQueue(-1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	const n = 50
+	results := make(chan interface{}, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := q.Take(ctx)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results <- v
+		}()
+	}
+	for i := 0; i < n; i++ {
+		_ = q.PushDelay(i, time.Duration(i%5)*time.Millisecond)
+	}
+	wg.Wait()
+	close(results)
+
+	var got []int
+	for v := range results {
+		got = append(got, v.(int))
+	}
+	sort.Ints(got)
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("taken elements = %v, want each of 0..%d once", got, n-1)
+		}
+	}
+	if len(got) != n || q.Len() != 0 {
+		t.Fatalf("took %d elements, %d left", len(got), q.Len())
+	}
+}
@@ -0,0 +1,181 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// 持久化队列，使用Okasaki的实时队列实现
+// 队列由惰性求值的前端流f、逆序保存新元素的后端链表r以及调度流s组成，始终保持|s| = |f| - |r|。
+// 每次操作强制求值s的一个结点，当s为空时（此时|r| = |f| + 1）开始把r逆序接到f后面，
+// 逆序的过程被分摊到之后的操作中，因此即使反复在同一个旧版本上操作，每次操作也是最坏O(1)的。
+
+// pqStream 惰性求值的流，nil表示空流，求值结果会被缓存，可以被多个go程同时求值
+type pqStream struct {
+	once  sync.Once
+	thunk func() *pqCell
+	cell  *pqCell
+}
+
+// pqCell 流中的一个结点，nil表示流已经结束
+type pqCell struct {
+	value interface{}
+	next  *pqStream
+}
+
+// pqList 不可变的单链表
+type pqList struct {
+	value interface{}
+	next  *pqList
+}
+
+func (s *pqStream) force() *pqCell {
+	if s == nil {
+		return nil
+	}
+
+	s.once.Do(func() {
+		s.cell = s.thunk()
+		s.thunk = nil
+	})
+
+	return s.cell
+}
+
+// evaluated 返回已经求值的流
+func evaluated(cell *pqCell) *pqStream {
+	s := &pqStream{}
+	s.once.Do(func() {
+		s.cell = cell
+	})
+
+	return s
+}
+
+// rotate 惰性地计算f ++ reverse(r) ++ a，要求|r| = |f| + 1
+func rotate(f *pqStream, r *pqList, a *pqStream) *pqStream {
+	return &pqStream{thunk: func() *pqCell {
+		fc := f.force()
+		if fc == nil {
+			return &pqCell{value: r.value, next: a}
+		}
+
+		return &pqCell{value: fc.value, next: rotate(fc.next, r.next, evaluated(&pqCell{value: r.value, next: a}))}
+	}}
+}
+
+// persistentQueue 不可变的队列，Push和Pop都返回新版本，可以被多个go程同时读取
+type persistentQueue struct {
+	f    *pqStream
+	r    *pqList
+	s    *pqStream
+	size int
+}
+
+func NewPersistentQueue(values ...interface{}) *persistentQueue {
+	return NewPersistentQueueWithSlice(values)
+}
+
+func NewPersistentQueueWithSlice(values []interface{}) *persistentQueue {
+	q := &persistentQueue{f: nil, r: nil, s: nil, size: 0}
+	for _, value := range values {
+		q = q.Push(value)
+	}
+
+	return q
+}
+
+// exec 强制求值调度流的一个结点，调度流为空时开始新一轮逆序
+func exec(f *pqStream, r *pqList, s *pqStream, size int) *persistentQueue {
+	if sc := s.force(); sc != nil {
+		return &persistentQueue{f: f, r: r, s: sc.next, size: size}
+	}
+
+	f = rotate(f, r, nil)
+
+	return &persistentQueue{f: f, r: nil, s: f, size: size}
+}
+
+// Push 返回value入队后的新版本
+func (q *persistentQueue) Push(value interface{}) *persistentQueue {
+	return exec(q.f, &pqList{value: value, next: q.r}, q.s, q.size+1)
+}
+
+// Pop 返回出队后的新版本以及队首元素
+func (q *persistentQueue) Pop() (*persistentQueue, interface{}, error) {
+	fc := q.f.force()
+	if fc == nil {
+		return nil, nil, errors.New("This queue is empty")
+	}
+
+	return exec(fc.next, q.r, q.s, q.size-1), fc.value, nil
+}
+
+func (q *persistentQueue) Front() (interface{}, error) {
+	fc := q.f.force()
+	if fc == nil {
+		return nil, errors.New("This queue is empty.")
+	}
+
+	return fc.value, nil
+}
+
+func (q *persistentQueue) Empty() bool {
+	return q.size == 0
+}
+
+func (q *persistentQueue) Size() int {
+	return q.size
+}
+
+// ToSlice 将队列以切片形式返回，队首元素位于最前
+func (q *persistentQueue) ToSlice() []interface{} {
+	ans := make([]interface{}, 0, q.size)
+	for c := q.f.force(); c != nil; c = c.next.force() {
+		ans = append(ans, c.value)
+	}
+
+	rear := make([]interface{}, 0, q.size-len(ans))
+	for p := q.r; p != nil; p = p.next {
+		rear = append(rear, p.value)
+	}
+	for i := len(rear) - 1; i >= 0; i-- {
+		ans = append(ans, rear[i])
+	}
+
+	return ans
+}
+
+func (q *persistentQueue) String() string {
+	items := make([]string, 0, q.size)
+	for _, value := range q.ToSlice() {
+		items = append(items, fmt.Sprintf("%v", value))
+	}
+
+	return fmt.Sprintf("persistentQueue{%s}", strings.Join(items, ", "))
+}
+
+// MarshalJSON 将Queue中的所有元素以Json数组的形式返回
+func (q *persistentQueue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.ToSlice())
+}
@@ -0,0 +1,133 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Queue
+
+import (
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// pqStreamLen 返回流的长度，会强制求值整个流
+func pqStreamLen(s *pqStream) int {
+	n := 0
+	for c := s.force(); c != nil; c = c.next.force() {
+		n++
+	}
+
+	return n
+}
+
+func pqListLen(l *pqList) int {
+	n := 0
+	for ; l != nil; l = l.next {
+		n++
+	}
+
+	return n
+}
+
+// 在任意旧版本上反复Push和Pop，每个版本的内容都与模型一致，并始终满足|s| = |f| - |r|
+func TestPersistentQueueVersions(t *testing.T) {
+	type version struct {
+		q    *persistentQueue
+		want []interface{}
+	}
+	versions := []version{{NewPersistentQueue(), []interface{}{}}}
+	rnd := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 3000; i++ {
+		// 偏向最近的版本以得到较长的队列，同时经常回到旧版本上操作
+		base := versions[len(versions)-1-rnd.Intn(len(versions))/(1+rnd.Intn(4))]
+
+		var next version
+		if len(base.want) == 0 || rnd.Intn(3) != 0 {
+			next.q = base.q.Push(i)
+			next.want = append(append([]interface{}{}, base.want...), i)
+		} else {
+			q, front, err := base.q.Pop()
+			if err != nil || front != base.want[0] {
+				t.Fatalf("Pop() = %v, %v, want %v", front, err, base.want[0])
+			}
+			next.q = q
+			next.want = append([]interface{}{}, base.want[1:]...)
+		}
+		versions = append(versions, next)
+	}
+
+	for _, v := range versions {
+		if got := v.q.ToSlice(); !reflect.DeepEqual(got, v.want) || v.q.Size() != len(v.want) {
+			t.Fatalf("ToSlice() = %v with Size() %d, want %v", got, v.q.Size(), v.want)
+		}
+		if f, r, s := pqStreamLen(v.q.f), pqListLen(v.q.r), pqStreamLen(v.q.s); s != f-r {
+			t.Fatalf("|f| = %d, |r| = %d, |s| = %d, want |s| = |f| - |r|", f, r, s)
+		}
+	}
+}
+
+// 多个go程同时在同一个版本上出队，共享的惰性流只被求值一次，结果相同
+func TestPersistentQueueConcurrentPop(t *testing.T) {
+	q := NewPersistentQueue()
+	for i := 0; i < 1000; i++ {
+		q = q.Push(i)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v := q
+			for i := 0; i < 1000; i++ {
+				var x interface{}
+				var err error
+				v, x, err = v.Pop()
+				if err != nil || x != i {
+					t.Errorf("Pop() = %v, %v, want %d", x, err, i)
+					return
+				}
+			}
+			if !v.Empty() {
+				t.Errorf("Size() = %d after popping everything", v.Size())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// 空队列上的Pop和Front返回错误
+func TestPersistentQueueEmpty(t *testing.T) {
+	q := NewPersistentQueue(1)
+	empty, x, err := q.Pop()
+	if err != nil || x != 1 || !empty.Empty() {
+		t.Fatalf("Pop() = %v, %v, want 1 and an empty queue", x, err)
+	}
+	if _, _, err := empty.Pop(); err == nil {
+		t.Fatal("Pop() on an empty queue succeeded")
+	}
+	if _, err := empty.Front(); err == nil {
+		t.Fatal("Front() on an empty queue succeeded")
+	}
+	if front, _ := q.Front(); front != 1 {
+		t.Fatalf("Front() of the old version = %v, want 1", front)
+	}
+	if got, err := NewPersistentQueue(1, 2, 3).MarshalJSON(); err != nil || string(got) != "[1,2,3]" {
+		t.Fatalf("MarshalJSON() = %s, %v, want [1,2,3]", got, err)
+	}
+}
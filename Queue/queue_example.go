@@ -17,7 +17,13 @@
 
 package Queue
 
-import "fmt"
+import (
+	"GTL/Container"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
 
 func UnsafeQueueExample() {
 	q, err := NewUnsafeQueue(-1, 1, 2, 3, 4, 5)
@@ -64,3 +70,81 @@ func UnsafeQueueExample() {
 func SafeQueueExample() {
 
 }
+
+func DelayQueueExample() {
+	q, err := NewDelayQueue(-1)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	_ = q.PushDelay("retry-2", 20*time.Millisecond)
+	_ = q.PushDelay("retry-1", 10*time.Millisecond)
+	_ = q.PushDelay("ready", 0)
+
+	fmt.Println("q.DrainReady =", q.DrainReady())
+	v, readyAt, err := q.Peek()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("q.Peek =", v, readyAt)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for q.Len() > 0 {
+		v, err = q.Take(ctx)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Println("q.Take =", v)
+	}
+}
+
+func TransactExample() {
+	src, _ := NewSafeQueue(-1, 1, 2, 3)
+	dst, _ := NewSafeQueue(3, 10)
+
+	// 先判断再入队，两步在同一把锁内完成
+	_ = dst.Do(func(u Queue) error {
+		if !u.Fill() {
+			return u.Push(11)
+		}
+		return nil
+	})
+
+	// 从src出队并加入dst，dst满时整体回滚，src中的元素不会丢失
+	move := func(n int) error {
+		return Container.Transact(func(views []interface{}) error {
+			from, to := views[0].(Queue), views[1].(Queue)
+			for i := 0; i < n; i++ {
+				v, err := from.Pop()
+				if err != nil {
+					return err
+				}
+				if err = to.Push(v); err != nil {
+					return errors.New("dst is fill, rollback")
+				}
+			}
+			return nil
+		}, src, dst)
+	}
+
+	fmt.Println(move(2), src.Size(), dst.Size())
+	fmt.Println(move(1), src.Size(), dst.Size())
+}
+
+func PersistentQueueExample() {
+	q1 := NewPersistentQueue(1, 2, 3)
+	q2 := q1.Push(4)
+	q3, front, _ := q2.Pop()
+
+	// 每次操作都返回新版本，旧版本保持不变
+	fmt.Println(q1, q2, q3, front)
+
+	// 在同一个旧版本上反复操作也不会退化
+	for i := 0; i < 3; i++ {
+		q, v, _ := q2.Pop()
+		fmt.Println(q.Size(), v)
+	}
+}
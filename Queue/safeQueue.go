@@ -18,20 +18,20 @@
 package Queue
 
 import (
-	"sync"
+	"GTL/GSync"
 )
 
 type safeQueue struct {
 	uq *unsafeQueue
-	m  *sync.RWMutex
+	m  *GSync.RWMutex
 }
 
 func NewSafeQueue(maxSize int, values ...interface{}) (*safeQueue, error) {
-	q, err := NewUnsafeQueue(maxSize, values)
+	q, err := NewUnsafeQueue(maxSize, values...)
 
 	return &safeQueue{
 		uq: q,
-		m:  new(sync.RWMutex),
+		m:  new(GSync.RWMutex),
 	}, err
 }
 
@@ -40,10 +40,20 @@ func NewSafeQueueWithSlice(maxSize int, values []interface{}) (*safeQueue, error
 
 	return &safeQueue{
 		uq: q,
-		m:  new(sync.RWMutex),
+		m:  new(GSync.RWMutex),
 	}, err
 }
 
+// NewSafeQueueWithLocker 返回使用m保护的队列，m可以由GSync.NewRWMutexWithLocker创建，为nil时与NewSafeQueueWithSlice相同
+func NewSafeQueueWithLocker(m *GSync.RWMutex, maxSize int, values []interface{}) (*safeQueue, error) {
+	q, err := NewSafeQueueWithSlice(maxSize, values)
+	if m != nil {
+		q.m = m
+	}
+
+	return q, err
+}
+
 func (q *safeQueue) Push(value interface{}) error {
 	q.m.Lock()
 	defer q.m.Unlock()
@@ -141,3 +151,31 @@ func (q *safeQueue) UnmarshalJSON(b []byte) error {
 
 	return q.uq.UnmarshalJSON(b)
 }
+
+// Do 持有写锁执行f，f中对u的多次操作整体上是原子的
+// u是不加锁的内部队列，不能在f返回后继续使用；f返回错误时已经做出的修改不会回滚，需要回滚时使用Container.Transact
+func (q *safeQueue) Do(f func(u Queue) error) error {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	return f(q.uq)
+}
+
+// Locker 返回保护队列的读写锁
+func (q *safeQueue) Locker() *GSync.RWMutex {
+	return q.m
+}
+
+// UnsafeView 返回不加锁的内部队列，只能在持有锁时使用
+func (q *safeQueue) UnsafeView() interface{} {
+	return q.uq
+}
+
+// Snapshot 保存队列当前的内容，返回的函数把队列恢复到保存时的状态，调用时必须持有写锁
+func (q *safeQueue) Snapshot() func() {
+	old := q.uq.clone()
+
+	return func() {
+		*q.uq = *old
+	}
+}
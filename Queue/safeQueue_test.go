@@ -0,0 +1,64 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Queue
+
+import (
+	"GTL/GSync"
+	"testing"
+	"time"
+)
+
+// 使用UpgradeableRWLock保护的队列：持有可升级读锁时其他go程仍然可以读，写操作要等可升级读锁释放
+func TestSafeQueueWithUpgradeableLock(t *testing.T) {
+	ul := GSync.NewUpgradeableRWLock()
+	q, err := NewSafeQueueWithLocker(GSync.NewRWMutexWithLocker(ul), -1, []interface{}{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ul.ULock()
+	read := make(chan int)
+	go func() {
+		read <- q.Size()
+	}()
+	select {
+	case n := <-read:
+		if n != 2 {
+			t.Fatalf("Size() = %d, want 2", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("read blocked by the upgradeable read lock")
+	}
+
+	pushed := make(chan error)
+	go func() {
+		pushed <- q.Push(3)
+	}()
+	select {
+	case <-pushed:
+		t.Fatal("Push succeeded while the upgradeable read lock was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+	ul.UUnlock()
+	if err := <-pushed; err != nil {
+		t.Fatal(err)
+	}
+	if q.Size() != 3 {
+		t.Fatalf("Size() = %d, want 3", q.Size())
+	}
+}
@@ -126,6 +126,11 @@ func (q *unsafeQueue) Pop() (interface{}, error) {
 
 	value := q.head.next.value
 	q.head.next = q.head.next.next
+	if q.head.next == nil {
+		q.rear = q.head
+	} else {
+		q.head.next.prev = q.head
+	}
 	q.size--
 
 	return value, nil
@@ -207,7 +212,7 @@ func (q *unsafeQueue) String() string {
 
 // ToSlice 将队列以切片形式返回
 func (q *unsafeQueue) ToSlice() []interface{} {
-	ans := make([]interface{}, q.size)
+	ans := make([]interface{}, 0, q.size)
 
 	for p := q.head.next; p != nil; p = p.next {
 		ans = append(ans, p.value)
@@ -216,6 +221,17 @@ func (q *unsafeQueue) ToSlice() []interface{} {
 	return ans
 }
 
+// clone 复制出一个内容相同的队列，元素本身不会被复制
+func (q *unsafeQueue) clone() *unsafeQueue {
+	c, _ := NewUnsafeQueue(-1)
+	for p := q.head.next; p != nil; p = p.next {
+		_ = c.Push(p.value)
+	}
+	c.maxSize = q.maxSize
+
+	return c
+}
+
 // MarshalJSON 将Queue中的所有元素以Json数组的形式返回
 func (q *unsafeQueue) MarshalJSON() ([]byte, error) {
 	items := make([]string, 0, q.Size())
@@ -0,0 +1,110 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Queue
+
+import (
+	"GTL/Container"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// 弹出最后一个元素后rear回到头结点，之后入队的元素不会丢失
+func TestUnsafeQueuePopToEmptyThenPush(t *testing.T) {
+	q, err := NewUnsafeQueue(-1, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for round := 0; round < 3; round++ {
+		for q.Size() > 0 {
+			if _, err := q.Pop(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := q.Pop(); err == nil {
+			t.Fatal("Pop() on an empty queue succeeded")
+		}
+
+		_ = q.Push(round)
+		_ = q.Push(round + 10)
+		if got := q.ToSlice(); !reflect.DeepEqual(got, []interface{}{round, round + 10}) {
+			t.Fatalf("round %d: ToSlice() = %v", round, got)
+		}
+		if front, _ := q.Front(); front != round {
+			t.Fatalf("round %d: Front() = %v, want %d", round, front, round)
+		}
+	}
+}
+
+// Do中的多次操作整体执行，返回错误时不回滚；Transact返回错误时所有队列恢复原状
+func TestSafeQueueDoAndTransact(t *testing.T) {
+	src, err := NewSafeQueue(-1, 1, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := NewSafeQueue(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fail := errors.New("fail")
+	err = dst.Do(func(u Queue) error {
+		_ = u.Push("kept")
+		return fail
+	})
+	if err != fail || dst.Size() != 1 {
+		t.Fatalf("Do() = %v, Size() = %d, want the push to stay", err, dst.Size())
+	}
+
+	// 把src中的元素全部移到dst，dst放不下时整个事务回滚
+	move := func(views []interface{}) error {
+		from, to := views[0].(Queue), views[1].(Queue)
+		for !from.Empty() {
+			value, _ := from.Pop()
+			if err := to.Push(value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := Container.Transact(move, src, dst); err == nil {
+		t.Fatal("moving three elements into one free slot succeeded")
+	}
+	if got := src.ToSlice(); !reflect.DeepEqual(got, []interface{}{1, 2, 3}) {
+		t.Fatalf("src after rollback = %v", got)
+	}
+	if got := dst.ToSlice(); !reflect.DeepEqual(got, []interface{}{"kept"}) {
+		t.Fatalf("dst after rollback = %v", got)
+	}
+
+	// 回滚后的队列仍然可以正常入队出队
+	_ = src.Push(4)
+	if value, _ := src.Pop(); value != 1 || src.Size() != 3 {
+		t.Fatalf("Pop() after rollback = %v, Size() = %d", value, src.Size())
+	}
+	if err := dst.SetMaxSize(-1); err != nil {
+		t.Fatal(err)
+	}
+	if err := Container.Transact(move, src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if got := dst.ToSlice(); !reflect.DeepEqual(got, []interface{}{"kept", 2, 3, 4}) || !src.Empty() {
+		t.Fatalf("dst = %v, src size %d after commit", got, src.Size())
+	}
+}
@@ -0,0 +1,141 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package RateLimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter 限制事件发生的速率，所有实现都可以并发使用
+type Limiter interface {
+	// Allow 等价于AllowN(1)
+	Allow() bool
+
+	// AllowN 现在是否允许发生n个事件，允许时立即占用额度，n不是正数时返回false
+	AllowN(n int) bool
+
+	// Wait 等待直到允许发生n个事件，ctx被取消时返回ctx.Err()，n不是正数时返回错误
+	Wait(ctx context.Context, n int) error
+}
+
+// Clock 提供当前时间和定时，测试时可以注入ManualClock
+type Clock interface {
+	Now() time.Time
+
+	// After 在d之后向返回的通道发送当时的时间
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock 使用系统时间
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+type manualTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// ManualClock 手动推进的时钟，用于测试
+type ManualClock struct {
+	m      sync.Mutex
+	now    time.Time
+	timers []manualTimer
+}
+
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.now
+}
+
+func (c *ManualClock) After(d time.Duration) <-chan time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+	} else {
+		c.timers = append(c.timers, manualTimer{at: c.now.Add(d), c: ch})
+	}
+
+	return ch
+}
+
+// Advance 将时间推进d，并触发所有到期的定时
+func (c *ManualClock) Advance(d time.Duration) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+		} else {
+			t.c <- c.now
+		}
+	}
+	c.timers = pending
+}
+
+// Waiters 返回还没有到期的定时数量，测试中可以用来判断是否有go程正在等待
+func (c *ManualClock) Waiters() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return len(c.timers)
+}
+
+// sleep 使用clock等待d，ctx被取消时提前返回ctx.Err()
+func sleep(ctx context.Context, clock Clock, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	select {
+	case <-clock.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// durationOf 将秒数转换为time.Duration，向上取整避免提前醒来
+func durationOf(seconds float64) time.Duration {
+	d := time.Duration(seconds * float64(time.Second))
+	if float64(d) < seconds*float64(time.Second) {
+		d++
+	}
+
+	return d
+}
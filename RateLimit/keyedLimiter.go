@@ -0,0 +1,177 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package RateLimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type keyedEntry struct {
+	limiter  Limiter
+	lastUsed time.Time
+}
+
+// KeyedLimiter 为每个key（例如租户）维护一个独立的限流器，超过idle没有使用的限流器会被移除
+// 被移除的限流器下次使用时重新创建，因此idle应当不短于限流器恢复到初始状态所需的时间，
+// 例如令牌桶的burst/rate，否则移除相当于提前补满了令牌
+type KeyedLimiter struct {
+	m          sync.Mutex
+	clock      Clock
+	newLimiter func(key interface{}) Limiter
+	idle       time.Duration
+	entries    map[interface{}]*keyedEntry
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewKeyedLimiter newLimiter为第一次出现的key创建限流器，idle为0时不移除，clock为nil时使用系统时间
+func NewKeyedLimiter(newLimiter func(key interface{}) Limiter, idle time.Duration, clock Clock) (*KeyedLimiter, error) {
+	if newLimiter == nil {
+		return nil, errors.New("NewLimiter can not be nil.")
+	}
+	if idle < 0 {
+		return nil, errors.New("Idle can not be negative.")
+	}
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	return &KeyedLimiter{
+		clock:      clock,
+		newLimiter: newLimiter,
+		idle:       idle,
+		entries:    make(map[interface{}]*keyedEntry),
+	}, nil
+}
+
+// Get 返回key的限流器，不存在时创建
+func (k *KeyedLimiter) Get(key interface{}) Limiter {
+	k.m.Lock()
+	defer k.m.Unlock()
+
+	e, ok := k.entries[key]
+	if !ok {
+		e = &keyedEntry{limiter: k.newLimiter(key)}
+		k.entries[key] = e
+	}
+	e.lastUsed = k.clock.Now()
+
+	return e.limiter
+}
+
+func (k *KeyedLimiter) Allow(key interface{}) bool {
+	return k.Get(key).Allow()
+}
+
+func (k *KeyedLimiter) AllowN(key interface{}, n int) bool {
+	return k.Get(key).AllowN(n)
+}
+
+func (k *KeyedLimiter) Wait(ctx context.Context, key interface{}, n int) error {
+	return k.Get(key).Wait(ctx, n)
+}
+
+// Remove 移除key的限流器，返回key是否存在
+func (k *KeyedLimiter) Remove(key interface{}) bool {
+	k.m.Lock()
+	defer k.m.Unlock()
+
+	_, ok := k.entries[key]
+	delete(k.entries, key)
+
+	return ok
+}
+
+// Evict 移除所有超过idle没有使用的限流器，返回移除的数量
+func (k *KeyedLimiter) Evict() int {
+	if k.idle == 0 {
+		return 0
+	}
+
+	k.m.Lock()
+	defer k.m.Unlock()
+
+	deadline := k.clock.Now().Add(-k.idle)
+	count := 0
+	for key, e := range k.entries {
+		if !e.lastUsed.After(deadline) {
+			delete(k.entries, key)
+			count++
+		}
+	}
+
+	return count
+}
+
+// Len 返回当前限流器的数量
+func (k *KeyedLimiter) Len() int {
+	k.m.Lock()
+	defer k.m.Unlock()
+
+	return len(k.entries)
+}
+
+// StartJanitor 开启后台清理go程，每隔interval调用一次Evict，间隔由clock计时
+func (k *KeyedLimiter) StartJanitor(interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("Interval must be positive.")
+	}
+
+	k.m.Lock()
+	defer k.m.Unlock()
+
+	if k.stop != nil {
+		return errors.New("Janitor is already running.")
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	k.stop, k.done = stop, done
+
+	clock := k.clock
+	go func() {
+		defer close(done)
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-clock.After(interval):
+				k.Evict()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// StopJanitor 停止后台清理go程并等待其退出，清理go程没有运行时直接返回
+func (k *KeyedLimiter) StopJanitor() {
+	k.m.Lock()
+	stop, done := k.stop, k.done
+	k.stop, k.done = nil, nil
+	k.m.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
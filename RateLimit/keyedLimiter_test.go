@@ -0,0 +1,112 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package RateLimit
+
+import (
+	"testing"
+	"time"
+)
+
+func newKeyedTokenBuckets(t *testing.T, idle time.Duration, clock Clock) *KeyedLimiter {
+	k, err := NewKeyedLimiter(func(key interface{}) Limiter {
+		b, err := NewTokenBucket(1, 1, clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}, idle, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return k
+}
+
+// 每个key有独立的限流器，超过idle没有使用的限流器被移除，再次使用时重新创建
+func TestKeyedLimiterEvictIdle(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	k := newKeyedTokenBuckets(t, time.Minute, clock)
+
+	if !k.Allow("a") || k.Allow("a") {
+		t.Fatal("limiter for a did not allow exactly one event")
+	}
+	if !k.Allow("b") {
+		t.Fatal("limiter for b shares state with a")
+	}
+	a := k.Get("a")
+
+	clock.Advance(30 * time.Second)
+	k.Get("b")
+	clock.Advance(30 * time.Second)
+	if n := k.Evict(); n != 1 {
+		t.Fatalf("Evict() = %d, want 1", n)
+	}
+	if n := k.Len(); n != 1 {
+		t.Fatalf("Len() = %d, want 1", n)
+	}
+	if k.Get("a") == a {
+		t.Fatal("evicted limiter was reused")
+	}
+
+	if !k.Remove("b") || k.Remove("b") {
+		t.Fatal("Remove(b) did not report the key exactly once")
+	}
+}
+
+// idle为0时从不移除
+func TestKeyedLimiterNoIdle(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	k := newKeyedTokenBuckets(t, 0, clock)
+
+	k.Get("a")
+	clock.Advance(24 * time.Hour)
+	if n := k.Evict(); n != 0 || k.Len() != 1 {
+		t.Fatalf("Evict() = %d, Len() = %d, want 0, 1", n, k.Len())
+	}
+}
+
+// 后台清理go程按照注入的时钟定时调用Evict
+func TestKeyedLimiterJanitor(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	k := newKeyedTokenBuckets(t, time.Minute, clock)
+	k.Get("a")
+
+	if err := k.StartJanitor(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.StartJanitor(10 * time.Second); err == nil {
+		t.Fatal("second StartJanitor succeeded")
+	}
+	waitUntil(t, func() bool { return clock.Waiters() == 1 })
+
+	clock.Advance(10 * time.Second)
+	waitUntil(t, func() bool { return clock.Waiters() == 1 })
+	if n := k.Len(); n != 1 {
+		t.Fatalf("Len() = %d before the limiter was idle, want 1", n)
+	}
+
+	clock.Advance(time.Minute)
+	waitUntil(t, func() bool { return k.Len() == 0 })
+
+	k.StopJanitor()
+	k.StopJanitor()
+	if err := k.StartJanitor(10 * time.Second); err != nil {
+		t.Fatalf("StartJanitor after StopJanitor = %v", err)
+	}
+	k.StopJanitor()
+}
@@ -0,0 +1,170 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package RateLimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// LeakyBucket 漏桶，事件进入桶中排队，以rate个每秒的速度匀速流出，桶中最多排队capacity个事件
+// 与令牌桶不同，漏桶不允许突发，相邻两个事件之间至少间隔1/rate秒
+type LeakyBucket struct {
+	m        sync.Mutex
+	clock    Clock
+	interval time.Duration
+	capacity int
+
+	// next 桶中已有的事件全部流出的时间，早于当前时间时桶为空
+	next time.Time
+}
+
+// NewLeakyBucket 返回一个空的漏桶，clock为nil时使用系统时间
+func NewLeakyBucket(rate float64, capacity int, clock Clock) (*LeakyBucket, error) {
+	if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+		return nil, errors.New("Rate must be positive.")
+	}
+	if capacity <= 0 {
+		return nil, errors.New("Capacity must be positive.")
+	}
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	return &LeakyBucket{
+		clock:    clock,
+		interval: durationOf(1 / rate),
+		capacity: capacity,
+		next:     clock.Now(),
+	}, nil
+}
+
+// queued 返回now时刻桶中排队的事件数量，调用时必须持有锁
+func (b *LeakyBucket) queued(now time.Time) int {
+	if !b.next.After(now) {
+		return 0
+	}
+
+	return int((b.next.Sub(now) + b.interval - 1) / b.interval)
+}
+
+// take 将n个事件放入桶中，返回第一个事件流出前需要等待的时间，桶中放不下时返回false，调用时必须持有锁
+func (b *LeakyBucket) take(now time.Time, n int) (time.Duration, bool) {
+	if n <= 0 || b.queued(now)+n > b.capacity {
+		return 0, false
+	}
+
+	if b.next.Before(now) {
+		b.next = now
+	}
+	wait := b.next.Sub(now)
+	b.next = b.next.Add(time.Duration(n) * b.interval)
+
+	return wait, true
+}
+
+func (b *LeakyBucket) Allow() bool {
+	return b.AllowN(1)
+}
+
+// AllowN 桶为空时允许n个事件立即流出，之后的事件需要等待它们流完
+func (b *LeakyBucket) AllowN(n int) bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	now := b.clock.Now()
+	if b.next.After(now) {
+		return false
+	}
+	_, ok := b.take(now, n)
+
+	return ok
+}
+
+// Take 将n个事件放入桶中，返回需要等待的时间，桶满或者n不是正数时返回false
+func (b *LeakyBucket) Take(n int) (time.Duration, bool) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	return b.take(b.clock.Now(), n)
+}
+
+// roomAfter 返回桶中能够再放下n个事件之前需要等待的时间，调用时必须持有锁
+func (b *LeakyBucket) roomAfter(now time.Time, n int) time.Duration {
+	return b.next.Add(-time.Duration(b.capacity-n) * b.interval).Sub(now)
+}
+
+// Wait 等待桶中能够放下n个事件，放入后再等待轮到它们
+// ctx被取消时，如果这些事件仍在队尾就把它们移出桶，否则它们占用的位置不会归还
+func (b *LeakyBucket) Wait(ctx context.Context, n int) error {
+	if n <= 0 {
+		return errors.New("N must be positive.")
+	}
+	if n > b.capacity {
+		return errors.New("N exceeds the bucket capacity.")
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		b.m.Lock()
+		now := b.clock.Now()
+		wait, ok := b.take(now, n)
+		end := b.next
+		if !ok {
+			wait = b.roomAfter(now, n)
+		}
+		b.m.Unlock()
+
+		if !ok {
+			// 桶满时等待前面的事件流出，醒来后重新尝试放入
+			if err := sleep(ctx, b.clock, wait); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := sleep(ctx, b.clock, wait); err != nil {
+			b.m.Lock()
+			if b.next.Equal(end) {
+				b.next = b.next.Add(-time.Duration(n) * b.interval)
+			}
+			b.m.Unlock()
+			return err
+		}
+
+		return nil
+	}
+}
+
+// Len 返回桶中正在排队的事件数量
+func (b *LeakyBucket) Len() int {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	return b.queued(b.clock.Now())
+}
+
+func (b *LeakyBucket) Capacity() int {
+	return b.capacity
+}
@@ -0,0 +1,164 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package RateLimit
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// waitUntil 等待cond成立，超时则测试失败
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		runtime.Gosched()
+	}
+}
+
+// waitAsync 在新的go程中调用l.Wait，返回接收结果的通道
+func waitAsync(l Limiter, ctx context.Context, n int) <-chan error {
+	ch := make(chan error, 1)
+	go func() {
+		ch <- l.Wait(ctx, n)
+	}()
+
+	return ch
+}
+
+// 事件以固定间隔流出，桶满后不再接受新的事件，流出之后腾出位置
+func TestLeakyBucketSpacing(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	b, err := NewLeakyBucket(10, 3, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !b.Allow() {
+		t.Fatal("Allow() on an empty bucket = false")
+	}
+	if b.Allow() {
+		t.Fatal("Allow() right after an event = true, want no bursts")
+	}
+	for _, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond} {
+		if wait, ok := b.Take(1); !ok || wait != want {
+			t.Fatalf("Take(1) = %v, %v, want %v, true", wait, ok, want)
+		}
+	}
+	if _, ok := b.Take(1); ok {
+		t.Fatal("Take(1) on a full bucket succeeded")
+	}
+	if n := b.Len(); n != 3 {
+		t.Fatalf("Len() = %d, want 3", n)
+	}
+
+	clock.Advance(150 * time.Millisecond)
+	if n := b.Len(); n != 2 {
+		t.Fatalf("Len() after 150ms = %d, want 2", n)
+	}
+	if wait, ok := b.Take(1); !ok || wait != 150*time.Millisecond {
+		t.Fatalf("Take(1) after 150ms = %v, %v, want 150ms, true", wait, ok)
+	}
+}
+
+// 桶满时Wait等待前面的事件流出，放入之后再等待轮到自己
+func TestLeakyBucketWaitForRoom(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	b, err := NewLeakyBucket(10, 2, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, ok := b.Take(1); !ok {
+			t.Fatal("Take(1) failed before the bucket was full")
+		}
+	}
+
+	done := waitAsync(b, context.Background(), 1)
+	waitUntil(t, func() bool { return clock.Waiters() == 1 })
+	select {
+	case err := <-done:
+		t.Fatalf("Wait() on a full bucket returned %v", err)
+	default:
+	}
+
+	// 第一个事件流出后放入桶中，还要等第二个事件流出
+	clock.Advance(100 * time.Millisecond)
+	waitUntil(t, func() bool { return clock.Waiters() == 1 && b.Len() == 2 })
+	select {
+	case err := <-done:
+		t.Fatalf("Wait() returned %v before its turn", err)
+	default:
+	}
+
+	clock.Advance(100 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+
+	if err := b.Wait(context.Background(), 3); err == nil {
+		t.Fatal("Wait(3) on a bucket of capacity 2 succeeded")
+	}
+}
+
+// 取消等待的调用返回ctx.Err()，仍在队尾的事件被移出桶
+func TestLeakyBucketWaitCancel(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	b, err := NewLeakyBucket(10, 2, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.Allow() {
+		t.Fatal("Allow() on an empty bucket = false")
+	}
+
+	// 已经放入桶中正在等待轮到自己
+	ctx, cancel := context.WithCancel(context.Background())
+	queued := waitAsync(b, ctx, 1)
+	waitUntil(t, func() bool { return clock.Waiters() == 1 })
+	if n := b.Len(); n != 2 {
+		t.Fatalf("Len() = %d, want 2", n)
+	}
+	cancel()
+	if err := <-queued; err != context.Canceled {
+		t.Fatalf("Wait() = %v, want context.Canceled", err)
+	}
+	if n := b.Len(); n != 1 {
+		t.Fatalf("Len() after cancel = %d, want 1", n)
+	}
+
+	// 桶满时正在等待位置
+	if _, ok := b.Take(1); !ok {
+		t.Fatal("Take(1) failed")
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	full := waitAsync(b, ctx, 1)
+	waitUntil(t, func() bool { return clock.Waiters() == 1 })
+	cancel()
+	if err := <-full; err != context.Canceled {
+		t.Fatalf("Wait() = %v, want context.Canceled", err)
+	}
+	if n := b.Len(); n != 2 {
+		t.Fatalf("Len() after cancel = %d, want 2", n)
+	}
+}
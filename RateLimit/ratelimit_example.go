@@ -0,0 +1,103 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package RateLimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func TokenBucketExample() {
+	clock := NewManualClock(time.Now())
+	b, err := NewTokenBucket(10, 5, clock)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// 桶满时允许突发5个事件
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if b.Allow() {
+			allowed++
+		}
+	}
+	fmt.Println("allowed =", allowed)
+
+	// 预约从未来借用令牌，每个令牌需要等待100ms
+	r := b.ReserveN(2)
+	fmt.Println(r.OK(), r.Delay())
+	r.Cancel()
+
+	clock.Advance(300 * time.Millisecond)
+	fmt.Println(b.Wait(context.Background(), 3))
+}
+
+func LeakyBucketExample() {
+	clock := NewManualClock(time.Now())
+	b, err := NewLeakyBucket(10, 3, clock)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// 事件匀速流出，每个事件间隔100ms
+	for i := 0; i < 4; i++ {
+		wait, ok := b.Take(1)
+		fmt.Println(wait, ok)
+	}
+
+	clock.Advance(100 * time.Millisecond)
+	fmt.Println(b.Len())
+}
+
+func SlidingWindowLogExample() {
+	clock := NewManualClock(time.Now())
+	w, err := NewSlidingWindowLog(3, time.Second, clock)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(w.Allow(), w.Allow())
+	clock.Advance(500 * time.Millisecond)
+	fmt.Println(w.Allow(), w.Allow())
+
+	// 最早的两个事件离开窗口之后才能继续
+	clock.Advance(500 * time.Millisecond)
+	fmt.Println(w.Allow(), w.Len())
+}
+
+func KeyedLimiterExample() {
+	clock := NewManualClock(time.Now())
+	k, err := NewKeyedLimiter(func(key interface{}) Limiter {
+		b, _ := NewTokenBucket(1, 2, clock)
+		return b
+	}, time.Minute, clock)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// 每个租户有自己的令牌桶
+	fmt.Println(k.Allow("a"), k.Allow("a"), k.Allow("a"), k.Allow("b"))
+
+	clock.Advance(2 * time.Minute)
+	fmt.Println(k.Evict(), k.Len())
+}
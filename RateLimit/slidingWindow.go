@@ -0,0 +1,144 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package RateLimit
+
+import (
+	"GTL/Queue"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// SlidingWindowLog 滑动窗口日志，任意长度为window的时间段内最多允许limit个事件
+// 每个被允许的事件的时间都按顺序记录在队列中，判断时先把窗口之外的记录从队首移除。
+// 精确但内存与limit成正比，适合limit较小的场景
+type SlidingWindowLog struct {
+	m      sync.Mutex
+	clock  Clock
+	window time.Duration
+	limit  int
+	log    Queue.Queue
+}
+
+// NewSlidingWindowLog 返回一个空的滑动窗口，clock为nil时使用系统时间
+func NewSlidingWindowLog(limit int, window time.Duration, clock Clock) (*SlidingWindowLog, error) {
+	if limit <= 0 {
+		return nil, errors.New("Limit must be positive.")
+	}
+	if window <= 0 {
+		return nil, errors.New("Window must be positive.")
+	}
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	log, err := Queue.NewUnsafeQueue(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SlidingWindowLog{
+		clock:  clock,
+		window: window,
+		limit:  limit,
+		log:    log,
+	}, nil
+}
+
+// evict 移除窗口之外的记录，调用时必须持有锁
+func (w *SlidingWindowLog) evict(now time.Time) {
+	start := now.Add(-w.window)
+	for !w.log.Empty() {
+		front, _ := w.log.Front()
+		if front.(time.Time).After(start) {
+			return
+		}
+		_, _ = w.log.Pop()
+	}
+}
+
+// tryTake 窗口中还能容纳n个事件时记录它们并返回0，否则返回最早的记录离开窗口需要等待的时间，调用时必须持有锁
+func (w *SlidingWindowLog) tryTake(n int) (time.Duration, bool) {
+	now := w.clock.Now()
+	w.evict(now)
+
+	if w.log.Size()+n <= w.limit {
+		for i := 0; i < n; i++ {
+			_ = w.log.Push(now)
+		}
+		return 0, true
+	}
+
+	front, _ := w.log.Front()
+
+	return front.(time.Time).Add(w.window).Sub(now), false
+}
+
+func (w *SlidingWindowLog) Allow() bool {
+	return w.AllowN(1)
+}
+
+func (w *SlidingWindowLog) AllowN(n int) bool {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	if n <= 0 || n > w.limit {
+		return false
+	}
+	_, ok := w.tryTake(n)
+
+	return ok
+}
+
+// Wait 等待直到窗口中能够容纳n个事件
+func (w *SlidingWindowLog) Wait(ctx context.Context, n int) error {
+	if n <= 0 {
+		return errors.New("N must be positive.")
+	}
+	if n > w.limit {
+		return errors.New("N exceeds the limit.")
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		w.m.Lock()
+		wait, ok := w.tryTake(n)
+		w.m.Unlock()
+		if ok {
+			return nil
+		}
+
+		if err := sleep(ctx, w.clock, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Len 返回当前窗口中的事件数量
+func (w *SlidingWindowLog) Len() int {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	w.evict(w.clock.Now())
+
+	return w.log.Size()
+}
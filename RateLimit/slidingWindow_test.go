@@ -0,0 +1,103 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package RateLimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// 窗口内最多允许limit个事件，最早的记录离开窗口后腾出额度
+func TestSlidingWindowLogLimit(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	w, err := NewSlidingWindowLog(3, time.Second, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !w.AllowN(2) {
+		t.Fatal("AllowN(2) on an empty window = false")
+	}
+	clock.Advance(500 * time.Millisecond)
+	if !w.Allow() {
+		t.Fatal("Allow() with one slot left = false")
+	}
+	if w.Allow() || w.AllowN(0) || w.AllowN(4) {
+		t.Fatal("full window, n = 0 or n > limit was allowed")
+	}
+
+	// 前两条记录离开窗口
+	clock.Advance(500 * time.Millisecond)
+	if n := w.Len(); n != 1 {
+		t.Fatalf("Len() = %d, want 1", n)
+	}
+	if !w.AllowN(2) || w.Allow() {
+		t.Fatal("window did not free exactly the expired slots")
+	}
+}
+
+// Wait等待最早的记录离开窗口，ctx被取消时不占用额度
+func TestSlidingWindowLogWait(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	w, err := NewSlidingWindowLog(2, time.Second, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.Allow() {
+		t.Fatal("Allow() on an empty window = false")
+	}
+	clock.Advance(300 * time.Millisecond)
+	if !w.Allow() {
+		t.Fatal("Allow() with one slot left = false")
+	}
+
+	done := waitAsync(w, context.Background(), 2)
+	waitUntil(t, func() bool { return clock.Waiters() == 1 })
+
+	// 第一条记录离开窗口后仍然放不下两个事件，继续等待第二条
+	clock.Advance(700 * time.Millisecond)
+	waitUntil(t, func() bool { return clock.Waiters() == 1 })
+	select {
+	case err := <-done:
+		t.Fatalf("Wait(2) returned %v with one slot free", err)
+	default:
+	}
+	clock.Advance(300 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatalf("Wait(2) = %v", err)
+	}
+	if n := w.Len(); n != 2 {
+		t.Fatalf("Len() = %d, want 2", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := waitAsync(w, ctx, 1)
+	waitUntil(t, func() bool { return clock.Waiters() == 1 })
+	cancel()
+	if err := <-cancelled; err != context.Canceled {
+		t.Fatalf("Wait() = %v, want context.Canceled", err)
+	}
+	clock.Advance(time.Second)
+	if n := w.Len(); n != 0 {
+		t.Fatalf("Len() = %d after the window passed, want 0", n)
+	}
+	if err := w.Wait(context.Background(), 3); err == nil {
+		t.Fatal("Wait(3) with limit 2 succeeded")
+	}
+}
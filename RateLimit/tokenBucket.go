@@ -0,0 +1,224 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package RateLimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// TokenBucket 令牌桶，以rate个每秒的速度向桶中放入令牌，桶中最多有burst个令牌，每个事件消耗一个令牌
+// 桶满时允许一次突发burst个事件，长期来看事件的速率不超过rate
+type TokenBucket struct {
+	m     sync.Mutex
+	clock Clock
+	rate  float64
+	burst int
+
+	// tokens 在last时刻桶中的令牌数量，有预约时可以为负数
+	tokens float64
+	last   time.Time
+
+	// lastEvent 最近一次预约的执行时间，取消预约时用来计算有多少令牌没有被之后的预约使用
+	lastEvent time.Time
+}
+
+// NewTokenBucket 返回一个装满令牌的令牌桶，clock为nil时使用系统时间
+func NewTokenBucket(rate float64, burst int, clock Clock) (*TokenBucket, error) {
+	if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+		return nil, errors.New("Rate must be positive.")
+	}
+	if burst <= 0 {
+		return nil, errors.New("Burst must be positive.")
+	}
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	return &TokenBucket{
+		clock:  clock,
+		rate:   rate,
+		burst:  burst,
+		tokens: float64(burst),
+		last:   clock.Now(),
+	}, nil
+}
+
+// advance 补充从last到now的令牌，调用时必须持有锁
+func (b *TokenBucket) advance(now time.Time) {
+	if now.After(b.last) {
+		b.tokens = math.Min(float64(b.burst), b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+func (b *TokenBucket) Allow() bool {
+	return b.AllowN(1)
+}
+
+func (b *TokenBucket) AllowN(n int) bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if n <= 0 {
+		return false
+	}
+
+	now := b.clock.Now()
+	b.advance(now)
+	if b.tokens < float64(n) {
+		return false
+	}
+	b.tokens -= float64(n)
+	b.lastEvent = now
+
+	return true
+}
+
+// Reservation 预约的令牌，到达预约时间后才能执行事件
+type Reservation struct {
+	b         *TokenBucket
+	ok        bool
+	n         int
+	timeToAct time.Time
+	cancelled bool
+}
+
+// OK 预约是否成功，n不是正数或者大于burst时永远不会成功
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay 返回距离可以执行事件还需要等待的时间
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return time.Duration(math.MaxInt64)
+	}
+
+	d := r.timeToAct.Sub(r.b.clock.Now())
+	if d < 0 {
+		return 0
+	}
+
+	return d
+}
+
+// Cancel 放弃预约，到达预约时间之前放弃时归还令牌
+// 之后的预约已经排在这个预约的后面，它们借用的令牌不会归还，只归还没有被之后的预约使用的部分
+func (r *Reservation) Cancel() {
+	if !r.ok {
+		return
+	}
+
+	b := r.b
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	now := b.clock.Now()
+	if r.cancelled || !now.Before(r.timeToAct) {
+		return
+	}
+	r.cancelled = true
+
+	// 从timeToAct到lastEvent之间补充的令牌已经被之后的预约占用
+	restore := float64(r.n) - b.lastEvent.Sub(r.timeToAct).Seconds()*b.rate
+	if restore <= 0 {
+		return
+	}
+
+	b.advance(now)
+	b.tokens = math.Min(float64(b.burst), b.tokens+restore)
+
+	// 取消的是最后一个预约时，把lastEvent退回到它之前的预约
+	if r.timeToAct.Equal(b.lastEvent) {
+		prev := r.timeToAct.Add(-durationOf(float64(r.n) / b.rate))
+		if !prev.Before(now) {
+			b.lastEvent = prev
+		}
+	}
+}
+
+// Reserve 等价于ReserveN(1)
+func (b *TokenBucket) Reserve() *Reservation {
+	return b.ReserveN(1)
+}
+
+// ReserveN 预约n个令牌，令牌不足时从未来借用，返回的预约说明需要等待多久
+// 调用者应该等待Delay之后再执行事件，或者调用Cancel放弃
+func (b *TokenBucket) ReserveN(n int) *Reservation {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	now := b.clock.Now()
+	if n <= 0 || n > b.burst {
+		return &Reservation{b: b, ok: false, n: n}
+	}
+
+	b.advance(now)
+	b.tokens -= float64(n)
+
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = durationOf(-b.tokens / b.rate)
+	}
+	b.lastEvent = now.Add(wait)
+
+	return &Reservation{b: b, ok: true, n: n, timeToAct: b.lastEvent}
+}
+
+// Wait 等待直到桶中有n个令牌，ctx被取消时归还预约的令牌
+func (b *TokenBucket) Wait(ctx context.Context, n int) error {
+	if n <= 0 {
+		return errors.New("N must be positive.")
+	}
+	if n > b.burst {
+		return errors.New("N exceeds the burst size.")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r := b.ReserveN(n)
+	if err := sleep(ctx, b.clock, r.Delay()); err != nil {
+		r.Cancel()
+		return err
+	}
+
+	return nil
+}
+
+// Tokens 返回当前桶中的令牌数量，有预约时可以为负数
+func (b *TokenBucket) Tokens() float64 {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.advance(b.clock.Now())
+
+	return b.tokens
+}
+
+func (b *TokenBucket) Rate() float64 {
+	return b.rate
+}
+
+func (b *TokenBucket) Burst() int {
+	return b.burst
+}
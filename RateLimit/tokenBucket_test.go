@@ -0,0 +1,108 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package RateLimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// n不是正数时不允许也不预约，桶中的令牌不变
+func TestTokenBucketRejectsNonPositiveN(t *testing.T) {
+	b, err := NewTokenBucket(1, 5, NewManualClock(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, n := range []int{0, -1, -100} {
+		if b.AllowN(n) {
+			t.Fatalf("AllowN(%d) = true", n)
+		}
+		if r := b.ReserveN(n); r.OK() {
+			t.Fatalf("ReserveN(%d).OK() = true", n)
+		}
+		if err := b.Wait(context.Background(), n); err == nil {
+			t.Fatalf("Wait(%d) succeeded", n)
+		}
+	}
+	if tokens := b.Tokens(); tokens != 5 {
+		t.Fatalf("Tokens() = %v, want 5", tokens)
+	}
+}
+
+// 取消预约只归还没有被之后的预约占用的令牌
+func TestTokenBucketCancelRefundsUnusedTokens(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	b, err := NewTokenBucket(1, 10, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.AllowN(10) {
+		t.Fatal("AllowN(10) on a full bucket = false")
+	}
+
+	r1 := b.ReserveN(5)
+	r2 := b.ReserveN(5)
+	if r1.Delay() != 5*time.Second || r2.Delay() != 10*time.Second {
+		t.Fatalf("delays = %v, %v, want 5s, 10s", r1.Delay(), r2.Delay())
+	}
+
+	// r2排在r1之后，r1的令牌已经被r2占用，取消r1不归还令牌
+	r1.Cancel()
+	if tokens := b.Tokens(); tokens != -10 {
+		t.Fatalf("Tokens() after cancelling r1 = %v, want -10", tokens)
+	}
+	if r3 := b.ReserveN(5); r3.Delay() != 15*time.Second {
+		t.Fatalf("reservation after r1 was cancelled waits %v, want 15s", r3.Delay())
+	}
+}
+
+// 从后往前取消预约时依次归还全部令牌，到达预约时间之后取消不归还
+func TestTokenBucketCancelInReverseOrder(t *testing.T) {
+	clock := NewManualClock(time.Now())
+	b, err := NewTokenBucket(1, 10, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.AllowN(10) {
+		t.Fatal("AllowN(10) on a full bucket = false")
+	}
+
+	r1 := b.ReserveN(5)
+	r2 := b.ReserveN(5)
+	r2.Cancel()
+	if tokens := b.Tokens(); tokens != -5 {
+		t.Fatalf("Tokens() after cancelling r2 = %v, want -5", tokens)
+	}
+	r1.Cancel()
+	if tokens := b.Tokens(); tokens != 0 {
+		t.Fatalf("Tokens() after cancelling r1 = %v, want 0", tokens)
+	}
+	r1.Cancel()
+	if tokens := b.Tokens(); tokens != 0 {
+		t.Fatalf("Tokens() after cancelling r1 twice = %v, want 0", tokens)
+	}
+
+	r := b.ReserveN(2)
+	clock.Advance(2 * time.Second)
+	r.Cancel()
+	if tokens := b.Tokens(); tokens != 0 {
+		t.Fatalf("Tokens() after cancelling a due reservation = %v, want 0", tokens)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package STM
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// 软件事务内存
+// 事务开始时记录全局版本号，事务内读到版本号更大的TVar说明它在事务开始后被修改过，事务立即重新执行，
+// 因此事务内看到的总是某一时刻一致的数据；写操作只记录在事务内部，提交时检查读过的TVar没有被修改，
+// 再一次性写入所有修改并增加全局版本号，检查失败的事务会重新执行。
+// 提交是串行的，但事务的执行过程不加锁，互不等待，多个事务任意组合也不需要考虑加锁顺序。
+
+var (
+	// clock 全局版本号，每次提交写事务加一
+	clock uint64
+
+	// commitMu 串行化提交，commitCond 在每次提交后唤醒调用了Retry的事务
+	commitMu   sync.Mutex
+	commitCond = sync.NewCond(&commitMu)
+)
+
+// conflict 事务读到了不一致的数据，需要重新执行
+type conflict struct{}
+
+// retry 事务调用了Retry，需要等待读过的数据发生变化后重新执行
+type retry struct{}
+
+// Atomically 原子地执行事务f
+// f返回nil时提交事务，返回错误时放弃事务中的所有修改并返回该错误；
+// f可能被执行多次，除了读写TVar以外不应该有其他副作用
+func Atomically(f func(tx *Tx) error) error {
+	for {
+		tx := newTx()
+
+		err, signal := run(tx, f)
+		switch signal.(type) {
+		case conflict:
+			continue
+		case retry:
+			if len(tx.reads) == 0 {
+				return errors.New("Retry is called without reading any TVar.")
+			}
+			tx.wait()
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if tx.commit() {
+			return nil
+		}
+	}
+}
+
+// run 执行f，捕获表示冲突和Retry的panic，其它panic原样抛出
+func run(tx *Tx, f func(tx *Tx) error) (err error, signal interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch r.(type) {
+			case conflict, retry:
+				signal = r
+			default:
+				panic(r)
+			}
+		}
+	}()
+
+	return f(tx), nil
+}
+
+func loadClock() uint64 {
+	return atomic.LoadUint64(&clock)
+}
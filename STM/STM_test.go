@@ -0,0 +1,322 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package STM
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitUntil 等待cond成立，超时则测试失败
+func waitUntil(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		runtime.Gosched()
+	}
+}
+
+func setTVar(v *TVar, value interface{}) {
+	_ = Atomically(func(tx *Tx) error {
+		tx.Set(v, value)
+		return nil
+	})
+}
+
+// 提交时发现读过的TVar已被其他事务修改，事务重新执行并基于新值提交
+func TestAtomicallyCommitConflict(t *testing.T) {
+	a, b := NewTVar(1), NewTVar(0)
+
+	runs := 0
+	err := Atomically(func(tx *Tx) error {
+		runs++
+		value := tx.Get(a).(int)
+		if runs == 1 {
+			// 读过a之后另一个事务修改了a
+			setTVar(a, 10)
+		}
+		tx.Set(b, value)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs != 2 {
+		t.Fatalf("transaction ran %d times, want 2", runs)
+	}
+	if got := b.Load(); got != 10 {
+		t.Fatalf("b = %v, want the value committed by the other transaction", got)
+	}
+}
+
+// 事务开始后被修改过的TVar在读取时就触发重新执行，事务不会看到新旧混合的数据
+func TestAtomicallyReadConflict(t *testing.T) {
+	a, b := NewTVar(1), NewTVar(1)
+
+	runs := 0
+	var seen []int
+	err := Atomically(func(tx *Tx) error {
+		runs++
+		x := tx.Get(a).(int)
+		if runs == 1 {
+			_ = Atomically(func(tx *Tx) error {
+				tx.Set(a, 2)
+				tx.Set(b, 2)
+				return nil
+			})
+		}
+		seen = append(seen, x, tx.Get(b).(int))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs != 2 || !reflect.DeepEqual(seen, []int{2, 2}) {
+		t.Fatalf("runs = %d, seen = %v, want 2 runs seeing [2 2]", runs, seen)
+	}
+}
+
+// 并发转账时任何事务看到的总额都不变
+func TestAtomicallyConcurrentTransfers(t *testing.T) {
+	const (
+		accounts = 4
+		initial  = 100
+		workers  = 8
+		rounds   = 200
+	)
+	vars := make([]*TVar, accounts)
+	for i := range vars {
+		vars[i] = NewTVar(initial)
+	}
+	total := func(tx *Tx) int {
+		sum := 0
+		for _, v := range vars {
+			sum += tx.Get(v).(int)
+		}
+		return sum
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		w := w
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				from, to := vars[(w+i)%accounts], vars[(w+i+1)%accounts]
+				_ = Atomically(func(tx *Tx) error {
+					tx.Set(from, tx.Get(from).(int)-1)
+					tx.Set(to, tx.Get(to).(int)+1)
+					return nil
+				})
+				_ = Atomically(func(tx *Tx) error {
+					if sum := total(tx); sum != accounts*initial {
+						t.Errorf("transaction saw total %d, want %d", sum, accounts*initial)
+					}
+					return nil
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	sum := 0
+	for _, v := range vars {
+		sum += v.Load().(int)
+	}
+	if sum != accounts*initial {
+		t.Fatalf("final total = %d, want %d", sum, accounts*initial)
+	}
+}
+
+// Retry阻塞到读过的TVar被修改，修改没有读过的TVar不会让事务重新执行
+func TestRetryWaitsForReadVar(t *testing.T) {
+	ready, other := NewTVar(false), NewTVar(0)
+
+	var runs int32
+	done := make(chan error, 1)
+	go func() {
+		done <- Atomically(func(tx *Tx) error {
+			atomic.AddInt32(&runs, 1)
+			if !tx.Get(ready).(bool) {
+				tx.Retry()
+			}
+			return nil
+		})
+	}()
+	waitUntil(t, func() bool { return atomic.LoadInt32(&runs) == 1 })
+
+	setTVar(other, 1)
+	select {
+	case err := <-done:
+		t.Fatalf("Atomically returned %v before ready was set", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Fatalf("unrelated commit reran the transaction: %d runs", n)
+	}
+
+	setTVar(ready, true)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&runs); n != 2 {
+		t.Fatalf("transaction ran %d times, want 2", n)
+	}
+
+	err := Atomically(func(tx *Tx) error {
+		tx.Retry()
+		return nil
+	})
+	if err == nil {
+		t.Fatal("Retry without reading any TVar succeeded")
+	}
+}
+
+// TQueue的Take在队列为空时阻塞，Put在队列满时阻塞
+func TestTQueueTakeAndPutBlock(t *testing.T) {
+	q, err := NewTQueue(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	taken := make(chan interface{}, 1)
+	go func() {
+		var value interface{}
+		_ = Atomically(func(tx *Tx) error {
+			value = q.Take(tx)
+			return nil
+		})
+		taken <- value
+	}()
+	select {
+	case value := <-taken:
+		t.Fatalf("Take on an empty queue returned %v", value)
+	case <-time.After(10 * time.Millisecond):
+	}
+	_ = Atomically(func(tx *Tx) error {
+		q.Put(tx, "job")
+		return nil
+	})
+	if value := <-taken; value != "job" {
+		t.Fatalf("Take() = %v, want job", value)
+	}
+
+	_ = Atomically(func(tx *Tx) error {
+		q.Put(tx, 1)
+		return nil
+	})
+	put := make(chan struct{})
+	go func() {
+		_ = Atomically(func(tx *Tx) error {
+			q.Put(tx, 2)
+			return nil
+		})
+		close(put)
+	}()
+	select {
+	case <-put:
+		t.Fatal("Put on a full queue returned")
+	case <-time.After(10 * time.Millisecond):
+	}
+	_ = Atomically(func(tx *Tx) error {
+		q.Take(tx)
+		return nil
+	})
+	<-put
+}
+
+// 事务返回错误或者panic时，对TVar和所有事务容器的修改都被丢弃
+func TestAtomicallyRollback(t *testing.T) {
+	v := NewTVar(0)
+	q, err := NewTQueue(-1, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewTSet(-1, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vec, err := NewTVector(-1, 1, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modify := func(tx *Tx) {
+		tx.Set(v, 1)
+		_ = q.Push(tx, 3)
+		_, _ = q.Pop(tx)
+		_ = s.Insert(tx, "b")
+		s.Remove(tx, "a")
+		_ = vec.PushBack(tx, 4)
+		_ = vec.Set(tx, 0, 100)
+	}
+	check := func(when string) {
+		t.Helper()
+		_ = Atomically(func(tx *Tx) error {
+			if got := tx.Get(v); got != 0 {
+				t.Errorf("%s: TVar = %v, want 0", when, got)
+			}
+			if got := q.ToSlice(tx); !reflect.DeepEqual(got, []interface{}{1, 2}) {
+				t.Errorf("%s: TQueue = %v, want [1 2]", when, got)
+			}
+			if !s.Contains(tx, "a") || s.Contains(tx, "b") || s.Size(tx) != 1 {
+				t.Errorf("%s: TSet = %v, want [a]", when, s.ToSlice(tx))
+			}
+			if got := vec.ToSlice(tx); !reflect.DeepEqual(got, []interface{}{1, 2, 3}) {
+				t.Errorf("%s: TVector = %v, want [1 2 3]", when, got)
+			}
+			return nil
+		})
+	}
+
+	fail := errors.New("fail")
+	err = Atomically(func(tx *Tx) error {
+		modify(tx)
+		// 事务内可以看到自己的修改
+		if size := q.Size(tx); size != 2 || !s.Contains(tx, "b") || vec.Size(tx) != 4 {
+			t.Errorf("transaction does not see its own writes")
+		}
+		return fail
+	})
+	if err != fail {
+		t.Fatalf("Atomically() = %v, want %v", err, fail)
+	}
+	check("after error")
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Fatalf("recover() = %v, want boom", r)
+			}
+		}()
+		_ = Atomically(func(tx *Tx) error {
+			modify(tx)
+			panic("boom")
+		})
+	}()
+	check("after panic")
+}
@@ -0,0 +1,91 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package STM
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+func TVarExample() {
+	a, b := NewTVar(100), NewTVar(0)
+
+	transfer := func(from, to *TVar, amount int) error {
+		return Atomically(func(tx *Tx) error {
+			balance := tx.Get(from).(int)
+			if balance < amount {
+				return errors.New("insufficient balance")
+			}
+			tx.Set(from, balance-amount)
+			tx.Set(to, tx.Get(to).(int)+amount)
+			return nil
+		})
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = transfer(a, b, 10)
+		}()
+		go func() {
+			defer wg.Done()
+			_ = transfer(b, a, 5)
+		}()
+	}
+	wg.Wait()
+
+	// 总额始终为100
+	fmt.Println(a.Load().(int) + b.Load().(int))
+	fmt.Println(transfer(b, a, 1000))
+}
+
+func TQueueExample() {
+	jobs, _ := NewTQueue(2)
+	done, _ := NewTSet(-1)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 5; i++ {
+			// 队列为空时Take会阻塞到生产者入队，出队和记录结果在同一个事务中完成
+			_ = Atomically(func(tx *Tx) error {
+				job := jobs.Take(tx)
+				return done.Insert(tx, job)
+			})
+		}
+	}()
+
+	for i := 0; i < 5; i++ {
+		i := i
+		// 队列已满时Put会阻塞到消费者出队
+		_ = Atomically(func(tx *Tx) error {
+			jobs.Put(tx, i)
+			return nil
+		})
+	}
+	wg.Wait()
+
+	_ = Atomically(func(tx *Tx) error {
+		fmt.Println(done.Size(tx), jobs.Empty(tx))
+		return nil
+	})
+}
@@ -0,0 +1,101 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package STM
+
+import "GTL/Queue"
+
+// TQueue 事务队列，只能在Atomically中使用
+// 内部的队列保存在TVar中，每个事务第一次修改时复制整个队列，代价为O(n)，之后同一事务中的修改都作用在副本上，提交时整体替换；
+// 只读的事务不会复制，因此TQueue适合元素不多或者读多写少的场景
+type TQueue struct {
+	v *TVar
+}
+
+func NewTQueue(maxSize int, values ...interface{}) (*TQueue, error) {
+	return NewTQueueWithSlice(maxSize, values)
+}
+
+func NewTQueueWithSlice(maxSize int, values []interface{}) (*TQueue, error) {
+	uq, err := Queue.NewUnsafeQueueWithSlice(maxSize, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TQueue{v: NewTVar(uq)}, nil
+}
+
+func (q *TQueue) read(tx *Tx) Queue.Queue {
+	return tx.Get(q.v).(Queue.Queue)
+}
+
+// write 返回事务私有的队列副本，事务中第一次调用时复制全部元素
+func (q *TQueue) write(tx *Tx) Queue.Queue {
+	cur := q.read(tx)
+	if tx.written(q.v) {
+		return cur
+	}
+
+	c, _ := Queue.NewUnsafeQueueWithSlice(cur.MaxSize(), cur.ToSlice())
+	tx.Set(q.v, c)
+
+	return c
+}
+
+func (q *TQueue) Push(tx *Tx, value interface{}) error {
+	return q.write(tx).Push(value)
+}
+
+// Put 入队，队列已满时调用Retry等待其他事务出队
+func (q *TQueue) Put(tx *Tx, value interface{}) {
+	if q.read(tx).Fill() {
+		tx.Retry()
+	}
+
+	_ = q.write(tx).Push(value)
+}
+
+func (q *TQueue) Front(tx *Tx) (interface{}, error) {
+	return q.read(tx).Front()
+}
+
+func (q *TQueue) Pop(tx *Tx) (interface{}, error) {
+	return q.write(tx).Pop()
+}
+
+// Take 出队，队列为空时调用Retry等待其他事务入队
+func (q *TQueue) Take(tx *Tx) interface{} {
+	if q.read(tx).Empty() {
+		tx.Retry()
+	}
+
+	value, _ := q.write(tx).Pop()
+
+	return value
+}
+
+func (q *TQueue) Empty(tx *Tx) bool {
+	return q.read(tx).Empty()
+}
+
+func (q *TQueue) Size(tx *Tx) int {
+	return q.read(tx).Size()
+}
+
+func (q *TQueue) ToSlice(tx *Tx) []interface{} {
+	return q.read(tx).ToSlice()
+}
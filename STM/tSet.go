@@ -0,0 +1,83 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package STM
+
+import "GTL/Set"
+
+// TSet 事务集合，只能在Atomically中使用
+// 内部的集合保存在TVar中，每个事务第一次修改时复制整个集合，代价为O(n)，之后同一事务中的修改都作用在副本上，提交时整体替换；
+// 只读的事务不会复制，因此TSet适合元素不多或者读多写少的场景
+type TSet struct {
+	v *TVar
+}
+
+func NewTSet(maxSize int, values ...interface{}) (*TSet, error) {
+	return NewTSetWithSlice(maxSize, values)
+}
+
+func NewTSetWithSlice(maxSize int, values []interface{}) (*TSet, error) {
+	us, err := Set.NewUnsafeSetWithSlice(maxSize, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TSet{v: NewTVar(us)}, nil
+}
+
+func (s *TSet) read(tx *Tx) Set.Set {
+	return tx.Get(s.v).(Set.Set)
+}
+
+// write 返回事务私有的集合副本，事务中第一次调用时复制全部元素
+func (s *TSet) write(tx *Tx) Set.Set {
+	cur := s.read(tx)
+	if tx.written(s.v) {
+		return cur
+	}
+
+	c := cur.Clone()
+	tx.Set(s.v, c)
+
+	return c
+}
+
+func (s *TSet) Insert(tx *Tx, value interface{}) error {
+	return s.write(tx).Insert(value)
+}
+
+func (s *TSet) Remove(tx *Tx, value interface{}) {
+	if s.read(tx).Contains(value) {
+		s.write(tx).Remove(value)
+	}
+}
+
+func (s *TSet) Contains(tx *Tx, values ...interface{}) bool {
+	return s.read(tx).Contains(values...)
+}
+
+func (s *TSet) Empty(tx *Tx) bool {
+	return s.read(tx).Empty()
+}
+
+func (s *TSet) Size(tx *Tx) int {
+	return s.read(tx).Size()
+}
+
+func (s *TSet) ToSlice(tx *Tx) []interface{} {
+	return s.read(tx).ToSlice()
+}
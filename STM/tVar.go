@@ -0,0 +1,48 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package STM
+
+import "sync/atomic"
+
+// tVarState TVar在某个版本的值，创建后不再修改
+type tVarState struct {
+	value   interface{}
+	version uint64
+}
+
+// TVar 事务变量，只能在Atomically中通过Tx读写
+// 保存在TVar中的值会被多个事务共享，不能在事务中直接修改，需要修改时应该复制后再调用Tx.Set
+type TVar struct {
+	s atomic.Value
+}
+
+func NewTVar(value interface{}) *TVar {
+	v := &TVar{}
+	v.s.Store(&tVarState{value: value, version: 0})
+
+	return v
+}
+
+func (v *TVar) load() *tVarState {
+	return v.s.Load().(*tVarState)
+}
+
+// Load 不开启事务，直接读取当前已经提交的值
+func (v *TVar) Load() interface{} {
+	return v.load().value
+}
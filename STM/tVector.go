@@ -0,0 +1,93 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package STM
+
+import "GTL/Vector"
+
+// TVector 事务Vector，只能在Atomically中使用
+// 内部的Vector保存在TVar中，每个事务第一次修改时复制整个Vector，代价为O(n)，之后同一事务中的修改都作用在副本上，提交时整体替换；
+// 只读的事务不会复制，因此TVector适合元素不多或者读多写少的场景
+type TVector struct {
+	v *TVar
+}
+
+func NewTVector(maxSize int, values ...interface{}) (*TVector, error) {
+	return NewTVectorWithSlice(maxSize, values)
+}
+
+func NewTVectorWithSlice(maxSize int, values []interface{}) (*TVector, error) {
+	uv, err := Vector.NewUnsafeVectorWithSlice(maxSize, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TVector{v: NewTVar(uv)}, nil
+}
+
+func (v *TVector) read(tx *Tx) Vector.Vector {
+	return tx.Get(v.v).(Vector.Vector)
+}
+
+// write 返回事务私有的Vector副本，事务中第一次调用时复制全部元素
+func (v *TVector) write(tx *Tx) Vector.Vector {
+	cur := v.read(tx)
+	if tx.written(v.v) {
+		return cur
+	}
+
+	c, _ := Vector.NewUnsafeVectorWithSlice(cur.MaxSize(), cur.ToSlice())
+	tx.Set(v.v, c)
+
+	return c
+}
+
+func (v *TVector) PushBack(tx *Tx, value interface{}) error {
+	return v.write(tx).PushBack(value)
+}
+
+func (v *TVector) PopBack(tx *Tx) (interface{}, error) {
+	return v.write(tx).PopBack()
+}
+
+func (v *TVector) Set(tx *Tx, index, value int) error {
+	return v.write(tx).Set(index, value)
+}
+
+func (v *TVector) At(tx *Tx, index int) (interface{}, error) {
+	return v.read(tx).At(index)
+}
+
+func (v *TVector) Remove(tx *Tx, start, end int) error {
+	return v.write(tx).Remove(start, end)
+}
+
+func (v *TVector) Find(tx *Tx, value interface{}, less func(interface{}, interface{}) bool) int {
+	return v.read(tx).Find(value, less)
+}
+
+func (v *TVector) Empty(tx *Tx) bool {
+	return v.read(tx).Empty()
+}
+
+func (v *TVector) Size(tx *Tx) int {
+	return v.read(tx).Size()
+}
+
+func (v *TVector) ToSlice(tx *Tx) []interface{} {
+	return v.read(tx).ToSlice()
+}
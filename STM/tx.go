@@ -0,0 +1,117 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package STM
+
+import "sync/atomic"
+
+// Tx 一次事务执行过程中的读写记录，只能在传给Atomically的函数中使用
+type Tx struct {
+	// readVersion 事务开始时的全局版本号
+	readVersion uint64
+
+	// reads 读过的TVar及读到的版本号
+	reads map[*TVar]uint64
+
+	// writes 写过的TVar及写入的值
+	writes map[*TVar]interface{}
+}
+
+func newTx() *Tx {
+	return &Tx{
+		readVersion: loadClock(),
+		reads:       make(map[*TVar]uint64),
+		writes:      make(map[*TVar]interface{}),
+	}
+}
+
+// Get 读取v的值，事务中写过v时返回写入的值
+func (tx *Tx) Get(v *TVar) interface{} {
+	if value, ok := tx.writes[v]; ok {
+		return value
+	}
+
+	s := v.load()
+	if s.version > tx.readVersion {
+		panic(conflict{})
+	}
+	tx.reads[v] = s.version
+
+	return s.value
+}
+
+// Set 把v的值设置为value，提交后才对其他事务可见
+func (tx *Tx) Set(v *TVar, value interface{}) {
+	tx.writes[v] = value
+}
+
+// written 判断事务中是否写过v
+func (tx *Tx) written(v *TVar) bool {
+	_, ok := tx.writes[v]
+	return ok
+}
+
+// Retry 放弃本次执行，阻塞到事务读过的某个TVar被其他事务修改后重新执行
+// 通常用于等待条件成立，例如队列为空时等待其他事务入队
+func (tx *Tx) Retry() {
+	panic(retry{})
+}
+
+// changed 判断读过的TVar是否被修改过，调用时必须持有commitMu
+func (tx *Tx) changed() bool {
+	for v, version := range tx.reads {
+		if v.load().version != version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// wait 阻塞到读过的TVar被修改
+func (tx *Tx) wait() {
+	commitMu.Lock()
+	for !tx.changed() {
+		commitCond.Wait()
+	}
+	commitMu.Unlock()
+}
+
+// commit 检查读过的TVar没有被修改后写入所有修改，检查失败时返回false
+func (tx *Tx) commit() bool {
+	if len(tx.writes) == 0 {
+		// 只读事务读到的数据一定是一致的，不需要检查
+		return true
+	}
+
+	commitMu.Lock()
+	defer commitMu.Unlock()
+
+	if tx.changed() {
+		return false
+	}
+
+	// 先写入新值再增加全局版本号，读到新版本号的事务一定能看到这次提交的所有修改
+	version := clock + 1
+	for v, value := range tx.writes {
+		v.s.Store(&tVarState{value: value, version: version})
+	}
+	atomic.StoreUint64(&clock, version)
+	commitCond.Broadcast()
+
+	return true
+}
@@ -65,3 +65,45 @@ type Set interface {
 
 	Container.Container
 }
+
+// ReadOnlySet Set中不修改集合的方法
+type ReadOnlySet interface {
+	Contains(values ...interface{}) bool
+
+	// Equal 判断两个集合是否相等
+	Equal(other Set) bool
+
+	// IsProperSubset 判断other是否是该集合s的真子集
+	IsProperSubset(other Set) bool
+
+	// IsProperSuperset 判断other是否是该集合s的真超集
+	IsProperSuperset(other Set) bool
+
+	// IsSubset 判断other是否是该集合s的子集
+	IsSubset(other Set) bool
+
+	// IsSuperset 判断other是否是该集合s的超集
+	IsSuperset(other Set) bool
+
+	// Iter 返回一个可以遍历该集合s的通道
+	Iter() <-chan interface{}
+
+	// Iterator 返回该集合s的一个迭代器
+	Iterator() *Iterator
+
+	Fill() bool
+
+	Empty() bool
+
+	Size() int
+
+	MaxSize() int
+
+	String() string
+
+	// ToSlice 将集合按切片形式返回
+	ToSlice() []interface{}
+
+	// MarshalJSON 将集合中的所有元素以Json数组的形式返回
+	MarshalJSON() ([]byte, error)
+}
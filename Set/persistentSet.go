@@ -0,0 +1,277 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Set
+
+import (
+	"GTL/Map"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// persistentSet 不可变的集合，基于Map.PersistentMap实现
+// 实现了ReadOnlySet，修改操作返回新的集合，新旧集合共享没有修改的部分，可以被多个go程同时读取
+type persistentSet struct {
+	m *Map.PersistentMap
+}
+
+func NewPersistentSet(values ...interface{}) *persistentSet {
+	return NewPersistentSetWithSlice(values)
+}
+
+func NewPersistentSetWithSlice(values []interface{}) *persistentSet {
+	m := Map.NewPersistentMap()
+	for _, value := range values {
+		m = m.Store(value, struct{}{})
+	}
+
+	return &persistentSet{m: m}
+}
+
+// NewPersistentSetFromSet 复制other中的元素创建持久化集合
+func NewPersistentSetFromSet(other ReadOnlySet) *persistentSet {
+	if o, ok := other.(*persistentSet); ok {
+		return o
+	}
+
+	return NewPersistentSetWithSlice(other.ToSlice())
+}
+
+func (s *persistentSet) wrap(m *Map.PersistentMap) *persistentSet {
+	if m == s.m {
+		return s
+	}
+
+	return &persistentSet{m: m}
+}
+
+// Insert 返回加入value之后的集合，value已经存在时返回s本身
+func (s *persistentSet) Insert(value interface{}) *persistentSet {
+	return s.wrap(s.m.Store(value, struct{}{}))
+}
+
+// Remove 返回删除value之后的集合，value不存在时返回s本身
+func (s *persistentSet) Remove(value interface{}) *persistentSet {
+	return s.wrap(s.m.Delete(value))
+}
+
+func (s *persistentSet) Contains(values ...interface{}) bool {
+	for _, value := range values {
+		if _, ok := s.m.Load(value); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Union 返回s和other的并集
+func (s *persistentSet) Union(other ReadOnlySet) *persistentSet {
+	big, small := s, NewPersistentSetFromSet(other)
+	if small.Size() > big.Size() {
+		big, small = small, big
+	}
+
+	m := big.m
+	small.m.Range(func(key, _ interface{}) bool {
+		m = m.Store(key, struct{}{})
+		return true
+	})
+
+	return big.wrap(m)
+}
+
+// Intersect 返回s和other的交集
+func (s *persistentSet) Intersect(other ReadOnlySet) *persistentSet {
+	m := s.m
+	s.m.Range(func(key, _ interface{}) bool {
+		if !other.Contains(key) {
+			m = m.Delete(key)
+		}
+		return true
+	})
+
+	return s.wrap(m)
+}
+
+// Difference 返回s - other差集
+func (s *persistentSet) Difference(other ReadOnlySet) *persistentSet {
+	m := s.m
+	for _, value := range other.ToSlice() {
+		m = m.Delete(value)
+	}
+
+	return s.wrap(m)
+}
+
+// SymmetricDifference 返回s和other的对称差集
+func (s *persistentSet) SymmetricDifference(other ReadOnlySet) *persistentSet {
+	m := s.m
+	for _, value := range other.ToSlice() {
+		if s.Contains(value) {
+			m = m.Delete(value)
+		} else {
+			m = m.Store(value, struct{}{})
+		}
+	}
+
+	return s.wrap(m)
+}
+
+// IsSubset 判断s是否是other的子集
+func (s *persistentSet) IsSubset(other Set) bool {
+	if s.Size() > other.Size() {
+		return false
+	}
+
+	ret := true
+	s.m.Range(func(key, _ interface{}) bool {
+		ret = other.Contains(key)
+		return ret
+	})
+
+	return ret
+}
+
+func (s *persistentSet) IsProperSubset(other Set) bool {
+	return s.Size() < other.Size() && s.IsSubset(other)
+}
+
+// IsSuperset 判断s是否是other的超集
+func (s *persistentSet) IsSuperset(other Set) bool {
+	return other.Size() <= s.Size() && s.Contains(other.ToSlice()...)
+}
+
+func (s *persistentSet) IsProperSuperset(other Set) bool {
+	return other.Size() < s.Size() && s.Contains(other.ToSlice()...)
+}
+
+func (s *persistentSet) Equal(other Set) bool {
+	return s.Size() == other.Size() && s.IsSubset(other)
+}
+
+// EqualTo 判断两个持久化集合是否相等，共享的部分不需要比较
+func (s *persistentSet) EqualTo(other *persistentSet) bool {
+	return s.m.Equal(other.m)
+}
+
+// SubsetOf 判断s是否是持久化集合other的子集，共享的部分不需要比较
+func (s *persistentSet) SubsetOf(other *persistentSet) bool {
+	if s.Size() > other.Size() {
+		return false
+	}
+
+	ret := true
+	s.m.Diff(other.m, func(_ interface{}, t Map.DiffType, _, _ interface{}) bool {
+		ret = t != Map.DiffRemoved
+		return ret
+	})
+
+	return ret
+}
+
+// Diff 把s作为旧版本，other作为新版本，对每个新增（added为true）或删除的元素调用f，f返回false时停止
+func (s *persistentSet) Diff(other *persistentSet, f func(value interface{}, added bool) bool) {
+	s.m.Diff(other.m, func(key interface{}, t Map.DiffType, _, _ interface{}) bool {
+		return f(key, t == Map.DiffAdded)
+	})
+}
+
+// ForEach 遍历集合中的元素，f返回false时停止遍历
+func (s *persistentSet) ForEach(f func(value interface{}) bool) {
+	s.m.Range(func(key, _ interface{}) bool {
+		return f(key)
+	})
+}
+
+func (s *persistentSet) Iter() <-chan interface{} {
+	ch := make(chan interface{})
+	go func() {
+		s.ForEach(func(value interface{}) bool {
+			ch <- value
+			return true
+		})
+		close(ch)
+	}()
+
+	return ch
+}
+
+func (s *persistentSet) Iterator() *Iterator {
+	iterator, ch, stopCh := newIterator()
+
+	go func() {
+		s.ForEach(func(value interface{}) bool {
+			select {
+			case <-stopCh:
+				return false
+			case ch <- value:
+				return true
+			}
+		})
+		close(ch)
+	}()
+
+	return iterator
+}
+
+func (s *persistentSet) Fill() bool {
+	return false
+}
+
+func (s *persistentSet) Empty() bool {
+	return s.m.Len() == 0
+}
+
+func (s *persistentSet) Size() int {
+	return s.m.Len()
+}
+
+func (s *persistentSet) MaxSize() int {
+	return -1
+}
+
+func (s *persistentSet) String() string {
+	items := make([]string, 0, s.Size())
+	s.ForEach(func(value interface{}) bool {
+		items = append(items, fmt.Sprintf("%v", value))
+		return true
+	})
+
+	return fmt.Sprintf("PersistentSet{%s}", strings.Join(items, ", "))
+}
+
+func (s *persistentSet) ToSlice() []interface{} {
+	ret := make([]interface{}, 0, s.Size())
+	s.ForEach(func(value interface{}) bool {
+		ret = append(ret, value)
+		return true
+	})
+
+	return ret
+}
+
+// ToSet 复制元素创建一个unsafeSet
+func (s *persistentSet) ToSet(maxSize int) (Set, error) {
+	return NewUnsafeSetWithSlice(maxSize, s.ToSlice())
+}
+
+// MarshalJSON 将集合中的所有元素以Json数组的形式返回
+func (s *persistentSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.ToSlice())
+}
@@ -0,0 +1,102 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Set
+
+import (
+	"testing"
+)
+
+func rangeSet(start, end int) *persistentSet {
+	s := NewPersistentSet()
+	for i := start; i < end; i++ {
+		s = s.Insert(i)
+	}
+
+	return s
+}
+
+// SubsetOf与EqualTo只比较两个版本不同的部分，结果与逐个元素比较一致
+func TestPersistentSetSubsetOfAndEqualTo(t *testing.T) {
+	base := rangeSet(0, 500)
+	bigger := base.Insert(500)
+	smaller := base.Remove(250)
+
+	cases := []struct {
+		name   string
+		a, b   *persistentSet
+		subset bool
+		equal  bool
+	}{
+		{"same version", base, base, true, true},
+		{"one element added", base, bigger, true, false},
+		{"one element removed", base, smaller, false, false},
+		{"removed side", smaller, base, true, false},
+		{"same size, different elements", base, base.Remove(0).Insert(-1), false, false},
+		{"same elements, different history", bigger.Remove(500), rangeSet(0, 500), true, true},
+		{"empty", NewPersistentSet(), base, true, false},
+	}
+	for _, c := range cases {
+		if got := c.a.SubsetOf(c.b); got != c.subset {
+			t.Errorf("%s: SubsetOf = %v, want %v", c.name, got, c.subset)
+		}
+		if got := c.a.EqualTo(c.b); got != c.equal {
+			t.Errorf("%s: EqualTo = %v, want %v", c.name, got, c.equal)
+		}
+
+		// 与通用的逐个比较一致
+		other, err := c.b.ToSet(-1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.a.IsSubset(other); got != c.subset {
+			t.Errorf("%s: IsSubset = %v, want %v", c.name, got, c.subset)
+		}
+		if got := c.a.Equal(other); got != c.equal {
+			t.Errorf("%s: Equal = %v, want %v", c.name, got, c.equal)
+		}
+	}
+}
+
+// 修改操作不影响旧版本，没有变化时返回原集合
+func TestPersistentSetVersions(t *testing.T) {
+	base := rangeSet(0, 100)
+	if base.Insert(5) != base || base.Remove(100) != base {
+		t.Fatal("no-op change created a new version")
+	}
+
+	next := base.Difference(rangeSet(0, 50)).Union(rangeSet(200, 210))
+	if base.Size() != 100 || !base.Contains(0, 99) {
+		t.Fatalf("base changed to %v", base)
+	}
+	if next.Size() != 60 || next.Contains(0) || !next.Contains(50, 99, 200, 209) {
+		t.Fatalf("next = %v, want [50, 100) and [200, 210)", next)
+	}
+
+	added, removed := 0, 0
+	base.Diff(next, func(value interface{}, isAdded bool) bool {
+		if isAdded {
+			added++
+		} else {
+			removed++
+		}
+		return true
+	})
+	if added != 10 || removed != 50 {
+		t.Fatalf("Diff() reported %d added and %d removed, want 10 and 50", added, removed)
+	}
+}
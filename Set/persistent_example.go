@@ -0,0 +1,39 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Set
+
+import "fmt"
+
+func PersistentSetExample() {
+	s1 := NewPersistentSet(1, 2, 3)
+	s2 := s1.Insert(4).Remove(1)
+
+	// 修改返回新的集合，s1保持不变
+	fmt.Println(s1.Size(), s2.Size(), s1.Contains(1), s2.Contains(1))
+
+	s2.Diff(s1, func(value interface{}, added bool) bool {
+		fmt.Println(value, added)
+		return true
+	})
+
+	// 实现了ReadOnlySet，可以和其它Set比较
+	us, _ := NewUnsafeSet(-1, 2, 3, 4)
+	fmt.Println(s2.Equal(us), s1.IsSubset(us), s2.EqualTo(NewPersistentSetFromSet(us)))
+
+	fmt.Println(s1.Union(us), s1.Intersect(us), s1.SymmetricDifference(us))
+}
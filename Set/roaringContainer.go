@@ -0,0 +1,518 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Set
+
+import (
+	"math/bits"
+	"sort"
+)
+
+const (
+	// arrayMaxSize 数组容器最多保存的元素数量，超过后转换为位图容器
+	arrayMaxSize = 4096
+
+	// bitmapWords 位图容器中uint64的个数，1024 * 64 = 65536
+	bitmapWords = 1024
+)
+
+// roaringContainer 保存高16位相同的一组元素的低16位
+// add和remove可能会改变容器的编码方式，所以调用者需要使用返回的容器替换原来的容器
+type roaringContainer interface {
+	add(x uint16) roaringContainer
+
+	remove(x uint16) roaringContainer
+
+	contains(x uint16) bool
+
+	cardinality() int
+
+	clone() roaringContainer
+
+	// iterate 按升序遍历容器中的元素，f返回false时停止遍历，返回值表示是否遍历完整个容器
+	iterate(f func(x uint16) bool) bool
+
+	// toBitmap 返回容器的位图表示，位图容器会直接返回自身，调用者不能修改返回值
+	toBitmap() *bitmapContainer
+
+	// numRuns 返回容器中连续区间的个数
+	numRuns() int
+}
+
+/*---------------------------------数组容器---------------------------------------*/
+
+// arrayContainer 使用有序数组保存元素，适用于稀疏的情况
+type arrayContainer struct {
+	content []uint16
+}
+
+// find 二分查找x的位置，如果不存在则返回x应该插入的位置
+func (c *arrayContainer) find(x uint16) (int, bool) {
+	i := sort.Search(len(c.content), func(i int) bool {
+		return c.content[i] >= x
+	})
+
+	return i, i < len(c.content) && c.content[i] == x
+}
+
+func (c *arrayContainer) add(x uint16) roaringContainer {
+	i, ok := c.find(x)
+	if ok {
+		return c
+	}
+
+	// 数组容器已满，转换为位图容器
+	if len(c.content) >= arrayMaxSize {
+		b := c.toBitmap()
+		return b.add(x)
+	}
+
+	c.content = append(c.content, 0)
+	copy(c.content[i+1:], c.content[i:])
+	c.content[i] = x
+
+	return c
+}
+
+func (c *arrayContainer) remove(x uint16) roaringContainer {
+	if i, ok := c.find(x); ok {
+		c.content = append(c.content[:i], c.content[i+1:]...)
+	}
+
+	return c
+}
+
+func (c *arrayContainer) contains(x uint16) bool {
+	_, ok := c.find(x)
+	return ok
+}
+
+func (c *arrayContainer) cardinality() int {
+	return len(c.content)
+}
+
+func (c *arrayContainer) clone() roaringContainer {
+	content := make([]uint16, len(c.content))
+	copy(content, c.content)
+
+	return &arrayContainer{content: content}
+}
+
+func (c *arrayContainer) iterate(f func(x uint16) bool) bool {
+	for _, x := range c.content {
+		if !f(x) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *arrayContainer) toBitmap() *bitmapContainer {
+	b := newBitmapContainer()
+	for _, x := range c.content {
+		b.words[x>>6] |= 1 << (x & 63)
+	}
+	b.card = len(c.content)
+
+	return b
+}
+
+func (c *arrayContainer) numRuns() int {
+	n := 0
+	for i, x := range c.content {
+		if i == 0 || c.content[i-1]+1 != x {
+			n++
+		}
+	}
+
+	return n
+}
+
+/*---------------------------------位图容器---------------------------------------*/
+
+// bitmapContainer 使用65536位的位图保存元素，适用于稠密的情况
+type bitmapContainer struct {
+	card  int
+	words []uint64
+}
+
+func newBitmapContainer() *bitmapContainer {
+	return &bitmapContainer{
+		card:  0,
+		words: make([]uint64, bitmapWords),
+	}
+}
+
+func (c *bitmapContainer) add(x uint16) roaringContainer {
+	mask := uint64(1) << (x & 63)
+	if c.words[x>>6]&mask == 0 {
+		c.words[x>>6] |= mask
+		c.card++
+	}
+
+	return c
+}
+
+func (c *bitmapContainer) remove(x uint16) roaringContainer {
+	mask := uint64(1) << (x & 63)
+	if c.words[x>>6]&mask != 0 {
+		c.words[x>>6] &^= mask
+		c.card--
+	}
+
+	return c.repair()
+}
+
+func (c *bitmapContainer) contains(x uint16) bool {
+	return c.words[x>>6]&(uint64(1)<<(x&63)) != 0
+}
+
+func (c *bitmapContainer) cardinality() int {
+	return c.card
+}
+
+func (c *bitmapContainer) clone() roaringContainer {
+	words := make([]uint64, bitmapWords)
+	copy(words, c.words)
+
+	return &bitmapContainer{card: c.card, words: words}
+}
+
+func (c *bitmapContainer) iterate(f func(x uint16) bool) bool {
+	for i, w := range c.words {
+		for w != 0 {
+			t := bits.TrailingZeros64(w)
+			if !f(uint16(i*64 + t)) {
+				return false
+			}
+			w &= w - 1
+		}
+	}
+
+	return true
+}
+
+func (c *bitmapContainer) toBitmap() *bitmapContainer {
+	return c
+}
+
+func (c *bitmapContainer) numRuns() int {
+	n := 0
+	var prev uint64
+	for _, w := range c.words {
+		// 当前位为1且前一位为0的位置就是一个区间的起点
+		n += bits.OnesCount64(w &^ (w<<1 | prev>>63))
+		prev = w
+	}
+
+	return n
+}
+
+// setRange 将闭区间[start, last]中的所有位置为1
+func (c *bitmapContainer) setRange(start, last uint32) {
+	firstWord, lastWord := start>>6, last>>6
+	for i := firstWord; i <= lastWord; i++ {
+		mask := ^uint64(0)
+		if i == firstWord {
+			mask &= ^uint64(0) << (start & 63)
+		}
+		if i == lastWord {
+			mask &= ^uint64(0) >> (63 - last&63)
+		}
+		c.card += bits.OnesCount64(mask &^ c.words[i])
+		c.words[i] |= mask
+	}
+}
+
+// repair 当元素数量不超过arrayMaxSize时转换为数组容器
+func (c *bitmapContainer) repair() roaringContainer {
+	if c.card > arrayMaxSize {
+		return c
+	}
+
+	content := make([]uint16, 0, c.card)
+	c.iterate(func(x uint16) bool {
+		content = append(content, x)
+		return true
+	})
+
+	return &arrayContainer{content: content}
+}
+
+/*---------------------------------行程容器---------------------------------------*/
+
+// interval16 表示闭区间[start, start+length]
+type interval16 struct {
+	start  uint16
+	length uint16
+}
+
+func (iv interval16) last() uint32 {
+	return uint32(iv.start) + uint32(iv.length)
+}
+
+// runContainer 使用有序且互不相邻的区间保存元素，适用于存在大段连续元素的情况
+type runContainer struct {
+	runs []interval16
+}
+
+// find 返回起点不大于x的最后一个区间下标，不存在时返回-1
+func (c *runContainer) find(x uint16) int {
+	return sort.Search(len(c.runs), func(i int) bool {
+		return c.runs[i].start > x
+	}) - 1
+}
+
+func (c *runContainer) add(x uint16) roaringContainer {
+	p := c.find(x)
+	if p >= 0 && uint32(x) <= c.runs[p].last() {
+		return c
+	}
+
+	i := p + 1
+	joinPrev := p >= 0 && c.runs[p].last()+1 == uint32(x)
+	joinNext := i < len(c.runs) && uint32(x)+1 == uint32(c.runs[i].start)
+
+	switch {
+	case joinPrev && joinNext:
+		// x恰好填补了两个区间之间的空隙，将两个区间合并
+		c.runs[p].length = uint16(c.runs[i].last() - uint32(c.runs[p].start))
+		c.runs = append(c.runs[:i], c.runs[i+1:]...)
+	case joinPrev:
+		c.runs[p].length++
+	case joinNext:
+		c.runs[i].start--
+		c.runs[i].length++
+	default:
+		c.runs = append(c.runs, interval16{})
+		copy(c.runs[i+1:], c.runs[i:])
+		c.runs[i] = interval16{start: x, length: 0}
+	}
+
+	return c
+}
+
+func (c *runContainer) remove(x uint16) roaringContainer {
+	p := c.find(x)
+	if p < 0 || uint32(x) > c.runs[p].last() {
+		return c
+	}
+
+	start, last := uint32(c.runs[p].start), c.runs[p].last()
+	switch {
+	case start == last:
+		c.runs = append(c.runs[:p], c.runs[p+1:]...)
+	case uint32(x) == start:
+		c.runs[p].start++
+		c.runs[p].length--
+	case uint32(x) == last:
+		c.runs[p].length--
+	default:
+		// x位于区间中间，将区间一分为二
+		c.runs[p].length = uint16(uint32(x) - start - 1)
+		c.runs = append(c.runs, interval16{})
+		copy(c.runs[p+2:], c.runs[p+1:])
+		c.runs[p+1] = interval16{start: x + 1, length: uint16(last - uint32(x) - 1)}
+	}
+
+	return c
+}
+
+func (c *runContainer) contains(x uint16) bool {
+	p := c.find(x)
+	return p >= 0 && uint32(x) <= c.runs[p].last()
+}
+
+func (c *runContainer) cardinality() int {
+	n := 0
+	for _, iv := range c.runs {
+		n += int(iv.length) + 1
+	}
+
+	return n
+}
+
+func (c *runContainer) clone() roaringContainer {
+	runs := make([]interval16, len(c.runs))
+	copy(runs, c.runs)
+
+	return &runContainer{runs: runs}
+}
+
+func (c *runContainer) iterate(f func(x uint16) bool) bool {
+	for _, iv := range c.runs {
+		for v := uint32(iv.start); v <= iv.last(); v++ {
+			if !f(uint16(v)) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (c *runContainer) toBitmap() *bitmapContainer {
+	b := newBitmapContainer()
+	for _, iv := range c.runs {
+		b.setRange(uint32(iv.start), iv.last())
+	}
+
+	return b
+}
+
+func (c *runContainer) numRuns() int {
+	return len(c.runs)
+}
+
+/*---------------------------------容器工具函数---------------------------------------*/
+
+// newRunContainerFrom 将任意容器转换为行程容器
+func newRunContainerFrom(c roaringContainer) *runContainer {
+	r := &runContainer{runs: make([]interval16, 0, c.numRuns())}
+	c.iterate(func(x uint16) bool {
+		n := len(r.runs)
+		if n > 0 && r.runs[n-1].last()+1 == uint32(x) {
+			r.runs[n-1].length++
+		} else {
+			r.runs = append(r.runs, interval16{start: x, length: 0})
+		}
+		return true
+	})
+
+	return r
+}
+
+// optimizeContainer 选择序列化后体积最小的编码方式
+func optimizeContainer(c roaringContainer) roaringContainer {
+	card := c.cardinality()
+	runSize := 2 + 4*c.numRuns()
+
+	otherSize := 8 * bitmapWords
+	if card <= arrayMaxSize {
+		otherSize = 2 * card
+	}
+
+	if runSize < otherSize {
+		if r, ok := c.(*runContainer); ok {
+			return r
+		}
+		return newRunContainerFrom(c)
+	}
+
+	if _, ok := c.(*runContainer); ok {
+		return c.toBitmap().repair()
+	}
+
+	return c
+}
+
+// containerAnd 求两个容器的交集
+func containerAnd(a, b roaringContainer) roaringContainer {
+	if x, ok := a.(*arrayContainer); ok {
+		return filterArray(x, b, true)
+	}
+	if y, ok := b.(*arrayContainer); ok {
+		return filterArray(y, a, true)
+	}
+
+	ba, bb := a.toBitmap(), b.toBitmap()
+	r := newBitmapContainer()
+	for i := range r.words {
+		r.words[i] = ba.words[i] & bb.words[i]
+		r.card += bits.OnesCount64(r.words[i])
+	}
+
+	return r.repair()
+}
+
+// containerOr 求两个容器的并集
+func containerOr(a, b roaringContainer) roaringContainer {
+	x, ok1 := a.(*arrayContainer)
+	y, ok2 := b.(*arrayContainer)
+	if ok1 && ok2 && len(x.content)+len(y.content) <= arrayMaxSize {
+		content := make([]uint16, 0, len(x.content)+len(y.content))
+		i, j := 0, 0
+		for i < len(x.content) && j < len(y.content) {
+			switch {
+			case x.content[i] < y.content[j]:
+				content = append(content, x.content[i])
+				i++
+			case x.content[i] > y.content[j]:
+				content = append(content, y.content[j])
+				j++
+			default:
+				content = append(content, x.content[i])
+				i++
+				j++
+			}
+		}
+		content = append(content, x.content[i:]...)
+		content = append(content, y.content[j:]...)
+
+		return &arrayContainer{content: content}
+	}
+
+	ba, bb := a.toBitmap(), b.toBitmap()
+	r := newBitmapContainer()
+	for i := range r.words {
+		r.words[i] = ba.words[i] | bb.words[i]
+		r.card += bits.OnesCount64(r.words[i])
+	}
+
+	return r.repair()
+}
+
+// containerAndNot 求a - b差集
+func containerAndNot(a, b roaringContainer) roaringContainer {
+	if x, ok := a.(*arrayContainer); ok {
+		return filterArray(x, b, false)
+	}
+
+	ba, bb := a.toBitmap(), b.toBitmap()
+	r := newBitmapContainer()
+	for i := range r.words {
+		r.words[i] = ba.words[i] &^ bb.words[i]
+		r.card += bits.OnesCount64(r.words[i])
+	}
+
+	return r.repair()
+}
+
+// containerXor 求两个容器的对称差集
+func containerXor(a, b roaringContainer) roaringContainer {
+	ba, bb := a.toBitmap(), b.toBitmap()
+	r := newBitmapContainer()
+	for i := range r.words {
+		r.words[i] = ba.words[i] ^ bb.words[i]
+		r.card += bits.OnesCount64(r.words[i])
+	}
+
+	return r.repair()
+}
+
+// filterArray 保留数组容器a中(keep为true时)属于或(keep为false时)不属于b的元素
+func filterArray(a *arrayContainer, b roaringContainer, keep bool) roaringContainer {
+	content := make([]uint16, 0, len(a.content))
+	for _, x := range a.content {
+		if b.contains(x) == keep {
+			content = append(content, x)
+		}
+	}
+
+	return &arrayContainer{content: content}
+}
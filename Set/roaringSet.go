@@ -0,0 +1,677 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Set
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+const (
+	// serialCookieNoRunContainer 不包含行程容器时序列化格式的标识
+	serialCookieNoRunContainer = 12346
+
+	// serialCookie 包含行程容器时序列化格式的标识
+	serialCookie = 12347
+
+	// noOffsetThreshold 包含行程容器且容器数量少于该值时，序列化结果中不写入偏移量
+	noOffsetThreshold = 4
+)
+
+// roaringSet 是一个压缩位图(Roaring Bitmap)实现的uint32集合
+// 元素的高16位作为key将整个空间划分为若干容器，低16位保存在容器中，
+// 容器根据元素的疏密程度使用数组、位图或行程三种编码方式
+type roaringSet struct {
+	keys       []uint16
+	containers []roaringContainer
+}
+
+func NewRoaringSet(values ...uint32) *roaringSet {
+	s := &roaringSet{}
+	for _, v := range values {
+		s.Insert(v)
+	}
+
+	return s
+}
+
+func NewRoaringSetWithSlice(values []uint32) *roaringSet {
+	s := &roaringSet{}
+	for _, v := range values {
+		s.Insert(v)
+	}
+
+	return s
+}
+
+// NewRoaringSetFromSet 将一个Set转换为roaringSet，Set中的元素必须是能够表示为uint32的整数
+func NewRoaringSetFromSet(other Set) (*roaringSet, error) {
+	s := &roaringSet{}
+	for _, v := range other.ToSlice() {
+		x, ok := toUint32(v)
+		if !ok {
+			return nil, fmt.Errorf("Element %v can not be converted to uint32.", v)
+		}
+		s.Insert(x)
+	}
+
+	return s, nil
+}
+
+// getIndex 二分查找key所在容器的下标，如果不存在则返回key应该插入的位置
+func (s *roaringSet) getIndex(key uint16) (int, bool) {
+	i := sort.Search(len(s.keys), func(i int) bool {
+		return s.keys[i] >= key
+	})
+
+	return i, i < len(s.keys) && s.keys[i] == key
+}
+
+// Insert 向集合中添加元素
+func (s *roaringSet) Insert(value uint32) {
+	key, low := uint16(value>>16), uint16(value)
+
+	i, ok := s.getIndex(key)
+	if ok {
+		s.containers[i] = s.containers[i].add(low)
+		return
+	}
+
+	c := &arrayContainer{content: []uint16{low}}
+	s.keys = append(s.keys, 0)
+	copy(s.keys[i+1:], s.keys[i:])
+	s.keys[i] = key
+	s.containers = append(s.containers, nil)
+	copy(s.containers[i+1:], s.containers[i:])
+	s.containers[i] = c
+}
+
+// Remove 从集合中删除元素，容器为空时会一并删除容器
+func (s *roaringSet) Remove(value uint32) {
+	i, ok := s.getIndex(uint16(value >> 16))
+	if !ok {
+		return
+	}
+
+	s.containers[i] = s.containers[i].remove(uint16(value))
+	if s.containers[i].cardinality() == 0 {
+		s.removeAt(i)
+	}
+}
+
+func (s *roaringSet) removeAt(i int) {
+	s.keys = append(s.keys[:i], s.keys[i+1:]...)
+	s.containers = append(s.containers[:i], s.containers[i+1:]...)
+}
+
+// appendContainer 向集合末尾追加一个容器，空容器将被忽略
+func (s *roaringSet) appendContainer(key uint16, c roaringContainer) {
+	if c.cardinality() == 0 {
+		return
+	}
+
+	s.keys = append(s.keys, key)
+	s.containers = append(s.containers, c)
+}
+
+func (s *roaringSet) Contains(values ...uint32) bool {
+	for _, v := range values {
+		i, ok := s.getIndex(uint16(v >> 16))
+		if !ok || !s.containers[i].contains(uint16(v)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Union 求该集合s和other的并集
+func (s *roaringSet) Union(other *roaringSet) *roaringSet {
+	ret := &roaringSet{}
+
+	i, j := 0, 0
+	for i < len(s.keys) && j < len(other.keys) {
+		switch {
+		case s.keys[i] < other.keys[j]:
+			ret.appendContainer(s.keys[i], s.containers[i].clone())
+			i++
+		case s.keys[i] > other.keys[j]:
+			ret.appendContainer(other.keys[j], other.containers[j].clone())
+			j++
+		default:
+			ret.appendContainer(s.keys[i], containerOr(s.containers[i], other.containers[j]))
+			i++
+			j++
+		}
+	}
+	for ; i < len(s.keys); i++ {
+		ret.appendContainer(s.keys[i], s.containers[i].clone())
+	}
+	for ; j < len(other.keys); j++ {
+		ret.appendContainer(other.keys[j], other.containers[j].clone())
+	}
+
+	return ret
+}
+
+// Intersect 求该集合s和other的交集
+func (s *roaringSet) Intersect(other *roaringSet) *roaringSet {
+	ret := &roaringSet{}
+
+	i, j := 0, 0
+	for i < len(s.keys) && j < len(other.keys) {
+		switch {
+		case s.keys[i] < other.keys[j]:
+			i++
+		case s.keys[i] > other.keys[j]:
+			j++
+		default:
+			ret.appendContainer(s.keys[i], containerAnd(s.containers[i], other.containers[j]))
+			i++
+			j++
+		}
+	}
+
+	return ret
+}
+
+// Difference 求s - other差集
+func (s *roaringSet) Difference(other *roaringSet) *roaringSet {
+	ret := &roaringSet{}
+
+	j := 0
+	for i := range s.keys {
+		for j < len(other.keys) && other.keys[j] < s.keys[i] {
+			j++
+		}
+
+		if j < len(other.keys) && other.keys[j] == s.keys[i] {
+			ret.appendContainer(s.keys[i], containerAndNot(s.containers[i], other.containers[j]))
+		} else {
+			ret.appendContainer(s.keys[i], s.containers[i].clone())
+		}
+	}
+
+	return ret
+}
+
+// SymmetricDifference 求该集合s和other的对称差集
+func (s *roaringSet) SymmetricDifference(other *roaringSet) *roaringSet {
+	ret := &roaringSet{}
+
+	i, j := 0, 0
+	for i < len(s.keys) && j < len(other.keys) {
+		switch {
+		case s.keys[i] < other.keys[j]:
+			ret.appendContainer(s.keys[i], s.containers[i].clone())
+			i++
+		case s.keys[i] > other.keys[j]:
+			ret.appendContainer(other.keys[j], other.containers[j].clone())
+			j++
+		default:
+			ret.appendContainer(s.keys[i], containerXor(s.containers[i], other.containers[j]))
+			i++
+			j++
+		}
+	}
+	for ; i < len(s.keys); i++ {
+		ret.appendContainer(s.keys[i], s.containers[i].clone())
+	}
+	for ; j < len(other.keys); j++ {
+		ret.appendContainer(other.keys[j], other.containers[j].clone())
+	}
+
+	return ret
+}
+
+// Equal 判断两个集合是否相等
+func (s *roaringSet) Equal(other *roaringSet) bool {
+	if len(s.keys) != len(other.keys) {
+		return false
+	}
+
+	for i := range s.keys {
+		if s.keys[i] != other.keys[i] ||
+			s.containers[i].cardinality() != other.containers[i].cardinality() {
+			return false
+		}
+		if containerXor(s.containers[i], other.containers[i]).cardinality() != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsSubset 判断s是否是other的子集
+func (s *roaringSet) IsSubset(other *roaringSet) bool {
+	j := 0
+	for i := range s.keys {
+		for j < len(other.keys) && other.keys[j] < s.keys[i] {
+			j++
+		}
+		if j == len(other.keys) || other.keys[j] != s.keys[i] {
+			return false
+		}
+		if containerAndNot(s.containers[i], other.containers[j]).cardinality() != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsSuperset 判断s是否是other的超集
+func (s *roaringSet) IsSuperset(other *roaringSet) bool {
+	return other.IsSubset(s)
+}
+
+// ForEach 按升序遍历集合中的元素，f返回false时停止遍历
+func (s *roaringSet) ForEach(f func(value uint32) bool) {
+	for i, c := range s.containers {
+		high := uint32(s.keys[i]) << 16
+		ok := c.iterate(func(x uint16) bool {
+			return f(high | uint32(x))
+		})
+		if !ok {
+			return
+		}
+	}
+}
+
+// Iterator 返回该集合s的一个迭代器，元素按升序输出
+func (s *roaringSet) Iterator() *Iterator {
+	iterator, ch, stopCh := newIterator()
+
+	go func() {
+		s.ForEach(func(value uint32) bool {
+			select {
+			case <-stopCh:
+				return false
+			case ch <- value:
+				return true
+			}
+		})
+		close(ch)
+	}()
+
+	return iterator
+}
+
+// RunOptimize 将每个容器转换为体积最小的编码方式，通常在集合构建完成后调用
+func (s *roaringSet) RunOptimize() {
+	for i, c := range s.containers {
+		s.containers[i] = optimizeContainer(c)
+	}
+}
+
+func (s *roaringSet) Clone() *roaringSet {
+	ret := &roaringSet{
+		keys:       make([]uint16, len(s.keys)),
+		containers: make([]roaringContainer, len(s.containers)),
+	}
+	copy(ret.keys, s.keys)
+	for i, c := range s.containers {
+		ret.containers[i] = c.clone()
+	}
+
+	return ret
+}
+
+func (s *roaringSet) Empty() bool {
+	return len(s.keys) == 0
+}
+
+func (s *roaringSet) Size() int {
+	n := 0
+	for _, c := range s.containers {
+		n += c.cardinality()
+	}
+
+	return n
+}
+
+func (s *roaringSet) Clear() {
+	s.keys = nil
+	s.containers = nil
+}
+
+func (s *roaringSet) String() string {
+	items := make([]string, 0, s.Size())
+	s.ForEach(func(value uint32) bool {
+		items = append(items, fmt.Sprintf("%v", value))
+		return true
+	})
+
+	return fmt.Sprintf("RoaringSet{%s}", strings.Join(items, ", "))
+}
+
+// ToArray 将集合中的元素按升序以切片形式返回
+func (s *roaringSet) ToArray() []uint32 {
+	ret := make([]uint32, 0, s.Size())
+	s.ForEach(func(value uint32) bool {
+		ret = append(ret, value)
+		return true
+	})
+
+	return ret
+}
+
+// ToSet 将roaringSet转换为一个元素类型为uint32的unsafeSet
+func (s *roaringSet) ToSet() Set {
+	ret, _ := NewUnsafeSet(-1)
+	s.ForEach(func(value uint32) bool {
+		_ = ret.Insert(value)
+		return true
+	})
+
+	return ret
+}
+
+// MarshalBinary 按照Roaring Bitmap的通用序列化格式(RoaringFormatSpec)将集合编码为字节切片，
+// 所有整数均以小端序存储，可与其他语言的Roaring实现互通
+func (s *roaringSet) MarshalBinary() ([]byte, error) {
+	n := len(s.keys)
+
+	hasRun := false
+	for _, c := range s.containers {
+		if _, ok := c.(*runContainer); ok {
+			hasRun = true
+			break
+		}
+	}
+
+	b := make([]byte, 0, 8+8*n)
+	if hasRun {
+		b = appendUint32(b, serialCookie|uint32(n-1)<<16)
+
+		// 使用位集标记哪些容器是行程容器
+		runFlags := make([]byte, (n+7)/8)
+		for i, c := range s.containers {
+			if _, ok := c.(*runContainer); ok {
+				runFlags[i/8] |= 1 << (i % 8)
+			}
+		}
+		b = append(b, runFlags...)
+	} else {
+		b = appendUint32(b, serialCookieNoRunContainer)
+		b = appendUint32(b, uint32(n))
+	}
+
+	for i, c := range s.containers {
+		b = appendUint16(b, s.keys[i])
+		b = appendUint16(b, uint16(c.cardinality()-1))
+	}
+
+	if !hasRun || n >= noOffsetThreshold {
+		offset := uint32(len(b) + 4*n)
+		for _, c := range s.containers {
+			b = appendUint32(b, offset)
+			offset += uint32(serializedSize(c))
+		}
+	}
+
+	for _, c := range s.containers {
+		switch t := c.(type) {
+		case *arrayContainer:
+			for _, x := range t.content {
+				b = appendUint16(b, x)
+			}
+		case *bitmapContainer:
+			for _, w := range t.words {
+				b = appendUint64(b, w)
+			}
+		case *runContainer:
+			b = appendUint16(b, uint16(len(t.runs)))
+			for _, iv := range t.runs {
+				b = appendUint16(b, iv.start)
+				b = appendUint16(b, iv.length)
+			}
+		}
+	}
+
+	return b, nil
+}
+
+// UnmarshalBinary 从MarshalBinary产生的字节切片中解析出集合，原有元素将被清空
+// 每个容器的内容都会被校验，元素数量与头部不一致、数组无序或重复、区间无序或重叠时返回错误，出错时原有元素保持不变
+func (s *roaringSet) UnmarshalBinary(data []byte) error {
+	invalid := errors.New("Invalid roaring bitmap data.")
+
+	r := &byteReader{b: data}
+	cookie, ok := r.uint32()
+	if !ok {
+		return invalid
+	}
+
+	var n int
+	var runFlags []byte
+	switch {
+	case cookie&0xFFFF == serialCookie:
+		n = int(cookie>>16) + 1
+		if runFlags, ok = r.bytes((n + 7) / 8); !ok {
+			return invalid
+		}
+	case cookie == serialCookieNoRunContainer:
+		size, ok := r.uint32()
+		if !ok || size > 1<<16 {
+			return invalid
+		}
+		n = int(size)
+	default:
+		return invalid
+	}
+
+	keys := make([]uint16, n)
+	cards := make([]int, n)
+	for i := 0; i < n; i++ {
+		key, ok1 := r.uint16()
+		card, ok2 := r.uint16()
+		if !ok1 || !ok2 || (i > 0 && key <= keys[i-1]) {
+			return invalid
+		}
+		keys[i] = key
+		cards[i] = int(card) + 1
+	}
+
+	// 偏移量只用于随机访问，顺序解析时直接跳过
+	if runFlags == nil || n >= noOffsetThreshold {
+		if _, ok = r.bytes(4 * n); !ok {
+			return invalid
+		}
+	}
+
+	containers := make([]roaringContainer, n)
+	for i := 0; i < n; i++ {
+		isRun := runFlags != nil && runFlags[i/8]&(1<<(i%8)) != 0
+
+		switch {
+		case isRun:
+			numRuns, ok := r.uint16()
+			if !ok {
+				return invalid
+			}
+			c := &runContainer{runs: make([]interval16, numRuns)}
+			for k := range c.runs {
+				start, ok1 := r.uint16()
+				length, ok2 := r.uint16()
+				if !ok1 || !ok2 || uint32(start)+uint32(length) > math.MaxUint16 {
+					return invalid
+				}
+				// 区间必须有序、互不重叠且互不相邻
+				if k > 0 && uint32(start) <= c.runs[k-1].last()+1 {
+					return invalid
+				}
+				c.runs[k] = interval16{start: start, length: length}
+			}
+			if c.cardinality() != cards[i] {
+				return invalid
+			}
+			containers[i] = c
+		case cards[i] <= arrayMaxSize:
+			c := &arrayContainer{content: make([]uint16, cards[i])}
+			for k := range c.content {
+				if c.content[k], ok = r.uint16(); !ok {
+					return invalid
+				}
+				// 元素必须严格递增
+				if k > 0 && c.content[k] <= c.content[k-1] {
+					return invalid
+				}
+			}
+			containers[i] = c
+		default:
+			c := newBitmapContainer()
+			for k := range c.words {
+				if c.words[k], ok = r.uint64(); !ok {
+					return invalid
+				}
+				c.card += bits.OnesCount64(c.words[k])
+			}
+			// 元素数量以位图中实际的位数为准，与头部记录的不一致说明数据损坏
+			if c.card != cards[i] {
+				return invalid
+			}
+			containers[i] = c
+		}
+	}
+
+	s.keys = keys
+	s.containers = containers
+
+	return nil
+}
+
+// serializedSize 返回容器序列化后的字节数
+func serializedSize(c roaringContainer) int {
+	switch t := c.(type) {
+	case *arrayContainer:
+		return 2 * len(t.content)
+	case *runContainer:
+		return 2 + 4*len(t.runs)
+	default:
+		return 8 * bitmapWords
+	}
+}
+
+// byteReader 按小端序从字节切片中依次读取整数
+type byteReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *byteReader) bytes(n int) ([]byte, bool) {
+	if n < 0 || r.pos+n > len(r.b) {
+		return nil, false
+	}
+	ret := r.b[r.pos : r.pos+n]
+	r.pos += n
+
+	return ret, true
+}
+
+func (r *byteReader) uint16() (uint16, bool) {
+	b, ok := r.bytes(2)
+	if !ok {
+		return 0, false
+	}
+
+	return binary.LittleEndian.Uint16(b), true
+}
+
+func (r *byteReader) uint32() (uint32, bool) {
+	b, ok := r.bytes(4)
+	if !ok {
+		return 0, false
+	}
+
+	return binary.LittleEndian.Uint32(b), true
+}
+
+func (r *byteReader) uint64() (uint64, bool) {
+	b, ok := r.bytes(8)
+	if !ok {
+		return 0, false
+	}
+
+	return binary.LittleEndian.Uint64(b), true
+}
+
+// toUint32 将整数类型的值转换为uint32，超出范围或不是整数时返回false
+func toUint32(v interface{}) (uint32, bool) {
+	var i int64
+	switch t := v.(type) {
+	case uint32:
+		return t, true
+	case uint8:
+		return uint32(t), true
+	case uint16:
+		return uint32(t), true
+	case uint:
+		if uint64(t) > math.MaxUint32 {
+			return 0, false
+		}
+		return uint32(t), true
+	case uint64:
+		if t > math.MaxUint32 {
+			return 0, false
+		}
+		return uint32(t), true
+	case int:
+		i = int64(t)
+	case int8:
+		i = int64(t)
+	case int16:
+		i = int64(t)
+	case int32:
+		i = int64(t)
+	case int64:
+		i = t
+	case json.Number:
+		var err error
+		if i, err = t.Int64(); err != nil {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+
+	if i < 0 || i > math.MaxUint32 {
+		return 0, false
+	}
+
+	return uint32(i), true
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v), byte(v>>8))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(v)), uint32(v>>32))
+}
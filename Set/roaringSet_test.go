@@ -0,0 +1,140 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Set
+
+import (
+	"testing"
+)
+
+// encodeArrays 按照不含行程容器的格式编码，每个数组容器的键依次为0, 1, 2...
+func encodeArrays(containers ...[]uint16) []byte {
+	b := appendUint32(nil, serialCookieNoRunContainer)
+	b = appendUint32(b, uint32(len(containers)))
+	for i, c := range containers {
+		b = appendUint16(b, uint16(i))
+		b = appendUint16(b, uint16(len(c)-1))
+	}
+	for range containers {
+		b = appendUint32(b, 0)
+	}
+	for _, c := range containers {
+		for _, x := range c {
+			b = appendUint16(b, x)
+		}
+	}
+
+	return b
+}
+
+// encodeBitmap 编码一个位图容器，头部记录的元素数量为card，位图的前ones位为1
+func encodeBitmap(card, ones int) []byte {
+	b := appendUint32(nil, serialCookieNoRunContainer)
+	b = appendUint32(b, 1)
+	b = appendUint16(b, 0)
+	b = appendUint16(b, uint16(card-1))
+	b = appendUint32(b, 0)
+	for i := 0; i < bitmapWords; i++ {
+		var w uint64
+		switch {
+		case ones >= 64:
+			w = ^uint64(0)
+			ones -= 64
+		case ones > 0:
+			w = uint64(1)<<uint(ones) - 1
+			ones = 0
+		}
+		b = appendUint64(b, w)
+	}
+
+	return b
+}
+
+// encodeRuns 编码一个行程容器，头部记录的元素数量为card
+func encodeRuns(card int, runs ...interval16) []byte {
+	b := appendUint32(nil, serialCookie)
+	b = append(b, 1)
+	b = appendUint16(b, 0)
+	b = appendUint16(b, uint16(card-1))
+	b = appendUint16(b, uint16(len(runs)))
+	for _, iv := range runs {
+		b = appendUint16(b, iv.start)
+		b = appendUint16(b, iv.length)
+	}
+
+	return b
+}
+
+func TestRoaringSetUnmarshalValid(t *testing.T) {
+	cases := map[string][]byte{
+		"array":  encodeArrays([]uint16{1, 5, 9}, []uint16{0}),
+		"bitmap": encodeBitmap(5000, 5000),
+		"run":    encodeRuns(15, interval16{0, 9}, interval16{20, 4}),
+	}
+	want := map[string]int{"array": 4, "bitmap": 5000, "run": 15}
+
+	for name, data := range cases {
+		s := NewRoaringSet()
+		if err := s.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if s.Size() != want[name] {
+			t.Fatalf("%s: Size() = %d, want %d", name, s.Size(), want[name])
+		}
+	}
+
+	s := NewRoaringSet(1, 2, 3, 70000, 1<<20)
+	for v := uint32(100000); v < 110000; v++ {
+		s.Insert(v)
+	}
+	s.RunOptimize()
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := NewRoaringSet()
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(s) {
+		t.Fatal("decoded set differs from the encoded one")
+	}
+}
+
+// 损坏的容器内容返回错误，原有元素保持不变
+func TestRoaringSetUnmarshalCorrupt(t *testing.T) {
+	cases := map[string][]byte{
+		"array unsorted":       encodeArrays([]uint16{5, 3}),
+		"array duplicate":      encodeArrays([]uint16{3, 3, 4}),
+		"bitmap popcount low":  encodeBitmap(5000, 4100),
+		"bitmap popcount high": encodeBitmap(5000, 6000),
+		"run unordered":        encodeRuns(4, interval16{20, 1}, interval16{0, 1}),
+		"run overlapping":      encodeRuns(15, interval16{0, 10}, interval16{5, 3}),
+		"run adjacent":         encodeRuns(8, interval16{0, 3}, interval16{4, 3}),
+		"run cardinality":      encodeRuns(5, interval16{0, 9}),
+	}
+
+	for name, data := range cases {
+		s := NewRoaringSet(42)
+		if err := s.UnmarshalBinary(data); err == nil {
+			t.Fatalf("%s: UnmarshalBinary succeeded", name)
+		}
+		if s.Size() != 1 || !s.Contains(42) {
+			t.Fatalf("%s: failed UnmarshalBinary modified the set", name)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Set
+
+import "fmt"
+
+func RoaringSetExample() {
+	s := NewRoaringSet(1, 2, 3, 100000, 100001)
+	for i := uint32(200000); i < 210000; i++ {
+		s.Insert(i)
+	}
+	s.RunOptimize()
+	fmt.Println("s.Size =", s.Size())
+	fmt.Println("s.Contains =", s.Contains(2, 100001, 205000))
+
+	other := NewRoaringSet(2, 3, 4, 200000)
+	fmt.Println("s.Intersect =", s.Intersect(other).String())
+	fmt.Println("other.Difference =", other.Difference(s).String())
+
+	b, err := s.MarshalBinary()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("len(b) =", len(b))
+	s.Clear()
+	err = s.UnmarshalBinary(b)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("s.Size =", s.Size())
+
+	set, err := NewRoaringSetFromSet(other.ToSet())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("set.Equal =", set.Equal(other))
+}
@@ -18,12 +18,12 @@
 package Set
 
 import (
-	"sync"
+	"GTL/GSync"
 )
 
 type safeSet struct {
 	us *unsafeSet
-	sync.RWMutex
+	*GSync.RWMutex
 }
 
 func NewSafeSet(maxSize int, values ...interface{}) (*safeSet, error) {
@@ -34,7 +34,7 @@ func NewSafeSet(maxSize int, values ...interface{}) (*safeSet, error) {
 
 	return &safeSet{
 		us:      s,
-		RWMutex: sync.RWMutex{},
+		RWMutex: new(GSync.RWMutex),
 	}, nil
 }
 
@@ -46,10 +46,23 @@ func NewSafeSetWithSlice(maxSize int, values []interface{}) (*safeSet, error) {
 
 	return &safeSet{
 		us:      s,
-		RWMutex: sync.RWMutex{},
+		RWMutex: new(GSync.RWMutex),
 	}, nil
 }
 
+// NewSafeSetWithLocker 返回使用m保护的集合，m可以由GSync.NewRWMutexWithLocker创建，为nil时与NewSafeSetWithSlice相同
+func NewSafeSetWithLocker(m *GSync.RWMutex, maxSize int, values []interface{}) (*safeSet, error) {
+	s, err := NewSafeSetWithSlice(maxSize, values)
+	if err != nil {
+		return nil, err
+	}
+	if m != nil {
+		s.RWMutex = m
+	}
+
+	return s, nil
+}
+
 func (set *safeSet) Insert(value interface{}) error {
 	set.Lock()
 	err := set.us.Insert(value)
@@ -105,7 +118,7 @@ func (set *safeSet) Union(other Set) Set {
 	unsafeUnion := set.us.Union(o.us).(*unsafeSet)
 	ret := &safeSet{
 		us:      unsafeUnion,
-		RWMutex: sync.RWMutex{},
+		RWMutex: new(GSync.RWMutex),
 	}
 	set.RUnlock()
 	o.RUnlock()
@@ -122,7 +135,7 @@ func (set *safeSet) Intersect(other Set) Set {
 	unsafeIntersection := set.us.Intersect(o.us).(*unsafeSet)
 	ret := &safeSet{
 		us:      unsafeIntersection,
-		RWMutex: sync.RWMutex{},
+		RWMutex: new(GSync.RWMutex),
 	}
 	set.RUnlock()
 	o.RUnlock()
@@ -139,7 +152,7 @@ func (set *safeSet) Difference(other Set) Set {
 	unsafeDifference := set.us.Difference(o.us).(*unsafeSet)
 	ret := &safeSet{
 		us:      unsafeDifference,
-		RWMutex: sync.RWMutex{},
+		RWMutex: new(GSync.RWMutex),
 	}
 	set.RUnlock()
 	o.RUnlock()
@@ -155,7 +168,7 @@ func (set *safeSet) SymmetricDifference(other Set) Set {
 	unsafeDifference := set.us.SymmetricDifference(o.us).(*unsafeSet)
 	ret := &safeSet{
 		us:      unsafeDifference,
-		RWMutex: sync.RWMutex{},
+		RWMutex: new(GSync.RWMutex),
 	}
 	set.RUnlock()
 	o.RUnlock()
@@ -224,7 +237,7 @@ func (set *safeSet) Clone() Set {
 	unsafeClone := set.us.Clone().(*unsafeSet)
 	return &safeSet{
 		us:      unsafeClone,
-		RWMutex: sync.RWMutex{},
+		RWMutex: new(GSync.RWMutex),
 	}
 }
 
@@ -244,7 +257,7 @@ func (set *safeSet) CartesianProduct(other Set) Set {
 	unsafeCartProduct := set.us.CartesianProduct(o.us).(*unsafeSet)
 	ret := &safeSet{
 		us:      unsafeCartProduct,
-		RWMutex: sync.RWMutex{},
+		RWMutex: new(GSync.RWMutex),
 	}
 	set.RUnlock()
 	o.RUnlock()
@@ -325,3 +338,31 @@ func (set *safeSet) UnmarshalJSON(p []byte) error {
 
 	return err
 }
+
+// Do 持有写锁执行f，f中对u的多次操作整体上是原子的
+// u是不加锁的内部集合，不能在f返回后继续使用；f返回错误时已经做出的修改不会回滚，需要回滚时使用Container.Transact
+func (set *safeSet) Do(f func(u Set) error) error {
+	set.Lock()
+	defer set.Unlock()
+
+	return f(set.us)
+}
+
+// Locker 返回保护集合的读写锁
+func (set *safeSet) Locker() *GSync.RWMutex {
+	return set.RWMutex
+}
+
+// UnsafeView 返回不加锁的内部集合，只能在持有锁时使用
+func (set *safeSet) UnsafeView() interface{} {
+	return set.us
+}
+
+// Snapshot 保存集合当前的内容，返回的函数把集合恢复到保存时的状态，调用时必须持有写锁
+func (set *safeSet) Snapshot() func() {
+	old := set.us.clone()
+
+	return func() {
+		*set.us = *old
+	}
+}
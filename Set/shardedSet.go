@@ -0,0 +1,311 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Set
+
+import (
+	"GTL/Container"
+	"GTL/GSync"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+type setShard struct {
+	m     sync.RWMutex
+	items map[interface{}]struct{}
+}
+
+// shardedSet 分片的并发集合
+// 元素根据哈希值分配到不同的分片，每个分片有自己的读写锁，不同分片上的操作互不阻塞；
+// 涉及多个分片的批量操作按照固定顺序对分片加锁，避免死锁
+type shardedSet struct {
+	shards []*setShard
+}
+
+// NewShardedSet 返回一个有shardCount个分片的集合
+func NewShardedSet(shardCount int, values ...interface{}) (*shardedSet, error) {
+	if shardCount <= 0 {
+		return nil, errors.New("Shard count must be positive.")
+	}
+
+	s := &shardedSet{shards: make([]*setShard, shardCount)}
+	for i := range s.shards {
+		s.shards[i] = &setShard{items: make(map[interface{}]struct{})}
+	}
+	s.InsertAll(values...)
+
+	return s, nil
+}
+
+// shardIndex 返回元素所在分片的下标，无法计算哈希值的元素放在第0个分片
+func (s *shardedSet) shardIndex(value interface{}) int {
+	h, err := Container.Hash64(value)
+	if err != nil {
+		return 0
+	}
+
+	return int(h % uint64(len(s.shards)))
+}
+
+func (s *shardedSet) shard(value interface{}) *setShard {
+	return s.shards[s.shardIndex(value)]
+}
+
+func (s *shardedSet) Insert(value interface{}) {
+	sh := s.shard(value)
+	sh.m.Lock()
+	sh.items[value] = struct{}{}
+	sh.m.Unlock()
+}
+
+// InsertAll 批量插入元素，所有相关分片同时加锁，其他go程不会观察到只插入了一部分的状态
+func (s *shardedSet) InsertAll(values ...interface{}) {
+	groups := make(map[int][]interface{})
+	ml := GSync.NewMultiLock()
+	for _, v := range values {
+		i := s.shardIndex(v)
+		groups[i] = append(groups[i], v)
+		ml.Add(&s.shards[i].m, true)
+	}
+
+	ml.Lock()
+	for i, vs := range groups {
+		for _, v := range vs {
+			s.shards[i].items[v] = struct{}{}
+		}
+	}
+	ml.Unlock()
+}
+
+func (s *shardedSet) Remove(value interface{}) {
+	sh := s.shard(value)
+	sh.m.Lock()
+	delete(sh.items, value)
+	sh.m.Unlock()
+}
+
+func (s *shardedSet) Contains(values ...interface{}) bool {
+	for _, v := range values {
+		sh := s.shard(v)
+		sh.m.RLock()
+		_, ok := sh.items[v]
+		sh.m.RUnlock()
+
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// LoadOrStore 元素不存在时插入元素，返回元素在插入前是否已经存在，整个过程是原子的
+func (s *shardedSet) LoadOrStore(value interface{}) bool {
+	sh := s.shard(value)
+	sh.m.Lock()
+	defer sh.m.Unlock()
+
+	if _, ok := sh.items[value]; ok {
+		return true
+	}
+	sh.items[value] = struct{}{}
+
+	return false
+}
+
+// Compute 在持有分片锁的情况下调用fn，fn的参数表示元素当前是否存在，返回值决定元素之后是否存在
+// fn中不能再访问该集合
+func (s *shardedSet) Compute(value interface{}, fn func(exists bool) bool) bool {
+	sh := s.shard(value)
+	sh.m.Lock()
+	defer sh.m.Unlock()
+
+	_, ok := sh.items[value]
+	keep := fn(ok)
+	if keep {
+		sh.items[value] = struct{}{}
+	} else {
+		delete(sh.items, value)
+	}
+
+	return keep
+}
+
+// Range 依次遍历每个分片中的元素，f返回false时停止遍历
+// 遍历某个分片时持有该分片的读锁，因此f中不能修改该集合
+func (s *shardedSet) Range(f func(value interface{}) bool) {
+	for _, sh := range s.shards {
+		sh.m.RLock()
+		for v := range sh.items {
+			if !f(v) {
+				sh.m.RUnlock()
+				return
+			}
+		}
+		sh.m.RUnlock()
+	}
+}
+
+func (s *shardedSet) Len() int {
+	n := 0
+	for _, sh := range s.shards {
+		sh.m.RLock()
+		n += len(sh.items)
+		sh.m.RUnlock()
+	}
+
+	return n
+}
+
+// lockAll 对s和other的所有分片加读锁，返回的MultiLock用于解锁
+func (s *shardedSet) lockAll(other *shardedSet) *GSync.MultiLock {
+	ml := GSync.NewMultiLock()
+	for _, sh := range s.shards {
+		ml.Add(&sh.m, false)
+	}
+	for _, sh := range other.shards {
+		ml.Add(&sh.m, false)
+	}
+	ml.Lock()
+
+	return ml
+}
+
+// has 判断元素是否存在，调用者需要持有对应分片的锁
+func (s *shardedSet) has(value interface{}) bool {
+	_, ok := s.shard(value).items[value]
+	return ok
+}
+
+// combine 在锁住两个集合所有分片的情况下，将满足keep的元素放入一个新集合
+// fromOther为false时只需要检查s中的元素
+func (s *shardedSet) combine(other *shardedSet, fromOther bool, keep func(inS, inOther bool) bool) *shardedSet {
+	ret, _ := NewShardedSet(len(s.shards))
+
+	ml := s.lockAll(other)
+	defer ml.Unlock()
+
+	for _, sh := range s.shards {
+		for v := range sh.items {
+			if keep(true, other.has(v)) {
+				ret.shard(v).items[v] = struct{}{}
+			}
+		}
+	}
+
+	if fromOther {
+		for _, sh := range other.shards {
+			for v := range sh.items {
+				if keep(s.has(v), true) {
+					ret.shard(v).items[v] = struct{}{}
+				}
+			}
+		}
+	}
+
+	return ret
+}
+
+// Union 求该集合s和other的并集
+func (s *shardedSet) Union(other *shardedSet) *shardedSet {
+	return s.combine(other, true, func(inS, inOther bool) bool {
+		return true
+	})
+}
+
+// Intersect 求该集合s和other的交集
+func (s *shardedSet) Intersect(other *shardedSet) *shardedSet {
+	return s.combine(other, false, func(inS, inOther bool) bool {
+		return inOther
+	})
+}
+
+// Difference 求s - other差集
+func (s *shardedSet) Difference(other *shardedSet) *shardedSet {
+	return s.combine(other, false, func(inS, inOther bool) bool {
+		return !inOther
+	})
+}
+
+// SymmetricDifference 求该集合s和other的对称差集
+func (s *shardedSet) SymmetricDifference(other *shardedSet) *shardedSet {
+	return s.combine(other, true, func(inS, inOther bool) bool {
+		return inS != inOther
+	})
+}
+
+// Equal 判断两个集合是否相等
+func (s *shardedSet) Equal(other *shardedSet) bool {
+	ml := s.lockAll(other)
+	defer ml.Unlock()
+
+	n := 0
+	for _, sh := range s.shards {
+		n += len(sh.items)
+		for v := range sh.items {
+			if !other.has(v) {
+				return false
+			}
+		}
+	}
+	for _, sh := range other.shards {
+		n -= len(sh.items)
+	}
+
+	return n == 0
+}
+
+func (s *shardedSet) Clear() {
+	ml := GSync.NewMultiLock()
+	for _, sh := range s.shards {
+		ml.Add(&sh.m, true)
+	}
+
+	ml.Lock()
+	for _, sh := range s.shards {
+		sh.items = make(map[interface{}]struct{})
+	}
+	ml.Unlock()
+}
+
+func (s *shardedSet) ToSlice() []interface{} {
+	ret := make([]interface{}, 0, s.Len())
+	s.Range(func(value interface{}) bool {
+		ret = append(ret, value)
+		return true
+	})
+
+	return ret
+}
+
+// ToSet 将shardedSet转换为unsafeSet
+func (s *shardedSet) ToSet() Set {
+	ret, _ := NewUnsafeSetWithSlice(-1, s.ToSlice())
+	return ret
+}
+
+func (s *shardedSet) String() string {
+	items := make([]string, 0, s.Len())
+	s.Range(func(value interface{}) bool {
+		items = append(items, fmt.Sprintf("%v", value))
+		return true
+	})
+
+	return fmt.Sprintf("ShardedSet{%s}", strings.Join(items, ", "))
+}
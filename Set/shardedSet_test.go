@@ -0,0 +1,179 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Set
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func sortedInts(values []interface{}) []int {
+	ret := make([]int, len(values))
+	for i, v := range values {
+		ret[i] = v.(int)
+	}
+	sort.Ints(ret)
+
+	return ret
+}
+
+func intRange(start, end int) []interface{} {
+	ret := make([]interface{}, 0, end-start)
+	for i := start; i < end; i++ {
+		ret = append(ret, i)
+	}
+
+	return ret
+}
+
+// 并发的LoadOrStore只有一个调用者插入元素；Compute的判断和修改是原子的
+func TestShardedSetLoadOrStoreAndCompute(t *testing.T) {
+	const goroutines = 8
+	s, err := NewShardedSet(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	inserted := make(chan struct{}, goroutines)
+	toggles := make(chan bool, goroutines*101)
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			if !s.LoadOrStore("once") {
+				inserted <- struct{}{}
+			}
+			for j := 0; j < 101; j++ {
+				// 每次调用翻转元素是否存在
+				toggles <- s.Compute("flag", func(exists bool) bool {
+					return !exists
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	close(toggles)
+
+	if len(inserted) != 1 {
+		t.Fatalf("%d LoadOrStore calls inserted the element, want 1", len(inserted))
+	}
+	present := 0
+	for keep := range toggles {
+		if keep {
+			present++
+		}
+	}
+	// 翻转了偶数次，插入与删除各占一半
+	if present != goroutines*101/2 || s.Contains("flag") {
+		t.Fatalf("Compute inserted %d times, Contains = %v", present, s.Contains("flag"))
+	}
+}
+
+// 分片数量不同的集合之间的集合运算
+func TestShardedSetOperations(t *testing.T) {
+	a, _ := NewShardedSet(3, intRange(0, 10)...)
+	b, _ := NewShardedSet(5, intRange(5, 15)...)
+
+	cases := []struct {
+		name string
+		got  *shardedSet
+		want []interface{}
+	}{
+		{"Union", a.Union(b), intRange(0, 15)},
+		{"Intersect", a.Intersect(b), intRange(5, 10)},
+		{"Difference", a.Difference(b), intRange(0, 5)},
+		{"SymmetricDifference", a.SymmetricDifference(b), append(intRange(0, 5), intRange(10, 15)...)},
+	}
+	for _, c := range cases {
+		got, want := sortedInts(c.got.ToSlice()), sortedInts(c.want)
+		if len(got) != len(want) {
+			t.Fatalf("%s = %v, want %v", c.name, got, want)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("%s = %v, want %v", c.name, got, want)
+			}
+		}
+	}
+
+	c, _ := NewShardedSet(7, intRange(0, 10)...)
+	if !a.Equal(c) || a.Equal(b) {
+		t.Fatal("Equal does not compare elements across shard counts")
+	}
+	c.Remove(3)
+	c.Insert(100)
+	if a.Equal(c) {
+		t.Fatal("sets of the same size with different elements are equal")
+	}
+}
+
+// 两个集合互相做集合运算，同时有go程修改它们，所有分片按照固定顺序加锁，不会死锁
+func TestShardedSetConcurrentOperations(t *testing.T) {
+	a, _ := NewShardedSet(4, intRange(0, 100)...)
+	b, _ := NewShardedSet(4, intRange(50, 150)...)
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if u := a.Union(b); u.Len() < 100 {
+				t.Errorf("Union has %d elements, want at least 100", u.Len())
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			b.Intersect(a)
+			b.Equal(a)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			a.InsertAll(i+1000, i+2000, i+3000)
+			a.Remove(i + 1000)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			b.InsertAll(i, i+5000)
+			b.SymmetricDifference(a)
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("set operations deadlocked")
+	}
+
+	if a.Len() != 100+400 || !a.Contains(2000, 3199) || a.Contains(1000) {
+		t.Fatalf("a has %d elements after concurrent updates, want 500", a.Len())
+	}
+}
@@ -0,0 +1,50 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Set
+
+import (
+	"fmt"
+	"sync"
+)
+
+func ShardedSetExample() {
+	s, err := NewShardedSet(16)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Insert(i*100 + j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	fmt.Println("s.Len =", s.Len())
+	fmt.Println("s.LoadOrStore =", s.LoadOrStore(1), s.LoadOrStore(1000))
+
+	other, _ := NewShardedSet(4, 1, 2, 3, 2000)
+	fmt.Println("s.Intersect =", s.Intersect(other).String())
+	fmt.Println("s.Union.Len =", s.Union(other).Len())
+}
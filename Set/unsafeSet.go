@@ -262,13 +262,26 @@ func (s *unsafeSet) Clone() Set {
 	return clonedSet
 }
 
+// clone 复制出一个内容相同的集合，与Clone不同的是返回具体类型
+func (s *unsafeSet) clone() *unsafeSet {
+	m := make(map[interface{}]struct{}, len(s.m))
+	for elem := range s.m {
+		m[elem] = struct{}{}
+	}
+
+	return &unsafeSet{
+		m:       m,
+		maxSize: s.maxSize,
+	}
+}
+
 func (s *unsafeSet) String() string {
 	items := make([]string, 0, s.Size())
 
 	for elem := range s.m {
 		items = append(items, fmt.Sprintf("%v", elem))
 	}
-	return fmt.Sprintf("Set{%m}", strings.Join(items, ", "))
+	return fmt.Sprintf("Set{%s}", strings.Join(items, ", "))
 }
 
 func (pair OrderedPair) String() string {
@@ -360,7 +373,7 @@ func (s *unsafeSet) MarshalJSON() ([]byte, error) {
 		items = append(items, string(b))
 	}
 
-	return []byte(fmt.Sprintf("[%m]", strings.Join(items, ","))), nil
+	return []byte(fmt.Sprintf("[%s]", strings.Join(items, ","))), nil
 }
 
 // UnmarshalJSON 从给定的Json数组中解析出一个集合,数字将被解析为json.Number
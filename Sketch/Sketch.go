@@ -0,0 +1,33 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Sketch
+
+// Sketch 是概率型统计结构的公共接口，同类型且参数相同的Sketch可以相互合并，
+// 因此可以在多个worker上分别统计，再将编码后的结果汇总
+type Sketch interface {
+	Clear()
+
+	// MarshalBinary 将Sketch编码为字节切片，第一个字节是编码格式的版本号，所有整数均以小端序存储
+	MarshalBinary() ([]byte, error)
+
+	// UnmarshalBinary 从MarshalBinary产生的字节切片中解析出Sketch
+	UnmarshalBinary(b []byte) error
+}
+
+// encodingVersion 当前的编码格式版本号
+const encodingVersion uint8 = 1
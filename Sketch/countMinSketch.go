@@ -0,0 +1,475 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Sketch
+
+import (
+	"GTL/Container"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+)
+
+// HeavyHitter 表示一个出现频率较高的元素及其估计次数
+type HeavyHitter struct {
+	Value interface{}
+	Count uint64
+}
+
+// hitterKey 元素的128位哈希值，用来标识一个元素，这样不可比较的类型(如[]byte)也可以被追踪
+type hitterKey [2]uint64
+
+// hitter 追踪中的高频元素，known为false表示元素是从编码中恢复的，只知道它的哈希值
+type hitter struct {
+	value interface{}
+	known bool
+	count uint64
+}
+
+// countMinSketch 用于估计元素出现的次数
+// depth行计数器各自使用一个哈希函数，估计值取各行计数的最小值，
+// 估计值不会小于真实值，并以1 - delta的概率不超过真实值 + epsilon * 总次数
+type countMinSketch struct {
+	width    uint32
+	depth    uint32
+	total    uint64
+	counters []uint64
+
+	// topK 追踪的高频元素数量，为0时不追踪
+	topK    int
+	hitters map[hitterKey]*hitter
+}
+
+// NewCountMinSketch 根据误差epsilon和置信度1 - delta计算计数器的行数与列数，
+// topK为需要追踪的高频元素数量，为0时不追踪
+func NewCountMinSketch(epsilon, delta float64, topK int) (*countMinSketch, error) {
+	if epsilon <= 0 || epsilon >= 1 {
+		return nil, errors.New("Epsilon must be in (0, 1).")
+	}
+	if delta <= 0 || delta >= 1 {
+		return nil, errors.New("Delta must be in (0, 1).")
+	}
+	if topK < 0 {
+		return nil, errors.New("TopK must not be negative.")
+	}
+
+	width := uint32(math.Ceil(math.E / epsilon))
+	depth := uint32(math.Ceil(math.Log(1 / delta)))
+
+	return newCountMinSketch(width, depth, topK), nil
+}
+
+func newCountMinSketch(width, depth uint32, topK int) *countMinSketch {
+	return &countMinSketch{
+		width:    width,
+		depth:    depth,
+		total:    0,
+		counters: make([]uint64, uint64(width)*uint64(depth)),
+		topK:     topK,
+		hitters:  make(map[hitterKey]*hitter),
+	}
+}
+
+// cell 返回第row行中元素对应的计数器下标
+func (s *countMinSketch) cell(row uint32, h1, h2 uint64) int {
+	return int(uint64(row)*uint64(s.width) + (h1+uint64(row)*h2)%uint64(s.width))
+}
+
+// Add 将元素value的出现次数增加count
+func (s *countMinSketch) Add(value interface{}, count uint64) error {
+	h1, h2, err := Container.Hash128(value)
+	if err != nil {
+		return err
+	}
+
+	for row := uint32(0); row < s.depth; row++ {
+		s.counters[s.cell(row, h1, h2)] += count
+	}
+	s.total += count
+
+	if s.topK > 0 {
+		s.track(hitterKey{h1, h2}, value, true, s.estimate(h1, h2))
+	}
+
+	return nil
+}
+
+// Estimate 返回元素value出现次数的估计值
+func (s *countMinSketch) Estimate(value interface{}) uint64 {
+	h1, h2, err := Container.Hash128(value)
+	if err != nil {
+		return 0
+	}
+
+	return s.estimate(h1, h2)
+}
+
+func (s *countMinSketch) estimate(h1, h2 uint64) uint64 {
+	ret := uint64(math.MaxUint64)
+	for row := uint32(0); row < s.depth; row++ {
+		if c := s.counters[s.cell(row, h1, h2)]; c < ret {
+			ret = c
+		}
+	}
+
+	return ret
+}
+
+// track 更新高频元素列表，列表已满时替换掉次数最少的元素
+// known为false时value没有意义，已追踪的元素值未知时使用新的value补全
+func (s *countMinSketch) track(key hitterKey, value interface{}, known bool, count uint64) {
+	if h, ok := s.hitters[key]; ok {
+		h.count = count
+		if known && !h.known {
+			h.value, h.known = value, true
+		}
+		return
+	}
+
+	if len(s.hitters) < s.topK {
+		s.hitters[key] = &hitter{value: value, known: known, count: count}
+		return
+	}
+
+	var minKey hitterKey
+	minCount := uint64(math.MaxUint64)
+	for k, h := range s.hitters {
+		if h.count < minCount {
+			minKey, minCount = k, h.count
+		}
+	}
+
+	if count > minCount {
+		delete(s.hitters, minKey)
+		s.hitters[key] = &hitter{value: value, known: known, count: count}
+	}
+}
+
+// sortedHitterKeys 返回按估计次数从大到小排列的高频元素，次数相同时按哈希值排列，使顺序与map的遍历顺序无关
+func (s *countMinSketch) sortedHitterKeys() []hitterKey {
+	keys := make([]hitterKey, 0, len(s.hitters))
+	for k := range s.hitters {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ci, cj := s.hitters[keys[i]].count, s.hitters[keys[j]].count
+		if ci != cj {
+			return ci > cj
+		}
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+
+	return keys
+}
+
+// HeavyHitters 返回追踪到的高频元素，按估计次数从大到小排列
+// 从编码中恢复的元素如果不是内置的基本类型，在再次被Add之前Value为nil
+func (s *countMinSketch) HeavyHitters() []HeavyHitter {
+	ret := make([]HeavyHitter, 0, len(s.hitters))
+	for _, k := range s.sortedHitterKeys() {
+		h := s.hitters[k]
+		ret = append(ret, HeavyHitter{Value: h.value, Count: h.count})
+	}
+
+	return ret
+}
+
+// Total 返回所有元素出现次数的总和
+func (s *countMinSketch) Total() uint64 {
+	return s.total
+}
+
+// Merge 将other合并到s中，两者的行数与列数必须相同，高频元素会根据合并后的计数重新评估
+func (s *countMinSketch) Merge(other *countMinSketch) error {
+	if s.width != other.width || s.depth != other.depth {
+		return errors.New("Count-min sketches have different dimensions.")
+	}
+
+	for i, c := range other.counters {
+		s.counters[i] += c
+	}
+	s.total += other.total
+
+	if s.topK > 0 {
+		for k, h := range s.hitters {
+			h.count = s.estimate(k[0], k[1])
+		}
+		for k, h := range other.hitters {
+			s.track(k, h.value, h.known, s.estimate(k[0], k[1]))
+		}
+	}
+
+	return nil
+}
+
+func (s *countMinSketch) Clear() {
+	for i := range s.counters {
+		s.counters[i] = 0
+	}
+	s.total = 0
+	s.hitters = make(map[hitterKey]*hitter)
+}
+
+// countMinHeader 编码时的头部
+type countMinHeader struct {
+	Version uint8
+	Width   uint32
+	Depth   uint32
+	Total   uint64
+	TopK    uint32
+}
+
+// hitterHeader 编码时每个高频元素的头部，其后紧跟Len字节的元素值
+type hitterHeader struct {
+	H1    uint64
+	H2    uint64
+	Count uint64
+	Kind  uint8
+	Len   uint32
+}
+
+// 高频元素值的编码类型，只有内置的基本类型能够无损地编码与解码，
+// 其他类型(如结构体、指针、chan)只保存哈希值
+const (
+	kindUnknown uint8 = iota
+	kindNil
+	kindBool
+	kindString
+	kindBytes
+	kindInt
+	kindInt8
+	kindInt16
+	kindInt32
+	kindInt64
+	kindUint
+	kindUint8
+	kindUint16
+	kindUint32
+	kindUint64
+	kindFloat32
+	kindFloat64
+)
+
+// encodeValue 返回元素值的编码类型与编码，无法编码时返回kindUnknown
+func encodeValue(value interface{}) (uint8, []byte) {
+	var kind uint8
+	var u uint64
+	switch v := value.(type) {
+	case nil:
+		return kindNil, nil
+	case bool:
+		if v {
+			return kindBool, []byte{1}
+		}
+		return kindBool, []byte{0}
+	case string:
+		return kindString, []byte(v)
+	case []byte:
+		return kindBytes, v
+	case int:
+		kind, u = kindInt, uint64(v)
+	case int8:
+		kind, u = kindInt8, uint64(v)
+	case int16:
+		kind, u = kindInt16, uint64(v)
+	case int32:
+		kind, u = kindInt32, uint64(v)
+	case int64:
+		kind, u = kindInt64, uint64(v)
+	case uint:
+		kind, u = kindUint, uint64(v)
+	case uint8:
+		kind, u = kindUint8, uint64(v)
+	case uint16:
+		kind, u = kindUint16, uint64(v)
+	case uint32:
+		kind, u = kindUint32, uint64(v)
+	case uint64:
+		kind, u = kindUint64, v
+	case float32:
+		kind, u = kindFloat32, uint64(math.Float32bits(v))
+	case float64:
+		kind, u = kindFloat64, math.Float64bits(v)
+	default:
+		return kindUnknown, nil
+	}
+
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, u)
+
+	return kind, b
+}
+
+// decodeValue 是encodeValue的逆过程，编码不合法时返回false
+func decodeValue(kind uint8, b []byte) (interface{}, bool) {
+	switch kind {
+	case kindNil:
+		return nil, len(b) == 0
+	case kindBool:
+		if len(b) != 1 || b[0] > 1 {
+			return nil, false
+		}
+		return b[0] == 1, true
+	case kindString:
+		return string(b), true
+	case kindBytes:
+		return b, true
+	}
+
+	if len(b) != 8 {
+		return nil, false
+	}
+	u := binary.LittleEndian.Uint64(b)
+	switch kind {
+	case kindInt:
+		return int(u), true
+	case kindInt8:
+		return int8(u), true
+	case kindInt16:
+		return int16(u), true
+	case kindInt32:
+		return int32(u), true
+	case kindInt64:
+		return int64(u), true
+	case kindUint:
+		return uint(u), true
+	case kindUint8:
+		return uint8(u), true
+	case kindUint16:
+		return uint16(u), true
+	case kindUint32:
+		return uint32(u), true
+	case kindUint64:
+		return u, true
+	case kindFloat32:
+		return math.Float32frombits(uint32(u)), true
+	case kindFloat64:
+		return math.Float64frombits(u), true
+	}
+
+	return nil, false
+}
+
+// MarshalBinary 编码格式为 header | counters | hitterCount | (hitterHeader | value)...
+// 高频元素按照HeavyHitters的顺序写入，相同内容的sketch总是得到相同的编码
+// 高频元素以哈希值标识，内置基本类型的值会连同类型一起保存，
+// 其他类型的值不保存，解码后在再次被Add之前HeavyHitters中的Value为nil
+func (s *countMinSketch) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+
+	header := countMinHeader{
+		Version: encodingVersion,
+		Width:   s.width,
+		Depth:   s.depth,
+		Total:   s.total,
+		TopK:    uint32(s.topK),
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, header); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, s.counters); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, uint32(len(s.hitters))); err != nil {
+		return nil, err
+	}
+
+	for _, k := range s.sortedHitterKeys() {
+		h := s.hitters[k]
+		kind, v := kindUnknown, []byte(nil)
+		if h.known {
+			kind, v = encodeValue(h.value)
+		}
+
+		hh := hitterHeader{H1: k[0], H2: k[1], Count: h.count, Kind: kind, Len: uint32(len(v))}
+		if err := binary.Write(&buf, binary.LittleEndian, hh); err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *countMinSketch) UnmarshalBinary(b []byte) error {
+	invalid := errors.New("Invalid count-min sketch data.")
+	r := bytes.NewReader(b)
+
+	var header countMinHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	if header.Version != encodingVersion || header.Width == 0 || header.Depth == 0 ||
+		uint64(r.Len()) < uint64(header.Width)*uint64(header.Depth)*8+4 {
+		return invalid
+	}
+
+	t := newCountMinSketch(header.Width, header.Depth, int(header.TopK))
+	t.total = header.Total
+	if err := binary.Read(r, binary.LittleEndian, t.counters); err != nil {
+		return err
+	}
+
+	var n uint32
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return err
+	}
+	if n > header.TopK {
+		return invalid
+	}
+	for i := uint32(0); i < n; i++ {
+		var hh hitterHeader
+		if err := binary.Read(r, binary.LittleEndian, &hh); err != nil {
+			return err
+		}
+		if uint64(r.Len()) < uint64(hh.Len) {
+			return invalid
+		}
+
+		v := make([]byte, hh.Len)
+		_, _ = r.Read(v)
+
+		key := hitterKey{hh.H1, hh.H2}
+		if _, ok := t.hitters[key]; ok {
+			return invalid
+		}
+		h := &hitter{count: hh.Count}
+		if hh.Kind != kindUnknown {
+			value, ok := decodeValue(hh.Kind, v)
+			if !ok {
+				return invalid
+			}
+			// 解码出的值必须与保存的哈希值一致
+			if h1, h2, err := Container.Hash128(value); err != nil || h1 != hh.H1 || h2 != hh.H2 {
+				return invalid
+			}
+			h.value, h.known = value, true
+		} else if hh.Len != 0 {
+			return invalid
+		}
+		t.hitters[key] = h
+	}
+
+	*s = *t
+
+	return nil
+}
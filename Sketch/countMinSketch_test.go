@@ -0,0 +1,183 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Sketch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// 编码与高频元素在map中的遍历顺序无关：重复编码以及按不同顺序加入相同元素得到相同的字节
+func TestCountMinSketchMarshalDeterministic(t *testing.T) {
+	build := func(reverse bool) *countMinSketch {
+		s, err := NewCountMinSketch(0.01, 0.01, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 32; i++ {
+			j := i
+			if reverse {
+				j = 31 - i
+			}
+			// 每两个元素次数相同，检查次数相同时的顺序
+			if err := s.Add(fmt.Sprint("item", j), uint64(j/2+1)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return s
+	}
+
+	s := build(false)
+	want, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		got, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatal("MarshalBinary is not deterministic")
+		}
+	}
+
+	got, err := build(true).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("MarshalBinary depends on insertion order")
+	}
+
+	var decoded countMinSketch
+	if err := decoded.UnmarshalBinary(want); err != nil {
+		t.Fatal(err)
+	}
+	again, err := decoded.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, want) {
+		t.Fatal("re-encoding a decoded sketch changed its bytes")
+	}
+}
+
+// 基本类型的高频元素解码后保持原来的类型，其他类型只保留哈希值，再次Add时补全
+func TestCountMinSketchMarshalHitterValues(t *testing.T) {
+	type point struct{ X, Y int }
+	ch := make(chan int)
+	type node struct{ next *node }
+	cyclic := &node{}
+	cyclic.next = cyclic
+
+	values := []interface{}{
+		nil, true, "s", []byte("b"), int(-1), int8(-8), int16(16), int32(-32), int64(64),
+		uint(1), uint8(9), uint16(17), uint32(33), uint64(65), float32(1.5), float64(-2.5),
+		point{1, 2}, ch, cyclic,
+	}
+	s, err := NewCountMinSketch(0.001, 0.01, len(values))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range values {
+		if err := s.Add(v, uint64(100+i)); err != nil {
+			t.Fatalf("Add(%#v): %v", v, err)
+		}
+	}
+
+	b, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	var decoded countMinSketch
+	if err := decoded.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+
+	hitters := decoded.HeavyHitters()
+	if len(hitters) != len(values) {
+		t.Fatalf("decoded %d hitters, want %d", len(hitters), len(values))
+	}
+	// 次数互不相同，所以HeavyHitters按values的逆序排列
+	for i, h := range hitters {
+		j := len(values) - 1 - i
+		want := values[j]
+		if j >= 16 {
+			want = nil
+		}
+		if !reflect.DeepEqual(h.Value, want) || h.Count != uint64(100+j) {
+			t.Fatalf("hitter %d = %#v, %d, want %#v, %d", i, h.Value, h.Count, want, 100+j)
+		}
+	}
+
+	decoded.Add(ch, 10)
+	if h := decoded.HeavyHitters()[0]; h.Value != ch || h.Count != 127 {
+		t.Fatalf("hitter after Add(ch) = %#v, %d, want ch, 127", h.Value, h.Count)
+	}
+
+	// 合并时也用已知的值补全
+	if err := decoded.Merge(s); err != nil {
+		t.Fatal(err)
+	}
+	if h := decoded.HeavyHitters()[1]; h.Value != cyclic || h.Count != 236 {
+		t.Fatalf("hitter after Merge = %#v, %d, want the cyclic node, 236", h.Value, h.Count)
+	}
+}
+
+// 解码时拒绝超过TopK个高频元素，以及与哈希值不一致的元素值
+func TestCountMinSketchUnmarshalInvalidHitters(t *testing.T) {
+	s, err := NewCountMinSketch(0.1, 0.1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Add("a", 3)
+	s.Add("b", 2)
+	b, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// TopK位于Version、Width、Depth、Total之后
+	const topKOffset = 1 + 4 + 4 + 8
+	tooMany := append([]byte(nil), b...)
+	binary.LittleEndian.PutUint32(tooMany[topKOffset:], 1)
+	var decoded countMinSketch
+	if err := decoded.UnmarshalBinary(tooMany); err == nil {
+		t.Fatal("UnmarshalBinary accepted more hitters than TopK")
+	}
+
+	// 把"a"改成"c"，哈希值不再匹配
+	forged := bytes.Replace(b, []byte("a"), []byte("c"), -1)
+	if bytes.Equal(forged, b) {
+		t.Fatal("test data does not contain the hitter value")
+	}
+	if err := decoded.UnmarshalBinary(forged); err == nil {
+		t.Fatal("UnmarshalBinary accepted a value that does not match its hash")
+	}
+
+	if err := decoded.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.HeavyHitters(); !reflect.DeepEqual(got, s.HeavyHitters()) {
+		t.Fatalf("HeavyHitters() = %v, want %v", got, s.HeavyHitters())
+	}
+}
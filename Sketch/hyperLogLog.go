@@ -0,0 +1,156 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Sketch
+
+import (
+	"GTL/Container"
+	"errors"
+	"math"
+	"math/bits"
+)
+
+const (
+	minPrecision = 4
+	maxPrecision = 18
+)
+
+// hyperLogLog 用于估计不同元素的数量(基数)
+// 哈希值的高precision位选择寄存器，其余位中前导零的个数加一作为观测值，每个寄存器保留最大观测值，
+// 标准误差约为 1.04 / sqrt(2^precision)
+type hyperLogLog struct {
+	precision uint8
+	registers []uint8
+}
+
+// NewHyperLogLog 返回一个精度为precision的hyperLogLog，precision的取值范围为[4, 18]
+func NewHyperLogLog(precision uint8) (*hyperLogLog, error) {
+	if precision < minPrecision || precision > maxPrecision {
+		return nil, errors.New("Precision must be in [4, 18].")
+	}
+
+	return &hyperLogLog{
+		precision: precision,
+		registers: make([]uint8, 1<<precision),
+	}, nil
+}
+
+func (h *hyperLogLog) Add(value interface{}) error {
+	x, err := Container.Hash64(value)
+	if err != nil {
+		return err
+	}
+
+	index := x >> (64 - h.precision)
+	// 在末尾补一个1，保证前导零的个数不超过64 - precision
+	w := x<<h.precision | 1<<(h.precision-1)
+	rho := uint8(bits.LeadingZeros64(w)) + 1
+
+	if rho > h.registers[index] {
+		h.registers[index] = rho
+	}
+
+	return nil
+}
+
+// Count 返回不同元素数量的估计值
+func (h *hyperLogLog) Count() uint64 {
+	m := float64(len(h.registers))
+
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := alpha(len(h.registers)) * m * m / sum
+
+	// 基数较小时偏差较大，改用线性计数
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(estimate + 0.5)
+}
+
+// alpha 修正系数
+func alpha(m int) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	default:
+		return 0.7213 / (1 + 1.079/float64(m))
+	}
+}
+
+// Merge 将other合并到h中，合并后的结果等价于对两者元素的并集进行统计，两者的精度必须相同
+func (h *hyperLogLog) Merge(other *hyperLogLog) error {
+	if h.precision != other.precision {
+		return errors.New("HyperLogLogs have different precisions.")
+	}
+
+	for i, r := range other.registers {
+		if r > h.registers[i] {
+			h.registers[i] = r
+		}
+	}
+
+	return nil
+}
+
+func (h *hyperLogLog) Precision() uint8 {
+	return h.precision
+}
+
+func (h *hyperLogLog) Clear() {
+	for i := range h.registers {
+		h.registers[i] = 0
+	}
+}
+
+// MarshalBinary 编码格式为 version | precision | registers
+func (h *hyperLogLog) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, 2+len(h.registers))
+	b = append(b, encodingVersion, h.precision)
+	b = append(b, h.registers...)
+
+	return b, nil
+}
+
+func (h *hyperLogLog) UnmarshalBinary(b []byte) error {
+	if len(b) < 2 || b[0] != encodingVersion {
+		return errors.New("Invalid hyperloglog data.")
+	}
+
+	precision := b[1]
+	if precision < minPrecision || precision > maxPrecision || len(b)-2 != 1<<precision {
+		return errors.New("Invalid hyperloglog data.")
+	}
+
+	h.precision = precision
+	h.registers = make([]uint8, 1<<precision)
+	copy(h.registers, b[2:])
+
+	return nil
+}
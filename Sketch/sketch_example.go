@@ -0,0 +1,90 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Sketch
+
+import "fmt"
+
+func HyperLogLogExample() {
+	h, err := NewHyperLogLog(14)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for i := 0; i < 100000; i++ {
+		_ = h.Add(i % 50000)
+	}
+	fmt.Println("h.Count =", h.Count())
+
+	other, _ := NewHyperLogLog(14)
+	for i := 0; i < 100000; i++ {
+		_ = other.Add(i)
+	}
+	err = h.Merge(other)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("h.Count =", h.Count())
+
+	b, err := h.MarshalBinary()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	h.Clear()
+	err = h.UnmarshalBinary(b)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("h.Count =", h.Count())
+}
+
+func CountMinSketchExample() {
+	s, err := NewCountMinSketch(0.001, 0.01, 3)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for i := 0; i < 10000; i++ {
+		_ = s.Add(i%100, 1)
+		if i%10 == 0 {
+			_ = s.Add("hot", 1)
+		}
+	}
+	fmt.Println("s.Estimate =", s.Estimate("hot"))
+	fmt.Println("s.HeavyHitters =", s.HeavyHitters())
+
+	b, err := s.MarshalBinary()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	other, _ := NewCountMinSketch(0.001, 0.01, 3)
+	err = other.UnmarshalBinary(b)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	err = s.Merge(other)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("s.Total =", s.Total())
+}
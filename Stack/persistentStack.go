@@ -0,0 +1,110 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Stack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type psNode struct {
+	value interface{}
+	next  *psNode
+}
+
+// persistentStack 不可变的栈，使用单链表实现
+// Push和Pop都返回新版本，新版本与旧版本共享栈顶以下的所有结点，可以被多个go程同时读取
+type persistentStack struct {
+	top  *psNode
+	size int
+}
+
+func NewPersistentStack(values ...interface{}) *persistentStack {
+	return NewPersistentStackWithSlice(values)
+}
+
+// NewPersistentStackWithSlice 按顺序将values中的元素入栈，最后一个元素位于栈顶
+func NewPersistentStackWithSlice(values []interface{}) *persistentStack {
+	s := &persistentStack{top: nil, size: 0}
+	for _, value := range values {
+		s = s.Push(value)
+	}
+
+	return s
+}
+
+// Push 返回value入栈后的新版本
+func (s *persistentStack) Push(value interface{}) *persistentStack {
+	return &persistentStack{
+		top:  &psNode{value: value, next: s.top},
+		size: s.size + 1,
+	}
+}
+
+// Pop 返回出栈后的新版本以及栈顶元素
+func (s *persistentStack) Pop() (*persistentStack, interface{}, error) {
+	if s.Empty() {
+		return nil, nil, errors.New("This stack is empty")
+	}
+
+	return &persistentStack{top: s.top.next, size: s.size - 1}, s.top.value, nil
+}
+
+func (s *persistentStack) Top() (interface{}, error) {
+	if s.Empty() {
+		return nil, errors.New("This stack is empty")
+	}
+
+	return s.top.value, nil
+}
+
+func (s *persistentStack) Empty() bool {
+	return s.size == 0
+}
+
+func (s *persistentStack) Size() int {
+	return s.size
+}
+
+// ToSlice 将栈以切片形式返回，栈顶元素位于最后
+func (s *persistentStack) ToSlice() []interface{} {
+	ans := make([]interface{}, s.size)
+	i := s.size - 1
+	for p := s.top; p != nil; p = p.next {
+		ans[i] = p.value
+		i--
+	}
+
+	return ans
+}
+
+func (s *persistentStack) String() string {
+	items := make([]string, 0, s.size)
+	for _, value := range s.ToSlice() {
+		items = append(items, fmt.Sprintf("%v", value))
+	}
+
+	return fmt.Sprintf("persistentStack{%s}", strings.Join(items, ", "))
+}
+
+// MarshalJSON 将Stack中的所有元素以Json数组的形式返回
+func (s *persistentStack) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.ToSlice())
+}
@@ -0,0 +1,85 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Stack
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Push和Pop返回的新版本与旧版本共享结点，旧版本保持不变
+func TestPersistentStackVersions(t *testing.T) {
+	s1 := NewPersistentStack(1, 2, 3)
+	s2 := s1.Push(4)
+	s3, top, err := s1.Pop()
+	if err != nil || top != 3 {
+		t.Fatalf("Pop() = %v, %v, want 3", top, err)
+	}
+	s4 := s3.Push(5)
+
+	for _, c := range []struct {
+		s    *persistentStack
+		want []interface{}
+	}{
+		{s1, []interface{}{1, 2, 3}},
+		{s2, []interface{}{1, 2, 3, 4}},
+		{s3, []interface{}{1, 2}},
+		{s4, []interface{}{1, 2, 5}},
+	} {
+		if got := c.s.ToSlice(); !reflect.DeepEqual(got, c.want) || c.s.Size() != len(c.want) {
+			t.Fatalf("ToSlice() = %v with Size() %d, want %v", got, c.s.Size(), c.want)
+		}
+		if top, _ := c.s.Top(); top != c.want[len(c.want)-1] {
+			t.Fatalf("Top() = %v, want %v", top, c.want[len(c.want)-1])
+		}
+	}
+
+	// 栈顶以下的结点是共享的
+	if s2.top.next != s1.top || s3.top != s1.top.next || s4.top.next != s3.top {
+		t.Fatal("versions do not share the nodes below the top")
+	}
+
+	if got, err := s4.MarshalJSON(); err != nil || string(got) != "[1,2,5]" {
+		t.Fatalf("MarshalJSON() = %s, %v, want [1,2,5]", got, err)
+	}
+	if got := s4.String(); got != "persistentStack{1, 2, 5}" {
+		t.Fatalf("String() = %q", got)
+	}
+}
+
+// 空栈上的Pop和Top返回错误，出栈到空之后还可以继续入栈
+func TestPersistentStackEmpty(t *testing.T) {
+	empty := NewPersistentStack()
+	if !empty.Empty() || len(empty.ToSlice()) != 0 {
+		t.Fatal("new stack is not empty")
+	}
+	if _, _, err := empty.Pop(); err == nil {
+		t.Fatal("Pop() on an empty stack succeeded")
+	}
+	if _, err := empty.Top(); err == nil {
+		t.Fatal("Top() on an empty stack succeeded")
+	}
+
+	s, _, err := empty.Push("x").Pop()
+	if err != nil || !s.Empty() {
+		t.Fatalf("Push then Pop left Size() %d, err %v", s.Size(), err)
+	}
+	if top, _ := s.Push("y").Top(); top != "y" || !empty.Empty() {
+		t.Fatalf("Top() = %v, want y with the original still empty", top)
+	}
+}
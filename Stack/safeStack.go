@@ -17,11 +17,40 @@
 
 package Stack
 
-import "sync"
+import "GTL/GSync"
 
 type safeStack struct {
 	us *unsafeStack
-	m  *sync.RWMutex
+	m  *GSync.RWMutex
+}
+
+func NewSafeStack(maxSize int, values ...interface{}) (*safeStack, error) {
+	return NewSafeStackWithSlice(maxSize, values)
+}
+
+func NewSafeStackWithSlice(maxSize int, values []interface{}) (*safeStack, error) {
+	us, err := NewUnsafeStackWithSlice(maxSize, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &safeStack{
+		us: us,
+		m:  new(GSync.RWMutex),
+	}, nil
+}
+
+// NewSafeStackWithLocker 返回使用m保护的栈，m可以由GSync.NewRWMutexWithLocker创建，为nil时与NewSafeStackWithSlice相同
+func NewSafeStackWithLocker(m *GSync.RWMutex, maxSize int, values []interface{}) (*safeStack, error) {
+	s, err := NewSafeStackWithSlice(maxSize, values)
+	if err != nil {
+		return nil, err
+	}
+	if m != nil {
+		s.m = m
+	}
+
+	return s, nil
 }
 
 func (s *safeStack) Push(value interface{}) error {
@@ -121,3 +150,31 @@ func (s *safeStack) UnmarshalJSON(b []byte) error {
 
 	return s.us.UnmarshalJSON(b)
 }
+
+// Do 持有写锁执行f，f中对u的多次操作整体上是原子的
+// u是不加锁的内部栈，不能在f返回后继续使用；f返回错误时已经做出的修改不会回滚，需要回滚时使用Container.Transact
+func (s *safeStack) Do(f func(u Stack) error) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return f(s.us)
+}
+
+// Locker 返回保护栈的读写锁
+func (s *safeStack) Locker() *GSync.RWMutex {
+	return s.m
+}
+
+// UnsafeView 返回不加锁的内部栈，只能在持有锁时使用
+func (s *safeStack) UnsafeView() interface{} {
+	return s.us
+}
+
+// Snapshot 保存栈当前的内容，返回的函数把栈恢复到保存时的状态，调用时必须持有写锁
+func (s *safeStack) Snapshot() func() {
+	old := s.us.clone()
+
+	return func() {
+		*s.us = *old
+	}
+}
@@ -58,3 +58,14 @@ func UnsafeStackExample() {
 func SafeStackExample() {
 
 }
+
+func PersistentStackExample() {
+	s1 := NewPersistentStack(1, 2, 3)
+
+	// 回溯搜索时每个分支从同一个版本出发，互不影响
+	left := s1.Push("left")
+	right := s1.Push("right")
+	back, top, _ := left.Pop()
+
+	fmt.Println(s1, left, right, back, top)
+}
@@ -212,7 +212,7 @@ func (s *unsafeStack) String() string {
 
 // ToSlice 将切片以切片形式返回
 func (s *unsafeStack) ToSlice() []interface{} {
-	ans := make([]interface{}, s.size)
+	ans := make([]interface{}, 0, s.size)
 
 	for p := s.head.next; p != nil; p = p.next {
 		ans = append(ans, p.value)
@@ -221,6 +221,17 @@ func (s *unsafeStack) ToSlice() []interface{} {
 	return ans
 }
 
+// clone 复制出一个内容相同的栈，元素本身不会被复制
+func (s *unsafeStack) clone() *unsafeStack {
+	c, _ := NewUnsafeStack(-1)
+	for p := s.head.next; p != nil; p = p.next {
+		_ = c.Push(p.value)
+	}
+	c.maxSize = s.maxSize
+
+	return c
+}
+
 // MarshalJSON 将Stack中的所有元素以Json数组的形式返回
 func (s *unsafeStack) MarshalJSON() ([]byte, error) {
 	items := make([]string, 0, s.Size())
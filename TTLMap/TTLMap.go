@@ -0,0 +1,71 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package TTLMap
+
+import "time"
+
+type TTLMap interface {
+	// Set 设置key对应的值，ttl为0时使用默认的过期时间，ttl为负数或默认过期时间为0时永不过期
+	Set(key, value interface{}, ttl time.Duration)
+
+	// Get 返回key对应的值，已过期的元素会在此时被删除并触发过期回调
+	Get(key interface{}) (interface{}, bool)
+
+	// Touch 将key的过期时间重置为当前时间加上它的ttl，返回key是否存在
+	Touch(key interface{}) bool
+
+	// TTL 返回key剩余的存活时间，永不过期的元素返回0
+	TTL(key interface{}) (time.Duration, bool)
+
+	// Remove 删除key，返回key是否存在，被删除的元素不会触发过期回调
+	Remove(key interface{}) bool
+
+	// OnExpire 设置元素过期时的回调
+	OnExpire(f ExpireCallback)
+
+	// DeleteExpired 删除所有已过期的元素，返回删除的数量
+	DeleteExpired() int
+
+	// Len 返回未过期元素的数量
+	Len() int
+
+	Clear()
+}
+
+// ExpireCallback 元素过期被删除时调用
+// 对于safeTTLMap，回调在持有锁的情况下执行，因此回调中不能再访问该TTLMap
+type ExpireCallback func(key, value interface{})
+
+// Clock 提供当前时间和定时，测试时可以注入一个手动控制的时钟
+type Clock interface {
+	Now() time.Time
+
+	// After 在d之后向返回的通道发送当时的时间，后台清理go程使用它定时
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock 使用系统时间
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,185 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package TTLMap
+
+import (
+	"GTL/GSync"
+	"errors"
+	"time"
+)
+
+// safeTTLMap 线程安全的TTLMap，可以开启一个后台清理go程定期删除过期元素
+// Get可能删除过期元素，所以和Set一样需要获取写锁
+type safeTTLMap struct {
+	ut *unsafeTTLMap
+	m  *GSync.RWMutex
+
+	// stop 关闭时通知后台清理go程退出，为nil表示清理go程没有运行
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewSafeTTLMap(defaultTTL time.Duration, clock Clock) (*safeTTLMap, error) {
+	t, err := NewUnsafeTTLMap(defaultTTL, clock)
+	if err != nil {
+		return nil, err
+	}
+
+	return &safeTTLMap{
+		ut:   t,
+		m:    new(GSync.RWMutex),
+		stop: nil,
+		done: nil,
+	}, nil
+}
+
+// NewSafeTTLMapWithLocker 返回使用m保护的TTLMap，m可以由GSync.NewRWMutexWithLocker创建，为nil时与NewSafeTTLMap相同
+func NewSafeTTLMapWithLocker(m *GSync.RWMutex, defaultTTL time.Duration, clock Clock) (*safeTTLMap, error) {
+	t, err := NewSafeTTLMap(defaultTTL, clock)
+	if err != nil {
+		return nil, err
+	}
+	if m != nil {
+		t.m = m
+	}
+
+	return t, nil
+}
+
+// StartJanitor 开启后台清理go程，按照创建时注入的时钟每隔interval调用一次DeleteExpired
+func (t *safeTTLMap) StartJanitor(interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("Interval must be positive.")
+	}
+
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	if t.stop != nil {
+		return errors.New("Janitor is already running.")
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	t.stop, t.done = stop, done
+
+	clock := t.ut.clock
+	go func() {
+		defer close(done)
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-clock.After(interval):
+				t.DeleteExpired()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// StopJanitor 停止后台清理go程并等待其退出，清理go程没有运行时直接返回
+func (t *safeTTLMap) StopJanitor() {
+	t.m.Lock()
+	stop, done := t.stop, t.done
+	t.stop, t.done = nil, nil
+	t.m.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (t *safeTTLMap) Set(key, value interface{}, ttl time.Duration) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	t.ut.Set(key, value, ttl)
+}
+
+func (t *safeTTLMap) Get(key interface{}) (interface{}, bool) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	return t.ut.Get(key)
+}
+
+func (t *safeTTLMap) Touch(key interface{}) bool {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	return t.ut.Touch(key)
+}
+
+func (t *safeTTLMap) TTL(key interface{}) (time.Duration, bool) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	return t.ut.TTL(key)
+}
+
+func (t *safeTTLMap) Remove(key interface{}) bool {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	return t.ut.Remove(key)
+}
+
+func (t *safeTTLMap) OnExpire(f ExpireCallback) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	t.ut.OnExpire(f)
+}
+
+func (t *safeTTLMap) DeleteExpired() int {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	return t.ut.DeleteExpired()
+}
+
+func (t *safeTTLMap) Len() int {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	return t.ut.Len()
+}
+
+func (t *safeTTLMap) Clear() {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	t.ut.Clear()
+}
+
+// Do 持有写锁执行f，f中对u的多次操作整体上是原子的
+// u是不加锁的内部TTLMap，不能在f返回后继续使用；f返回错误时已经做出的修改不会回滚
+func (t *safeTTLMap) Do(f func(u TTLMap) error) error {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	return f(t.ut)
+}
+
+// Locker 返回保护TTLMap的读写锁，可以用来开启锁的统计
+func (t *safeTTLMap) Locker() *GSync.RWMutex {
+	return t.m
+}
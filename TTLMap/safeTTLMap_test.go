@@ -0,0 +1,137 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package TTLMap
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// testClock 可以并发使用的手动时钟，Advance时触发到期的定时
+type testClock struct {
+	m      sync.Mutex
+	now    time.Time
+	timers []testTimer
+}
+
+func (c *testClock) Now() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.now
+}
+
+func (c *testClock) After(d time.Duration) <-chan time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	ch := make(chan time.Time, 1)
+	c.timers = append(c.timers, testTimer{at: c.now.Add(d), c: ch})
+
+	return ch
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+		} else {
+			t.c <- c.now
+		}
+	}
+	c.timers = pending
+}
+
+func (c *testClock) waiters() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return len(c.timers)
+}
+
+// waitWaiters 等待清理go程在时钟上开始下一次定时
+func waitWaiters(t *testing.T, c *testClock) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for c.waiters() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("janitor is not waiting on the clock")
+		}
+		runtime.Gosched()
+	}
+}
+
+// 后台清理go程由注入的时钟驱动，时钟不前进时不会清理
+func TestSafeTTLMapJanitorUsesClock(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	m, err := NewSafeTTLMap(time.Minute, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := make(chan interface{}, 10)
+	m.OnExpire(func(key, value interface{}) {
+		expired <- key
+	})
+	if err := m.StartJanitor(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	defer m.StopJanitor()
+
+	m.Set("a", 1, 15*time.Second)
+	m.Set("b", 2, 25*time.Second)
+
+	waitWaiters(t, clock)
+	clock.Advance(10 * time.Second)
+	waitWaiters(t, clock)
+	select {
+	case key := <-expired:
+		t.Fatalf("%v expired before its deadline", key)
+	default:
+	}
+
+	clock.Advance(10 * time.Second)
+	if key := <-expired; key != "a" {
+		t.Fatalf("janitor expired %v, want a", key)
+	}
+	waitWaiters(t, clock)
+	clock.Advance(10 * time.Second)
+	if key := <-expired; key != "b" {
+		t.Fatalf("janitor expired %v, want b", key)
+	}
+
+	// 真实时间流逝不会触发清理
+	m.Set("c", 3, time.Nanosecond)
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case key := <-expired:
+		t.Fatalf("%v expired without the clock advancing", key)
+	default:
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package TTLMap
+
+import (
+	"fmt"
+	"time"
+)
+
+// manualClock 手动推进的时钟
+type manualClock struct {
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	return c.now
+}
+
+// After 示例中不使用后台清理go程，返回的通道永远不会收到时间
+func (c *manualClock) After(d time.Duration) <-chan time.Time {
+	return nil
+}
+
+func UnsafeTTLMapExample() {
+	clock := &manualClock{now: time.Now()}
+	t, err := NewUnsafeTTLMap(time.Minute, clock)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	t.OnExpire(func(key, value interface{}) {
+		fmt.Println("expire", key, value)
+	})
+
+	t.Set("session", "alice", 0)
+	t.Set("token", "abc", 10*time.Second)
+	t.Set("config", "v1", -1)
+
+	clock.now = clock.now.Add(30 * time.Second)
+	_, ok := t.Get("token")
+	fmt.Println("t.Get =", ok)
+	fmt.Println("t.Touch =", t.Touch("session"))
+
+	clock.now = clock.now.Add(time.Minute)
+	fmt.Println("t.DeleteExpired =", t.DeleteExpired())
+	fmt.Println("t.Len =", t.Len())
+}
+
+func SafeTTLMapExample() {
+	t, err := NewSafeTTLMap(50*time.Millisecond, nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	err = t.StartJanitor(10 * time.Millisecond)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer t.StopJanitor()
+
+	t.Set("key", "value", 0)
+	time.Sleep(100 * time.Millisecond)
+	fmt.Println("t.Len =", t.Len())
+}
@@ -0,0 +1,213 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package TTLMap
+
+import (
+	"GTL/PriorityQueue"
+	"errors"
+	"time"
+)
+
+type ttlEntry struct {
+	key      interface{}
+	value    interface{}
+	ttl      time.Duration
+	deadline time.Time
+
+	// index 元素在过期堆中的下标，由堆通过SetIndexFunc维护，不在堆中时为-1
+	index int
+}
+
+// unsafeTTLMap 使用一个以过期时间排序的小顶堆记录所有会过期的元素
+// 每个元素在堆中最多只有一条记录，更新过期时间时原地调整，删除时从堆中移除，因此堆的大小不会超过元素的数量
+type unsafeTTLMap struct {
+	m          map[interface{}]*ttlEntry
+	pq         PriorityQueue.PriorityQueue
+	defaultTTL time.Duration
+	clock      Clock
+	onExpire   ExpireCallback
+}
+
+// NewUnsafeTTLMap 返回一个默认过期时间为defaultTTL的TTLMap，clock为nil时使用系统时间
+func NewUnsafeTTLMap(defaultTTL time.Duration, clock Clock) (*unsafeTTLMap, error) {
+	if defaultTTL < 0 {
+		return nil, errors.New("Default TTL must not be negative.")
+	}
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	pq, err := PriorityQueue.NewUnsafePriorityQueue(-1)
+	if err != nil {
+		return nil, err
+	}
+	pq.SetFunc(func(a, b interface{}) bool {
+		return a.(*ttlEntry).deadline.Before(b.(*ttlEntry).deadline)
+	})
+	pq.SetIndexFunc(func(value interface{}, index int) {
+		value.(*ttlEntry).index = index
+	})
+
+	return &unsafeTTLMap{
+		m:          make(map[interface{}]*ttlEntry),
+		pq:         pq,
+		defaultTTL: defaultTTL,
+		clock:      clock,
+		onExpire:   nil,
+	}, nil
+}
+
+// schedule 根据ttl计算元素新的过期时间并调整它在堆中的位置，永不过期的元素从堆中移除
+func (t *unsafeTTLMap) schedule(e *ttlEntry) {
+	if e.ttl <= 0 {
+		e.deadline = time.Time{}
+		t.unschedule(e)
+		return
+	}
+
+	e.deadline = t.clock.Now().Add(e.ttl)
+	if e.index >= 0 {
+		_ = t.pq.Fix(e.index)
+	} else {
+		_ = t.pq.Push(e)
+	}
+}
+
+// unschedule 将元素从堆中移除
+func (t *unsafeTTLMap) unschedule(e *ttlEntry) {
+	if e.index >= 0 {
+		_, _ = t.pq.Remove(e.index)
+	}
+}
+
+func (t *unsafeTTLMap) expired(e *ttlEntry, now time.Time) bool {
+	return !e.deadline.IsZero() && !now.Before(e.deadline)
+}
+
+func (t *unsafeTTLMap) expire(e *ttlEntry) {
+	t.unschedule(e)
+	delete(t.m, e.key)
+	if t.onExpire != nil {
+		t.onExpire(e.key, e.value)
+	}
+}
+
+func (t *unsafeTTLMap) Set(key, value interface{}, ttl time.Duration) {
+	if ttl == 0 {
+		ttl = t.defaultTTL
+	}
+
+	e, ok := t.m[key]
+	if !ok {
+		e = &ttlEntry{key: key, index: -1}
+		t.m[key] = e
+	}
+	e.value = value
+	e.ttl = ttl
+	t.schedule(e)
+}
+
+func (t *unsafeTTLMap) Get(key interface{}) (interface{}, bool) {
+	e, ok := t.m[key]
+	if !ok {
+		return nil, false
+	}
+
+	if t.expired(e, t.clock.Now()) {
+		t.expire(e)
+		return nil, false
+	}
+
+	return e.value, true
+}
+
+func (t *unsafeTTLMap) Touch(key interface{}) bool {
+	e, ok := t.m[key]
+	if !ok {
+		return false
+	}
+
+	if t.expired(e, t.clock.Now()) {
+		t.expire(e)
+		return false
+	}
+	t.schedule(e)
+
+	return true
+}
+
+func (t *unsafeTTLMap) TTL(key interface{}) (time.Duration, bool) {
+	e, ok := t.m[key]
+	if !ok {
+		return 0, false
+	}
+
+	now := t.clock.Now()
+	if t.expired(e, now) {
+		t.expire(e)
+		return 0, false
+	}
+	if e.deadline.IsZero() {
+		return 0, true
+	}
+
+	return e.deadline.Sub(now), true
+}
+
+func (t *unsafeTTLMap) Remove(key interface{}) bool {
+	e, ok := t.m[key]
+	if !ok {
+		return false
+	}
+
+	t.unschedule(e)
+	delete(t.m, key)
+
+	return true
+}
+
+func (t *unsafeTTLMap) OnExpire(f ExpireCallback) {
+	t.onExpire = f
+}
+
+func (t *unsafeTTLMap) DeleteExpired() int {
+	now := t.clock.Now()
+	n := 0
+
+	for {
+		top, err := t.pq.Top()
+		if err != nil || now.Before(top.(*ttlEntry).deadline) {
+			break
+		}
+		t.expire(top.(*ttlEntry))
+		n++
+	}
+
+	return n
+}
+
+func (t *unsafeTTLMap) Len() int {
+	t.DeleteExpired()
+
+	return len(t.m)
+}
+
+func (t *unsafeTTLMap) Clear() {
+	t.m = make(map[interface{}]*ttlEntry)
+	t.pq.Clear()
+}
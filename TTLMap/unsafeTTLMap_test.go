@@ -0,0 +1,90 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package TTLMap
+
+import (
+	"testing"
+	"time"
+)
+
+// 反复Set、Touch和Remove同一批键时堆的大小不超过会过期的元素数量
+func TestUnsafeTTLMapHeapBounded(t *testing.T) {
+	clock := &manualClock{now: time.Now()}
+	m, err := NewUnsafeTTLMap(time.Minute, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const keys = 10
+	for round := 0; round < 100; round++ {
+		for k := 0; k < keys; k++ {
+			m.Set(k, round, 0)
+			m.Touch(k)
+		}
+		m.Set("forever", round, -1)
+		m.Remove(round % keys)
+		clock.now = clock.now.Add(time.Second)
+
+		if m.pq.Size() > len(m.m) {
+			t.Fatalf("round %d: heap holds %d records for %d entries", round, m.pq.Size(), len(m.m))
+		}
+	}
+	for i, value := range m.pq.ToSlice() {
+		e := value.(*ttlEntry)
+		if e.index != i || m.m[e.key] != e {
+			t.Fatalf("heap record %d is stale: index %d, key %v", i, e.index, e.key)
+		}
+	}
+}
+
+// 过期时间更新后按照新的过期时间删除，删除或改为永不过期的元素不再触发回调
+func TestUnsafeTTLMapExpireOrder(t *testing.T) {
+	clock := &manualClock{now: time.Now()}
+	m, err := NewUnsafeTTLMap(time.Minute, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expired []interface{}
+	m.OnExpire(func(key, value interface{}) {
+		expired = append(expired, key)
+	})
+
+	m.Set("a", 1, 10*time.Second)
+	m.Set("b", 2, 20*time.Second)
+	m.Set("c", 3, 30*time.Second)
+	m.Set("d", 4, 40*time.Second)
+	m.Set("a", 1, 50*time.Second)
+	m.Set("b", 2, -1)
+	m.Remove("c")
+
+	clock.now = clock.now.Add(45 * time.Second)
+	if n := m.DeleteExpired(); n != 1 {
+		t.Fatalf("DeleteExpired() = %d, want 1", n)
+	}
+	clock.now = clock.now.Add(10 * time.Second)
+	if n := m.DeleteExpired(); n != 1 {
+		t.Fatalf("DeleteExpired() = %d, want 1", n)
+	}
+
+	if len(expired) != 2 || expired[0] != "d" || expired[1] != "a" {
+		t.Fatalf("expired keys = %v, want [d a]", expired)
+	}
+	if _, ok := m.Get("b"); !ok || m.Len() != 1 || m.pq.Size() != 0 {
+		t.Fatalf("Len() = %d, heap size %d, want the non-expiring key only", m.Len(), m.pq.Size())
+	}
+}
@@ -0,0 +1,239 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package TimingWheel
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TimingWheel 分层时间轮
+// 第L层的每个格子跨越wheelSize^L格，距离到期还有delta格的任务放在满足delta < wheelSize^(L+1)的最低层，
+// 高层格子被处理时，其中的任务重新计算位置并下沉到低层，最终在第0层到期执行。
+// 添加、停止定时任务只需要锁住对应的格子，不存在全局锁
+type TimingWheel struct {
+	tick      time.Duration
+	wheelSize int64
+
+	// spans spans[L]为第L层每个格子跨越的格数
+	spans   []int64
+	buckets [][]*bucket
+
+	// now 已经处理过的格数，使用原子操作读写
+	now int64
+
+	// advanceM 保证同一时刻只有一个go程推进时间轮
+	advanceM sync.Mutex
+
+	// stop 关闭时通知驱动go程退出，为nil表示驱动go程没有运行
+	startM sync.Mutex
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewTimingWheel 返回一个每格时长为tick、每层wheelSize个格子、共levels层的时间轮
+// 超出最高层范围的任务会停留在最高层并反复下沉，直到进入范围内
+func NewTimingWheel(tick time.Duration, wheelSize, levels int) (*TimingWheel, error) {
+	if tick <= 0 {
+		return nil, errors.New("Tick must be positive.")
+	}
+	if wheelSize < 2 {
+		return nil, errors.New("Wheel size must be at least 2.")
+	}
+	if levels < 1 {
+		return nil, errors.New("Levels must be positive.")
+	}
+
+	w := &TimingWheel{
+		tick:      tick,
+		wheelSize: int64(wheelSize),
+		spans:     make([]int64, levels),
+		buckets:   make([][]*bucket, levels),
+		now:       0,
+	}
+
+	span := int64(1)
+	for l := 0; l < levels; l++ {
+		if span > math.MaxInt64/int64(wheelSize) {
+			return nil, errors.New("Too many levels.")
+		}
+		w.spans[l] = span
+		span *= int64(wheelSize)
+
+		w.buckets[l] = make([]*bucket, wheelSize)
+		for i := range w.buckets[l] {
+			w.buckets[l][i] = newBucket()
+		}
+	}
+
+	return w, nil
+}
+
+// AfterFunc 在d之后执行fn，fn在单独的go程中执行，d会向上取整为tick的整数倍
+func (w *TimingWheel) AfterFunc(d time.Duration, fn func()) *Timer {
+	t := &Timer{fn: fn}
+
+	t.m.Lock()
+	t.task = w.schedule(t, d)
+	t.m.Unlock()
+
+	return t
+}
+
+// Stop 停止定时器，返回定时器在停止前是否处于等待状态
+func (w *TimingWheel) Stop(t *Timer) bool {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	return t.task.stop()
+}
+
+// Reset 将定时器重新设置为在d之后执行，返回定时器在重置前是否处于等待状态
+func (w *TimingWheel) Reset(t *Timer, d time.Duration) bool {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	ret := t.task.stop()
+	t.task = w.schedule(t, d)
+
+	return ret
+}
+
+func (w *TimingWheel) schedule(t *Timer, d time.Duration) *timerTask {
+	ticks := int64((d + w.tick - 1) / w.tick)
+	task := &timerTask{
+		timer:      t,
+		expiration: atomic.LoadInt64(&w.now) + ticks,
+		state:      taskPending,
+	}
+	w.add(task)
+
+	return task
+}
+
+// add 将任务放入对应的格子，已经到期的任务直接执行
+func (w *TimingWheel) add(t *timerTask) {
+	for {
+		if atomic.LoadInt32(&t.state) != taskPending {
+			return
+		}
+
+		now := atomic.LoadInt64(&w.now)
+		delta := t.expiration - now
+		if delta <= 0 {
+			t.fire()
+			return
+		}
+
+		levels := len(w.spans)
+		l := 0
+		for l < levels-1 && delta >= w.spans[l+1] {
+			l++
+		}
+
+		// 超出最高层范围的任务先放在最高层最远的格子中
+		exp := t.expiration
+		if max := w.spans[l] * w.wheelSize; delta >= max {
+			exp = now + max - 1
+		}
+
+		// flushAt 该格子被处理时的格数
+		slot := exp / w.spans[l]
+		flushAt := slot * w.spans[l]
+		w.buckets[l][slot%w.wheelSize].add(t)
+
+		// 如果在计算位置之后时间轮已经处理过该格子，任务会被遗漏，此时取回任务重新放置
+		if atomic.LoadInt64(&w.now) < flushAt || !t.remove() {
+			return
+		}
+	}
+}
+
+// Advance 将时间轮推进ticks格，处理所有经过的格子
+func (w *TimingWheel) Advance(ticks int) {
+	w.advanceM.Lock()
+	defer w.advanceM.Unlock()
+
+	for i := 0; i < ticks; i++ {
+		// 先更新now再处理格子，保证与add中的检查配合时不会遗漏任务
+		now := atomic.AddInt64(&w.now, 1)
+
+		for l := len(w.spans) - 1; l >= 0; l-- {
+			if now%w.spans[l] != 0 {
+				continue
+			}
+
+			b := w.buckets[l][(now/w.spans[l])%w.wheelSize]
+			for _, t := range b.flush() {
+				if atomic.LoadInt32(&t.state) == taskPending {
+					w.add(t)
+				}
+			}
+		}
+	}
+}
+
+// Start 开启一个驱动go程，每从source收到一个信号时间轮前进一格
+func (w *TimingWheel) Start(source TickSource) error {
+	w.startM.Lock()
+	defer w.startM.Unlock()
+
+	if w.stop != nil {
+		return errors.New("Timing wheel is already running.")
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	w.stop, w.done = stop, done
+
+	go func() {
+		defer close(done)
+		defer source.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-source.C():
+				w.Advance(1)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Shutdown 停止驱动go程并等待其退出，尚未到期的定时器不会再执行
+func (w *TimingWheel) Shutdown() {
+	w.startM.Lock()
+	stop, done := w.stop, w.done
+	w.stop, w.done = nil, nil
+	w.startM.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Tick 返回每格的时长
+func (w *TimingWheel) Tick() time.Duration {
+	return w.tick
+}
@@ -0,0 +1,205 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package TimingWheel
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fireLog 收集定时器执行时发送的编号
+type fireLog chan int
+
+func (f fireLog) fn(id int) func() {
+	return func() {
+		f <- id
+	}
+}
+
+// expect 接收want中的全部编号，顺序不限
+func (f fireLog) expect(t *testing.T, tick int, want []int) {
+	t.Helper()
+	got := make([]int, 0, len(want))
+	for range want {
+		select {
+		case id := <-f:
+			got = append(got, id)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("tick %d: fired %v, want %v", tick, got, want)
+		}
+	}
+	sort.Ints(got)
+	sort.Ints(want)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("tick %d: fired %v, want %v", tick, got, want)
+		}
+	}
+}
+
+// none 确认没有定时器执行
+func (f fireLog) none(t *testing.T, tick int) {
+	t.Helper()
+	select {
+	case id := <-f:
+		t.Fatalf("tick %d: timer %d fired unexpectedly", tick, id)
+	default:
+	}
+}
+
+// 由注入的TickSource驱动，各层以及超出最高层范围的定时器都恰好在到期的那一格执行
+func TestTimingWheelFiresAcrossLevels(t *testing.T) {
+	// 每层4格共3层，各层每格跨越1、4、16格，最大范围64格
+	w, err := NewTimingWheel(time.Second, 4, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := NewManualTickSource()
+	if err := w.Start(source); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Shutdown()
+
+	f := make(fireLog, 16)
+	delays := []int{1, 3, 4, 5, 15, 16, 17, 33, 63, 64, 100}
+	at := make(map[int][]int)
+	for id, d := range delays {
+		w.AfterFunc(time.Duration(d)*time.Second, f.fn(id))
+		at[d] = append(at[d], id)
+	}
+	// 不足一格的时间向上取整
+	w.AfterFunc(1500*time.Millisecond, f.fn(len(delays)))
+	at[2] = append(at[2], len(delays))
+
+	for tick := 1; tick <= 100; tick++ {
+		source.Tick()
+		if want := at[tick]; len(want) > 0 {
+			f.expect(t, tick, want)
+		}
+	}
+	// 最后一个信号被接收后Advance可能还没有完成，再推进一格确保之前的格子都已处理
+	source.Tick()
+	time.Sleep(10 * time.Millisecond)
+	f.none(t, 101)
+}
+
+// 定时器在高层格子中、下沉过程中以及到达第0层之后都可以停止和重置
+func TestTimingWheelStopResetAcrossLevels(t *testing.T) {
+	w, err := NewTimingWheel(time.Second, 4, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := make(fireLog, 16)
+
+	// 20格的定时器先放在第2层，第16格下沉到第1层
+	stopHigh := w.AfterFunc(20*time.Second, f.fn(0))
+	stopLow := w.AfterFunc(20*time.Second, f.fn(1))
+	growing := w.AfterFunc(2*time.Second, f.fn(2))
+	shrinking := w.AfterFunc(40*time.Second, f.fn(3))
+
+	if !w.Stop(stopHigh) {
+		t.Fatal("Stop on a pending timer in level 2 = false")
+	}
+	if w.Stop(stopHigh) {
+		t.Fatal("second Stop = true")
+	}
+	// 第0层的定时器重置到第2层
+	if !w.Reset(growing, 30*time.Second) {
+		t.Fatal("Reset on a pending timer = false")
+	}
+
+	w.Advance(17)
+	f.none(t, 17)
+	// 已经下沉到第0层
+	if !w.Stop(stopLow) {
+		t.Fatal("Stop on a timer that cascaded to level 0 = false")
+	}
+	// 从第2层重置到第0层
+	if !w.Reset(shrinking, 2*time.Second) {
+		t.Fatal("Reset on a pending timer = false")
+	}
+
+	w.Advance(2)
+	f.expect(t, 19, []int{3})
+	w.Advance(1)
+	f.none(t, 20)
+	w.Advance(10)
+	f.expect(t, 30, []int{2})
+
+	// 已经执行的定时器停止时返回false，重置后会再次执行
+	if w.Stop(growing) {
+		t.Fatal("Stop on a fired timer = true")
+	}
+	if w.Reset(growing, time.Second) {
+		t.Fatal("Reset on a fired timer = true")
+	}
+	w.Advance(1)
+	f.expect(t, 31, []int{2})
+
+	w.Advance(100)
+	time.Sleep(10 * time.Millisecond)
+	f.none(t, 131)
+}
+
+// 与时间轮推进同时调用Stop，每个定时器要么执行要么Stop返回true，不会两者都发生或者都不发生
+func TestTimingWheelConcurrentStopAndFire(t *testing.T) {
+	const timers = 1000
+
+	w, err := NewTimingWheel(time.Millisecond, 8, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fired int32
+	ts := make([]*Timer, timers)
+	for i := range ts {
+		ts[i] = w.AfterFunc(time.Duration(1+i%3)*time.Millisecond, func() {
+			atomic.AddInt32(&fired, 1)
+		})
+	}
+
+	var stopped int32
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		w.Advance(3)
+	}()
+	go func() {
+		defer wg.Done()
+		for i := len(ts) - 1; i >= 0; i-- {
+			if w.Stop(ts[i]) {
+				atomic.AddInt32(&stopped, 1)
+			}
+		}
+	}()
+	wg.Wait()
+
+	want := timers - atomic.LoadInt32(&stopped)
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&fired) < want && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if got := atomic.LoadInt32(&fired); got != want {
+		t.Fatalf("%d timers fired and %d were stopped, want %d in total", got, timers-want, timers)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package TimingWheel
+
+import "time"
+
+// TickSource 驱动时间轮前进，每从C()中收到一个值，时间轮前进一格
+type TickSource interface {
+	C() <-chan time.Time
+
+	Stop()
+}
+
+// tickerSource 使用time.Ticker驱动时间轮
+type tickerSource struct {
+	t *time.Ticker
+}
+
+// NewTickerSource 返回一个每隔d产生一次信号的TickSource
+func NewTickerSource(d time.Duration) TickSource {
+	return &tickerSource{t: time.NewTicker(d)}
+}
+
+func (s *tickerSource) C() <-chan time.Time {
+	return s.t.C
+}
+
+func (s *tickerSource) Stop() {
+	s.t.Stop()
+}
+
+// ManualTickSource 由调用者手动产生信号的TickSource，用于测试
+type ManualTickSource struct {
+	c chan time.Time
+}
+
+func NewManualTickSource() *ManualTickSource {
+	return &ManualTickSource{c: make(chan time.Time)}
+}
+
+// Tick 产生一个信号，会阻塞到时间轮接收为止
+func (s *ManualTickSource) Tick() {
+	s.c <- time.Now()
+}
+
+func (s *ManualTickSource) C() <-chan time.Time {
+	return s.c
+}
+
+func (s *ManualTickSource) Stop() {}
@@ -0,0 +1,129 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package TimingWheel
+
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	taskPending int32 = iota
+	taskFired
+	taskStopped
+)
+
+// Timer 由TimingWheel.AfterFunc返回，每次Reset都会为Timer创建一个新的timerTask
+type Timer struct {
+	fn func()
+
+	// m 只用于串行化同一个Timer上的Reset与Stop，不同Timer之间互不影响
+	m    sync.Mutex
+	task *timerTask
+}
+
+// timerTask 时间轮中的一次调度
+type timerTask struct {
+	timer *Timer
+
+	// expiration 到期的格数
+	expiration int64
+
+	state int32
+
+	// bucket 当前所在的格子，使用原子操作读写，为nil表示不在任何格子中
+	bucket unsafe.Pointer
+}
+
+func (t *timerTask) getBucket() *bucket {
+	return (*bucket)(atomic.LoadPointer(&t.bucket))
+}
+
+func (t *timerTask) setBucket(b *bucket) {
+	atomic.StorePointer(&t.bucket, unsafe.Pointer(b))
+}
+
+// fire 执行定时任务，保证每个timerTask最多执行一次
+func (t *timerTask) fire() {
+	if atomic.CompareAndSwapInt32(&t.state, taskPending, taskFired) {
+		go t.timer.fn()
+	}
+}
+
+// stop 停止定时任务，返回任务在停止前是否处于等待状态
+func (t *timerTask) stop() bool {
+	ret := atomic.CompareAndSwapInt32(&t.state, taskPending, taskStopped)
+	t.remove()
+
+	return ret
+}
+
+// remove 将任务从所在的格子中移除
+func (t *timerTask) remove() bool {
+	for {
+		b := t.getBucket()
+		if b == nil {
+			return false
+		}
+
+		b.m.Lock()
+		// 加锁期间任务可能已经被时间轮取走或者移动到其他格子，此时重新读取
+		if t.getBucket() == b {
+			delete(b.tasks, t)
+			t.setBucket(nil)
+			b.m.Unlock()
+			return true
+		}
+		b.m.Unlock()
+	}
+}
+
+// bucket 时间轮中的一个格子，每个格子有自己的锁
+type bucket struct {
+	m     sync.Mutex
+	tasks map[*timerTask]struct{}
+}
+
+func newBucket() *bucket {
+	return &bucket{tasks: make(map[*timerTask]struct{})}
+}
+
+func (b *bucket) add(t *timerTask) {
+	b.m.Lock()
+	b.tasks[t] = struct{}{}
+	t.setBucket(b)
+	b.m.Unlock()
+}
+
+// flush 取出格子中的所有任务
+func (b *bucket) flush() []*timerTask {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	ret := make([]*timerTask, 0, len(b.tasks))
+	for t := range b.tasks {
+		t.setBucket(nil)
+		ret = append(ret, t)
+	}
+	if len(ret) > 0 {
+		b.tasks = make(map[*timerTask]struct{})
+	}
+
+	return ret
+}
@@ -0,0 +1,69 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package TimingWheel
+
+import (
+	"fmt"
+	"time"
+)
+
+func TimingWheelExample() {
+	w, err := NewTimingWheel(10*time.Millisecond, 64, 4)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	err = w.Start(NewTickerSource(w.Tick()))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer w.Shutdown()
+
+	done := make(chan struct{})
+	w.AfterFunc(50*time.Millisecond, func() {
+		fmt.Println("fired")
+		close(done)
+	})
+
+	t := w.AfterFunc(time.Second, func() {
+		fmt.Println("never fired")
+	})
+	fmt.Println("w.Stop =", w.Stop(t))
+
+	<-done
+}
+
+func ManualTimingWheelExample() {
+	w, err := NewTimingWheel(time.Second, 8, 3)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	done := make(chan struct{})
+	t := w.AfterFunc(3*time.Second, func() {
+		close(done)
+	})
+	fmt.Println("w.Reset =", w.Reset(t, 100*time.Second))
+
+	// 手动推进时间轮，不依赖真实时间
+	w.Advance(100)
+	<-done
+	fmt.Println("fired after 100 ticks")
+}
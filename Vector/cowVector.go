@@ -0,0 +1,204 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Vector
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// cowVector 写时复制的Vector，适用于读多写少的场景
+// 读操作通过原子操作获取当前快照后直接读取，不需要加锁；
+// 写操作在互斥锁的保护下复制当前快照、修改副本，最后原子地替换快照，已发布的快照永远不会被修改
+type cowVector struct {
+	// v 保存当前快照*unsafeVector
+	v atomic.Value
+
+	// m 串行化写操作
+	m sync.Mutex
+}
+
+func NewCOWVector(maxSize int, values ...interface{}) (*cowVector, error) {
+	return NewCOWVectorWithSlice(maxSize, values)
+}
+
+func NewCOWVectorWithSlice(maxSize int, values []interface{}) (*cowVector, error) {
+	uv, err := NewUnsafeVectorWithSlice(maxSize, values)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &cowVector{}
+	v.v.Store(uv)
+
+	return v, nil
+}
+
+func (v *cowVector) load() *unsafeVector {
+	return v.v.Load().(*unsafeVector)
+}
+
+// update 复制当前快照并调用f修改副本，f返回错误时放弃修改
+func (v *cowVector) update(f func(uv *unsafeVector) error) error {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	uv := v.load().clone()
+	if err := f(uv); err != nil {
+		return err
+	}
+	v.v.Store(uv)
+
+	return nil
+}
+
+// Do 在当前快照的副本上执行f，f返回nil时用副本替换当前快照，返回错误时丢弃副本
+// 读操作不会看到f执行过程中的中间状态，u不能在f返回后继续使用
+func (v *cowVector) Do(f func(u Vector) error) error {
+	return v.update(func(uv *unsafeVector) error {
+		return f(uv)
+	})
+}
+
+// Snapshot 返回当前内容的只读视图，之后对cowVector的修改不会影响该视图
+func (v *cowVector) Snapshot() *VectorSnapshot {
+	return &VectorSnapshot{uv: v.load()}
+}
+
+func (v *cowVector) PushBack(value interface{}) error {
+	return v.update(func(uv *unsafeVector) error {
+		return uv.PushBack(value)
+	})
+}
+
+func (v *cowVector) PopBack() (interface{}, error) {
+	var ret interface{}
+	err := v.update(func(uv *unsafeVector) error {
+		var err error
+		ret, err = uv.PopBack()
+		return err
+	})
+
+	return ret, err
+}
+
+func (v *cowVector) Set(index, value int) error {
+	return v.update(func(uv *unsafeVector) error {
+		return uv.Set(index, value)
+	})
+}
+
+func (v *cowVector) At(index int) (interface{}, error) {
+	return v.load().At(index)
+}
+
+func (v *cowVector) Remove(start, end int) error {
+	return v.update(func(uv *unsafeVector) error {
+		return uv.Remove(start, end)
+	})
+}
+
+func (v *cowVector) Find(value interface{}, less func(interface{}, interface{}) bool) int {
+	return v.load().Find(value, less)
+}
+
+func (v *cowVector) Fill() bool {
+	return v.load().Fill()
+}
+
+func (v *cowVector) Empty() bool {
+	return v.load().Empty()
+}
+
+func (v *cowVector) Size() int {
+	return v.load().Size()
+}
+
+func (v *cowVector) MaxSize() int {
+	return v.load().MaxSize()
+}
+
+func (v *cowVector) SetMaxSize(maxSize int) error {
+	return v.update(func(uv *unsafeVector) error {
+		return uv.SetMaxSize(maxSize)
+	})
+}
+
+func (v *cowVector) Clear() {
+	_ = v.update(func(uv *unsafeVector) error {
+		uv.Clear()
+		return nil
+	})
+}
+
+func (v *cowVector) String() string {
+	return v.load().String()
+}
+
+func (v *cowVector) CatFromSlice(values []interface{}) error {
+	return v.update(func(uv *unsafeVector) error {
+		return uv.CatFromSlice(values)
+	})
+}
+
+func (v *cowVector) ToSlice() []interface{} {
+	return v.load().ToSlice()
+}
+
+func (v *cowVector) MarshalJSON() ([]byte, error) {
+	return v.load().MarshalJSON()
+}
+
+func (v *cowVector) UnmarshalJSON(b []byte) error {
+	return v.update(func(uv *unsafeVector) error {
+		return uv.UnmarshalJSON(b)
+	})
+}
+
+// VectorSnapshot 是cowVector某一时刻内容的只读视图，可以被任意多个go程同时读取
+type VectorSnapshot struct {
+	uv *unsafeVector
+}
+
+func (s *VectorSnapshot) At(index int) (interface{}, error) {
+	return s.uv.At(index)
+}
+
+func (s *VectorSnapshot) Find(value interface{}, less func(interface{}, interface{}) bool) int {
+	return s.uv.Find(value, less)
+}
+
+func (s *VectorSnapshot) Empty() bool {
+	return s.uv.Empty()
+}
+
+func (s *VectorSnapshot) Size() int {
+	return s.uv.Size()
+}
+
+func (s *VectorSnapshot) String() string {
+	return s.uv.String()
+}
+
+func (s *VectorSnapshot) ToSlice() []interface{} {
+	return s.uv.ToSlice()
+}
+
+func (s *VectorSnapshot) MarshalJSON() ([]byte, error) {
+	return s.uv.MarshalJSON()
+}
@@ -0,0 +1,163 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Vector
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// 快照在之后的任何修改下都保持不变
+func TestCOWVectorSnapshotIsolation(t *testing.T) {
+	v, err := NewCOWVector(-1, 1, 2, 3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := v.Snapshot()
+	_ = v.PushBack(5)
+	_ = v.Set(0, 10)
+	_ = v.Remove(1, 3)
+	if got := v.ToSlice(); !reflect.DeepEqual(got, []interface{}{10, 4, 5}) {
+		t.Fatalf("ToSlice() = %v, want [10 4 5]", got)
+	}
+	if got := s.ToSlice(); !reflect.DeepEqual(got, []interface{}{1, 2, 3, 4}) {
+		t.Fatalf("snapshot changed to %v", got)
+	}
+
+	s2 := v.Snapshot()
+	v.Clear()
+	if s2.Size() != 3 || s2.Empty() || !v.Empty() {
+		t.Fatalf("snapshot Size() = %d after Clear, want 3", s2.Size())
+	}
+	if x, err := s2.At(1); err != nil || x != 4 {
+		t.Fatalf("snapshot At(1) = %v, %v, want 4", x, err)
+	}
+
+	// 修改ToSlice的结果不会影响快照
+	got := s.ToSlice()
+	got[0] = 100
+	if x, _ := s.At(0); x != 1 {
+		t.Fatalf("snapshot At(0) = %v after modifying ToSlice, want 1", x)
+	}
+}
+
+// 失败的写操作不改变内容，Do返回错误时丢弃所有修改
+func TestCOWVectorFailedWrites(t *testing.T) {
+	v, _ := NewCOWVector(3, 1, 2, 3)
+
+	if err := v.PushBack(4); err == nil {
+		t.Fatal("PushBack into a full vector succeeded")
+	}
+	if err := v.SetMaxSize(2); err == nil {
+		t.Fatal("SetMaxSize below Size() succeeded")
+	}
+	if err := v.Remove(1, 5); err == nil {
+		t.Fatal("Remove out of bounds succeeded")
+	}
+
+	errAbort := errors.New("abort")
+	err := v.Do(func(u Vector) error {
+		_, _ = u.PopBack()
+		_ = u.PushBack(30)
+		_ = u.Set(0, 10)
+		return errAbort
+	})
+	if err != errAbort {
+		t.Fatalf("Do() = %v, want %v", err, errAbort)
+	}
+	if got := v.ToSlice(); !reflect.DeepEqual(got, []interface{}{1, 2, 3}) || v.MaxSize() != 3 {
+		t.Fatalf("ToSlice() = %v after failed writes, want [1 2 3]", got)
+	}
+
+	err = v.Do(func(u Vector) error {
+		_, _ = u.PopBack()
+		return u.PushBack(30)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.ToSlice(); !reflect.DeepEqual(got, []interface{}{1, 2, 30}) {
+		t.Fatalf("ToSlice() = %v, want [1 2 30]", got)
+	}
+
+	empty, _ := NewCOWVector(-1)
+	if _, err := empty.PopBack(); err == nil {
+		t.Fatal("PopBack on an empty vector succeeded")
+	}
+}
+
+// 读者不会看到Do执行过程中的中间状态，并发的写操作不会丢失
+func TestCOWVectorConcurrent(t *testing.T) {
+	v, _ := NewCOWVector(-1)
+
+	const writers, rounds = 4, 200
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				// 每次Do写入一对相同的元素，快照中的元素总是成对出现
+				s := v.Snapshot().ToSlice()
+				if len(s)%2 != 0 {
+					t.Errorf("reader saw %d elements, want pairs", len(s))
+					return
+				}
+				for j := 0; j < len(s); j += 2 {
+					if s[j] != s[j+1] {
+						t.Errorf("reader saw a torn pair %v, %v", s[j], s[j+1])
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	for w := 0; w < writers; w++ {
+		w := w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				x := w*rounds + r
+				_ = v.Do(func(u Vector) error {
+					_ = u.PushBack(x)
+					return u.PushBack(x)
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	readers.Wait()
+
+	if v.Size() != 2*writers*rounds {
+		t.Fatalf("Size() = %d, want %d", v.Size(), 2*writers*rounds)
+	}
+}
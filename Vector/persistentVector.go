@@ -0,0 +1,444 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Vector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 持久化Vector
+// 元素保存在每个结点32路的前缀树中，下标按5位一组从高到低决定每一层的分支，
+// 最后不满32个的元素单独放在tail中，因此大部分Append只需要复制tail。
+// 修改只复制从根到目标叶子的路径，其余结点在新旧版本之间共享，旧版本保持不变。
+// transientVector 用于批量修改，它创建的结点归它所有，可以原地修改，Persistent之后不能再使用。
+
+const (
+	pvBits  = 5
+	pvWidth = 1 << pvBits
+	pvMask  = pvWidth - 1
+)
+
+// pvOwner 标识结点属于哪个transientVector
+type pvOwner struct {
+	_ int
+}
+
+type pvNode struct {
+	// owner 创建该结点的transientVector，只有它可以原地修改该结点
+	owner *pvOwner
+	array [pvWidth]interface{}
+}
+
+var emptyPVNode = &pvNode{}
+
+type pvTrie struct {
+	size  int
+	shift uint
+	root  *pvNode
+	tail  []interface{}
+}
+
+// tailOff 返回tail中第一个元素的下标
+func (t *pvTrie) tailOff() int {
+	if t.size < pvWidth {
+		return 0
+	}
+
+	return ((t.size - 1) >> pvBits) << pvBits
+}
+
+// arrayFor 返回下标i所在的叶子结点，返回的切片只能读
+func (t *pvTrie) arrayFor(i int) []interface{} {
+	if i >= t.tailOff() {
+		return t.tail
+	}
+
+	node := t.root
+	for level := t.shift; level > 0; level -= pvBits {
+		node = node.array[(i>>level)&pvMask].(*pvNode)
+	}
+
+	return node.array[:]
+}
+
+func (t *pvTrie) at(index int) (interface{}, error) {
+	if index < 0 || index >= t.size {
+		return nil, errors.New("Index out of range.")
+	}
+
+	return t.arrayFor(index)[index&pvMask], nil
+}
+
+func (t *pvTrie) toSlice() []interface{} {
+	ans := make([]interface{}, 0, t.size)
+	for i := 0; i < t.size; i += pvWidth {
+		arr := t.arrayFor(i)
+		if n := t.size - i; n < len(arr) {
+			arr = arr[:n]
+		}
+		ans = append(ans, arr...)
+	}
+
+	return ans
+}
+
+type persistentVector struct {
+	pvTrie
+}
+
+func NewPersistentVector(values ...interface{}) *persistentVector {
+	return NewPersistentVectorWithSlice(values)
+}
+
+func NewPersistentVectorWithSlice(values []interface{}) *persistentVector {
+	t := (&persistentVector{
+		pvTrie: pvTrie{
+			size:  0,
+			shift: pvBits,
+			root:  emptyPVNode,
+			tail:  []interface{}{},
+		},
+	}).Transient()
+
+	for _, value := range values {
+		_ = t.Append(value)
+	}
+
+	return t.Persistent()
+}
+
+// NewPersistentVectorFromVector 复制v中的元素创建持久化Vector
+func NewPersistentVectorFromVector(v Vector) *persistentVector {
+	return NewPersistentVectorWithSlice(v.ToSlice())
+}
+
+// Transient 返回一个内容相同的transientVector，对它的修改不会影响v
+func (v *persistentVector) Transient() *transientVector {
+	tail := make([]interface{}, len(v.tail), pvWidth)
+	copy(tail, v.tail)
+
+	return &transientVector{
+		pvTrie: pvTrie{
+			size:  v.size,
+			shift: v.shift,
+			root:  v.root,
+			tail:  tail,
+		},
+		owner: &pvOwner{},
+	}
+}
+
+// Append 返回在末尾添加value后的新版本
+func (v *persistentVector) Append(value interface{}) *persistentVector {
+	t := v.Transient()
+	_ = t.Append(value)
+
+	return t.Persistent()
+}
+
+// Set 返回把下标index处的元素设置为value后的新版本
+func (v *persistentVector) Set(index int, value interface{}) (*persistentVector, error) {
+	t := v.Transient()
+	if err := t.Set(index, value); err != nil {
+		return nil, err
+	}
+
+	return t.Persistent(), nil
+}
+
+// Pop 返回删除最后一个元素后的新版本以及被删除的元素
+func (v *persistentVector) Pop() (*persistentVector, interface{}, error) {
+	t := v.Transient()
+	value, err := t.Pop()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return t.Persistent(), value, nil
+}
+
+func (v *persistentVector) At(index int) (interface{}, error) {
+	return v.at(index)
+}
+
+// ForEach 按下标顺序遍历元素，f返回false时停止遍历
+func (v *persistentVector) ForEach(f func(index int, value interface{}) bool) {
+	for i := 0; i < v.size; i += pvWidth {
+		arr := v.arrayFor(i)
+		for j := 0; j < pvWidth && i+j < v.size; j++ {
+			if !f(i+j, arr[j]) {
+				return
+			}
+		}
+	}
+}
+
+func (v *persistentVector) Empty() bool {
+	return v.size == 0
+}
+
+func (v *persistentVector) Size() int {
+	return v.size
+}
+
+func (v *persistentVector) ToSlice() []interface{} {
+	return v.toSlice()
+}
+
+// ToVector 复制元素创建一个unsafeVector
+func (v *persistentVector) ToVector(maxSize int) (Vector, error) {
+	return NewUnsafeVectorWithSlice(maxSize, v.toSlice())
+}
+
+func (v *persistentVector) String() string {
+	var b strings.Builder
+	b.WriteString("persistentVector{")
+
+	v.ForEach(func(index int, value interface{}) bool {
+		if index != 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(fmt.Sprintf("%v", value))
+		return true
+	})
+	b.WriteString("}")
+
+	return b.String()
+}
+
+// MarshalJSON 将Vector中的所有元素以Json数组的形式返回
+func (v *persistentVector) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.toSlice())
+}
+
+// transientVector 可以原地修改的持久化Vector，用于批量构建或修改
+// 调用Persistent之后不能再修改，不能被多个go程同时使用
+type transientVector struct {
+	pvTrie
+
+	// owner 为nil表示已经调用过Persistent
+	owner *pvOwner
+
+	// persistent Persistent返回的结果
+	persistent *persistentVector
+}
+
+func (t *transientVector) check() error {
+	if t.owner == nil {
+		return errors.New("Transient is used after Persistent.")
+	}
+
+	return nil
+}
+
+// editable 返回可以原地修改的结点，结点不属于t时复制一份
+func (t *transientVector) editable(node *pvNode) *pvNode {
+	if node.owner == t.owner {
+		return node
+	}
+
+	return &pvNode{
+		owner: t.owner,
+		array: node.array,
+	}
+}
+
+// Persistent 结束批量修改，返回持久化Vector，之后t不能再修改
+func (t *transientVector) Persistent() *persistentVector {
+	if t.owner == nil {
+		return t.persistent
+	}
+
+	t.owner = nil
+	t.persistent = &persistentVector{
+		pvTrie: pvTrie{
+			size:  t.size,
+			shift: t.shift,
+			root:  t.root,
+			tail:  t.tail[:len(t.tail):len(t.tail)],
+		},
+	}
+
+	return t.persistent
+}
+
+func (t *transientVector) Append(value interface{}) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+
+	if t.size-t.tailOff() < pvWidth {
+		t.tail = append(t.tail, value)
+		t.size++
+		return nil
+	}
+
+	// tail已满，放入树中
+	tailNode := &pvNode{owner: t.owner}
+	copy(tailNode.array[:], t.tail)
+	t.tail = make([]interface{}, 1, pvWidth)
+	t.tail[0] = value
+
+	if (t.size >> pvBits) > (1 << t.shift) {
+		// 根结点已满，树增加一层
+		root := &pvNode{owner: t.owner}
+		root.array[0] = t.root
+		root.array[1] = t.newPath(t.shift, tailNode)
+		t.root = root
+		t.shift += pvBits
+	} else {
+		t.root = t.pushTail(t.shift, t.root, tailNode)
+	}
+	t.size++
+
+	return nil
+}
+
+// pushTail 把tailNode放到第level层的parent下面
+func (t *transientVector) pushTail(level uint, parent, tailNode *pvNode) *pvNode {
+	ret := t.editable(parent)
+	i := ((t.size - 1) >> level) & pvMask
+
+	if level == pvBits {
+		ret.array[i] = tailNode
+	} else if child, ok := ret.array[i].(*pvNode); ok {
+		ret.array[i] = t.pushTail(level-pvBits, child, tailNode)
+	} else {
+		ret.array[i] = t.newPath(level-pvBits, tailNode)
+	}
+
+	return ret
+}
+
+// newPath 创建一条从第level层到node的路径
+func (t *transientVector) newPath(level uint, node *pvNode) *pvNode {
+	if level == 0 {
+		return node
+	}
+
+	ret := &pvNode{owner: t.owner}
+	ret.array[0] = t.newPath(level-pvBits, node)
+
+	return ret
+}
+
+func (t *transientVector) Set(index int, value interface{}) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+	if index < 0 || index >= t.size {
+		return errors.New("Index out of range.")
+	}
+
+	if index >= t.tailOff() {
+		t.tail[index&pvMask] = value
+	} else {
+		t.root = t.set(t.shift, t.root, index, value)
+	}
+
+	return nil
+}
+
+func (t *transientVector) set(level uint, node *pvNode, index int, value interface{}) *pvNode {
+	ret := t.editable(node)
+	if level == 0 {
+		ret.array[index&pvMask] = value
+	} else {
+		i := (index >> level) & pvMask
+		ret.array[i] = t.set(level-pvBits, ret.array[i].(*pvNode), index, value)
+	}
+
+	return ret
+}
+
+func (t *transientVector) Pop() (interface{}, error) {
+	if err := t.check(); err != nil {
+		return nil, err
+	}
+	if t.size == 0 {
+		return nil, errors.New("This vector is empty.")
+	}
+
+	value := t.tail[len(t.tail)-1]
+	if len(t.tail) > 1 || t.size == 1 {
+		t.tail[len(t.tail)-1] = nil
+		t.tail = t.tail[:len(t.tail)-1]
+		t.size--
+		return value, nil
+	}
+
+	// tail中只有一个元素，把最后一个叶子结点取出作为新的tail
+	tail := make([]interface{}, pvWidth)
+	copy(tail, t.arrayFor(t.size-2))
+
+	root := t.popTail(t.shift, t.root)
+	if root == nil {
+		root = emptyPVNode
+	}
+	if t.shift > pvBits && root.array[1] == nil {
+		// 根结点只剩一个子结点，树减少一层
+		root = root.array[0].(*pvNode)
+		t.shift -= pvBits
+	}
+
+	t.root = root
+	t.tail = tail
+	t.size--
+
+	return value, nil
+}
+
+// popTail 删除最后一个叶子结点，子树变为空时返回nil
+func (t *transientVector) popTail(level uint, node *pvNode) *pvNode {
+	i := ((t.size - 2) >> level) & pvMask
+
+	if level > pvBits {
+		child := t.popTail(level-pvBits, node.array[i].(*pvNode))
+		if child == nil && i == 0 {
+			return nil
+		}
+
+		ret := t.editable(node)
+		if child == nil {
+			ret.array[i] = nil
+		} else {
+			ret.array[i] = child
+		}
+		return ret
+	}
+
+	if i == 0 {
+		return nil
+	}
+
+	ret := t.editable(node)
+	ret.array[i] = nil
+
+	return ret
+}
+
+func (t *transientVector) At(index int) (interface{}, error) {
+	return t.at(index)
+}
+
+func (t *transientVector) Size() int {
+	return t.size
+}
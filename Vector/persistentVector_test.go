@@ -0,0 +1,209 @@
+/*
+ *  Copyright (C) 2021  Shixuan Liu
+ *
+ *     This program is free software: you can redistribute it and/or modify
+ *     it under the terms of the GNU General Public License as published by
+ *     the Free Software Foundation, either version 3 of the License, or
+ *     (at your option) any later version.
+ *
+ *     This program is distributed in the hope that it will be useful,
+ *     but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *     GNU General Public License for more details.
+ *
+ *     You should have received a copy of the GNU General Public License
+ *     along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package Vector
+
+import (
+	"testing"
+)
+
+// pvBoundaries 前缀树的边界：tail满(32)、两层的树满(1024 + 32)、三层的树满(32768 + 32)
+var pvBoundaries = []int{
+	0, 1, 31, 32, 33, 63, 64, 65,
+	1023, 1024, 1025, 1055, 1056, 1057, 1088, 1089,
+	32767, 32768, 32799, 32800, 32801, 32833,
+}
+
+// checkPV 检查v的内容为0..size-1
+func checkPV(t *testing.T, v *persistentVector, size int) {
+	t.Helper()
+	if v.Size() != size || v.Empty() != (size == 0) {
+		t.Fatalf("Size() = %d, want %d", v.Size(), size)
+	}
+	for i := 0; i < size; i++ {
+		if x, err := v.At(i); err != nil || x != i {
+			t.Fatalf("size %d: At(%d) = %v, %v", size, i, x, err)
+		}
+	}
+	s := v.ToSlice()
+	if len(s) != size {
+		t.Fatalf("size %d: len(ToSlice()) = %d", size, len(s))
+	}
+	for i, x := range s {
+		if x != i {
+			t.Fatalf("size %d: ToSlice()[%d] = %v", size, i, x)
+		}
+	}
+	if _, err := v.At(size); err == nil {
+		t.Fatalf("size %d: At(%d) succeeded", size, size)
+	}
+}
+
+// pvRange 返回内容为0..size-1的持久化Vector
+func pvRange(size int) *persistentVector {
+	values := make([]interface{}, size)
+	for i := range values {
+		values[i] = i
+	}
+
+	return NewPersistentVectorWithSlice(values)
+}
+
+// pvShift 返回容纳size个元素的树的高度
+func pvShift(size int) uint {
+	switch {
+	case size <= 1056:
+		return 5
+	case size <= 32800:
+		return 10
+	default:
+		return 15
+	}
+}
+
+// 逐个Append和Pop跨过每一层的边界，途中保存的旧版本不受之后修改的影响
+func TestPersistentVectorBoundaries(t *testing.T) {
+	last := pvBoundaries[len(pvBoundaries)-1]
+	versions := make(map[int]*persistentVector)
+
+	v := NewPersistentVector()
+	for i := 0; ; i++ {
+		for _, b := range pvBoundaries {
+			if b == i {
+				versions[i] = v
+				if v.shift != pvShift(i) {
+					t.Fatalf("size %d: shift = %d, want %d", i, v.shift, pvShift(i))
+				}
+			}
+		}
+		if i == last {
+			break
+		}
+		v = v.Append(i)
+	}
+	for size, old := range versions {
+		checkPV(t, old, size)
+	}
+
+	for i := last; i > 0; i-- {
+		next, x, err := v.Pop()
+		if err != nil || x != i-1 {
+			t.Fatalf("Pop() at size %d = %v, %v, want %d", i, x, err, i-1)
+		}
+		v = next
+		if old, ok := versions[i-1]; ok {
+			if v.shift != pvShift(i-1) {
+				t.Fatalf("size %d after Pop: shift = %d, want %d", i-1, v.shift, pvShift(i-1))
+			}
+			checkPV(t, v, i-1)
+			checkPV(t, old, i-1)
+			// Pop之后再Append与原来的版本内容相同
+			checkPV(t, v.Append(i-1), i)
+		}
+	}
+
+	if _, _, err := v.Pop(); err == nil {
+		t.Fatal("Pop() on an empty vector succeeded")
+	}
+	for size, old := range versions {
+		checkPV(t, old, size)
+	}
+}
+
+// Set只改变新版本中的一个元素，树中和tail中的元素都是如此
+func TestPersistentVectorSet(t *testing.T) {
+	for _, size := range []int{1, 33, 1057, 32801} {
+		v := pvRange(size)
+
+		for _, index := range []int{0, size / 2, size - 1} {
+			w, err := v.Set(index, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkPV(t, v, size)
+			for i := 0; i < size; i++ {
+				want := interface{}(i)
+				if i == index {
+					want = -1
+				}
+				if x, _ := w.At(i); x != want {
+					t.Fatalf("size %d, Set(%d): At(%d) = %v, want %v", size, index, i, x, want)
+				}
+			}
+		}
+
+		if _, err := v.Set(size, 0); err == nil {
+			t.Fatalf("Set(%d) on size %d succeeded", size, size)
+		}
+		if _, err := v.Set(-1, 0); err == nil {
+			t.Fatal("Set(-1) succeeded")
+		}
+	}
+}
+
+// transient只原地修改自己创建的结点，Persistent之后不能再使用
+func TestTransientVectorOwnership(t *testing.T) {
+	v := pvRange(2000)
+
+	t1 := v.Transient()
+	_ = t1.Set(0, -1)
+	root := t1.root
+	_ = t1.Set(1, -2)
+	_ = t1.Append(2000)
+	_, _ = t1.Pop()
+	_, _ = t1.Pop()
+	if t1.root != root {
+		t.Fatal("transient copied a node it already owns")
+	}
+	checkPV(t, v, 2000)
+
+	// 两个transient互不影响
+	t2 := v.Transient()
+	_ = t2.Set(0, -3)
+	if x, _ := t1.At(0); x != -1 {
+		t.Fatalf("t1.At(0) = %v after modifying t2, want -1", x)
+	}
+
+	p1 := t1.Persistent()
+	if t1.Persistent() != p1 {
+		t.Fatal("second Persistent() returned a different vector")
+	}
+	if err := t1.Append(0); err == nil {
+		t.Fatal("Append after Persistent succeeded")
+	}
+	if err := t1.Set(0, 0); err == nil {
+		t.Fatal("Set after Persistent succeeded")
+	}
+	if _, err := t1.Pop(); err == nil {
+		t.Fatal("Pop after Persistent succeeded")
+	}
+
+	// 新的transient不能原地修改p1中由t1创建的结点
+	t3 := p1.Transient()
+	_ = t3.Set(0, -4)
+	_ = t3.Set(1000, -5)
+	_ = t3.Set(1998, -6)
+	want := map[int]interface{}{0: -1, 1: -2, 1000: 1000, 1998: 1998}
+	for i, w := range want {
+		if x, _ := p1.At(i); x != w {
+			t.Fatalf("p1.At(%d) = %v after modifying a later transient, want %v", i, x, w)
+		}
+	}
+	if p1.Size() != 1999 {
+		t.Fatalf("p1.Size() = %d, want 1999", p1.Size())
+	}
+}
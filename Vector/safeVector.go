@@ -17,11 +17,40 @@
 
 package Vector
 
-import "sync"
+import "GTL/GSync"
 
 type safeVector struct {
 	uv *unsafeVector
-	m  *sync.RWMutex
+	m  *GSync.RWMutex
+}
+
+func NewSafeVector(maxSize int, values ...interface{}) (*safeVector, error) {
+	return NewSafeVectorWithSlice(maxSize, values)
+}
+
+func NewSafeVectorWithSlice(maxSize int, values []interface{}) (*safeVector, error) {
+	uv, err := NewUnsafeVectorWithSlice(maxSize, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &safeVector{
+		uv: uv,
+		m:  new(GSync.RWMutex),
+	}, nil
+}
+
+// NewSafeVectorWithLocker 返回使用m保护的Vector，m可以由GSync.NewRWMutexWithLocker创建，为nil时与NewSafeVectorWithSlice相同
+func NewSafeVectorWithLocker(m *GSync.RWMutex, maxSize int, values []interface{}) (*safeVector, error) {
+	v, err := NewSafeVectorWithSlice(maxSize, values)
+	if err != nil {
+		return nil, err
+	}
+	if m != nil {
+		v.m = m
+	}
+
+	return v, nil
 }
 
 func (v *safeVector) PushBack(value interface{}) error {
@@ -142,3 +171,31 @@ func (v *safeVector) UnmarshalJSON(b []byte) error {
 
 	return v.uv.UnmarshalJSON(b)
 }
+
+// Do 持有写锁执行f，f中对u的多次操作整体上是原子的
+// u是不加锁的内部Vector，不能在f返回后继续使用；f返回错误时已经做出的修改不会回滚，需要回滚时使用Container.Transact
+func (v *safeVector) Do(f func(u Vector) error) error {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	return f(v.uv)
+}
+
+// Locker 返回保护Vector的读写锁
+func (v *safeVector) Locker() *GSync.RWMutex {
+	return v.m
+}
+
+// UnsafeView 返回不加锁的内部Vector，只能在持有锁时使用
+func (v *safeVector) UnsafeView() interface{} {
+	return v.uv
+}
+
+// Snapshot 保存Vector当前的内容，返回的函数把Vector恢复到保存时的状态，调用时必须持有写锁
+func (v *safeVector) Snapshot() func() {
+	old := v.uv.clone()
+
+	return func() {
+		*v.uv = *old
+	}
+}
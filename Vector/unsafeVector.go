@@ -211,6 +211,17 @@ func (v *unsafeVector) ToSlice() []interface{} {
 	return b
 }
 
+// clone 复制出一个内容相同的Vector，元素本身不会被复制
+func (v *unsafeVector) clone() *unsafeVector {
+	s := make([]interface{}, len(v.s))
+	copy(s, v.s)
+
+	return &unsafeVector{
+		s:       s,
+		maxSize: v.maxSize,
+	}
+}
+
 // MarshalJSON 将Vector中的所有元素以Json数组的形式返回
 func (v *unsafeVector) MarshalJSON() ([]byte, error) {
 	items := make([]string, 0, v.Size())
@@ -19,6 +19,7 @@ package Vector
 
 import (
 	"fmt"
+	"sync"
 )
 
 func UnsafeVectorExample() {
@@ -64,3 +65,55 @@ func UnsafeVectorExample() {
 	v.UnmarshalJSON(b)
 	fmt.Println("v.String =", v.String())
 }
+
+func COWVectorExample() {
+	v, err := NewCOWVector(-1, "a", "b", "c")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	snapshot := v.Snapshot()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// 读操作不需要加锁
+			for j := 0; j < v.Size(); j++ {
+				_, _ = v.At(j)
+			}
+		}()
+	}
+	err = v.PushBack("d")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	wg.Wait()
+
+	fmt.Println("v.String =", v.String())
+	fmt.Println("snapshot.String =", snapshot.String())
+}
+
+func PersistentVectorExample() {
+	v1 := NewPersistentVector(1, 2, 3)
+	v2 := v1.Append(4)
+	v3, _ := v2.Set(0, 100)
+	v4, last, _ := v3.Pop()
+
+	// 每次修改都返回新版本，旧版本保持不变
+	fmt.Println(v1, v2, v3, v4, last)
+
+	// 批量修改时使用transientVector，避免每次都复制路径
+	t := v4.Transient()
+	for i := 0; i < 100; i++ {
+		_ = t.Append(i)
+	}
+	v5 := t.Persistent()
+	fmt.Println(v4.Size(), v5.Size())
+
+	uv, _ := v5.ToVector(-1)
+	fmt.Println(NewPersistentVectorFromVector(uv).Size())
+}